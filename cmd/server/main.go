@@ -38,9 +38,14 @@ func main() {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	clusterRepo := repository.NewClusterRepository(db)
+	protoRepo := repository.NewProtoRepository(db)
+	searchRepo := repository.NewSearchJobRepository(db)
+	replayRepo := repository.NewReplayJobRepository(db)
+	throttleRepo := repository.NewReplicationThrottleRepository(db)
 
 	// Initialize utilities
 	kafkaManager := util.NewKafkaClientManager()
+	schemaRegistry := util.NewSchemaRegistryClient()
 	tokenCache := util.NewTokenCache(
 		time.Duration(config.GlobalConfig.Cache.TokenExpiration)*time.Second,
 		config.GlobalConfig.Cache.MaxTokens,
@@ -49,11 +54,35 @@ func main() {
 	// Initialize services
 	userService := service.NewUserService(userRepo)
 	topicStatsRepo := repository.NewTopicStatsRepository(db)
-	brokerService := service.NewBrokerService(clusterRepo, kafkaManager)
-	topicService := service.NewTopicService(clusterRepo, topicStatsRepo, kafkaManager)
+	reassignmentService := service.NewReassignmentService(clusterRepo, throttleRepo, kafkaManager)
+	brokerService := service.NewBrokerService(clusterRepo, kafkaManager, reassignmentService)
+	protoService := service.NewProtoService(clusterRepo, protoRepo)
+	topicService := service.NewTopicService(clusterRepo, topicStatsRepo, kafkaManager, schemaRegistry, protoService)
 	consumerGroupService := service.NewConsumerGroupService(clusterRepo, kafkaManager)
+	searchService := service.NewSearchJobService(clusterRepo, searchRepo, kafkaManager, topicService)
+	replayService := service.NewReplayJobService(clusterRepo, replayRepo, kafkaManager, topicService)
+	tableService := service.NewTopicTableService(clusterRepo, kafkaManager, topicService)
+	backupDir := config.GlobalConfig.Backup.Dir
+	if backupDir == "" {
+		backupDir = "data/backups"
+	}
+	backupService := service.NewBackupService(clusterRepo, kafkaManager, topicService, backupDir)
 	clusterService := service.NewClusterService(clusterRepo, kafkaManager, topicService, brokerService, consumerGroupService)
 
+	// Jobs that were running when the server stopped cannot resume
+	if err := searchService.FailInterruptedJobs(); err != nil {
+		log.Printf("Failed to mark interrupted search jobs: %v", err)
+	}
+	if err := replayService.FailInterruptedJobs(); err != nil {
+		log.Printf("Failed to mark interrupted replay jobs: %v", err)
+	}
+	if err := backupService.FailInterruptedBackups(); err != nil {
+		log.Printf("Failed to mark interrupted backups: %v", err)
+	}
+	if err := reassignmentService.RestoreInterruptedThrottles(); err != nil {
+		log.Printf("Failed to restore interrupted replication throttles: %v", err)
+	}
+
 	// Start topic stats background task (refresh every 1 minute)
 	topicStatsTask := service.NewTopicStatsTask(clusterRepo, topicStatsRepo, kafkaManager, 1*time.Minute)
 	topicStatsTask.Start()
@@ -83,7 +112,13 @@ func main() {
 	clusterController := controller.NewClusterController(clusterService)
 	brokerController := controller.NewBrokerController(brokerService)
 	topicController := controller.NewTopicController(topicService)
+	topicTableController := controller.NewTopicTableController(tableService)
 	consumerGroupController := controller.NewConsumerGroupController(consumerGroupService)
+	protoController := controller.NewProtoController(protoService)
+	searchJobController := controller.NewSearchJobController(searchService)
+	replayJobController := controller.NewReplayJobController(replayService)
+	backupController := controller.NewBackupController(backupService)
+	reassignmentController := controller.NewReassignmentController(reassignmentService)
 
 	// Setup Gin router
 	router := gin.Default()
@@ -132,6 +167,7 @@ func main() {
 			protected.GET("/clusters", clusterController.GetClusters)
 			protected.GET("/clusters/paging", clusterController.GetClustersPaged)
 			protected.GET("/clusters/:id", clusterController.GetCluster)
+			protected.GET("/clusters/:id/health", clusterController.GetClusterHealth)
 			protected.POST("/clusters", clusterController.CreateCluster)
 			protected.PUT("/clusters/:id", clusterController.UpdateCluster)
 			protected.DELETE("/clusters/:id", clusterController.DeleteCluster)
@@ -140,21 +176,73 @@ func main() {
 			protected.GET("/brokers", brokerController.GetBrokers)
 			protected.GET("/brokers/:id/configs", brokerController.GetBrokerConfigs)
 			protected.PUT("/brokers/:id/configs", brokerController.UpdateBrokerConfigs)
+			protected.GET("/brokers/:id/drain/plan", brokerController.PlanDrain)
+			protected.POST("/brokers/:id/drain", brokerController.DrainBroker)
+			protected.GET("/brokers/:id/drain", brokerController.GetDrain)
+			protected.POST("/brokers/:id/drain/cancel", brokerController.CancelDrain)
+			protected.GET("/leadership", brokerController.GetLeadership)
+			protected.POST("/leadership/elections", brokerController.ElectLeaders)
+
+			// Partition reassignment routes
+			protected.POST("/reassignments/plan", reassignmentController.PlanReassignment)
+			protected.POST("/reassignments", reassignmentController.StartReassignment)
+			protected.GET("/reassignments", reassignmentController.GetReassignment)
+			protected.POST("/reassignments/cancel", reassignmentController.CancelReassignment)
 
 			// Topic routes
 			protected.GET("/topics", topicController.GetTopics)
 			protected.GET("/topics/names", topicController.GetTopicNames)
 			protected.GET("/topics/:topic", topicController.GetTopicDetail)
 			protected.GET("/topics/:topic/partitions", topicController.GetTopicPartitions)
+			protected.GET("/topics/:topic/partitions/:partition/offsets/:offset", topicController.GetMessage)
 			protected.GET("/topics/:topic/brokers", topicController.GetTopicBrokers)
 			protected.POST("/topics", topicController.CreateTopic)
 			protected.POST("/topics/batch-delete", topicController.DeleteTopics)
 			protected.POST("/topics/:topic/partitions", topicController.ExpandPartitions)
+			protected.POST("/topics/:topic/replication-factor", reassignmentController.ChangeReplicationFactor)
+			protected.POST("/topics/:topic/delete-records", topicController.DeleteRecords)
 			protected.GET("/topics/:topic/configs", topicController.GetTopicConfigs)
 			protected.PUT("/topics/:topic/configs", topicController.UpdateTopicConfigs)
 			protected.GET("/topics/:topic/data/live", topicController.GetMessagesLive)
+			protected.GET("/topics/:topic/data/ws", topicController.GetMessagesSocket)
 			protected.GET("/topics/:topic/data", topicController.GetMessages)
 			protected.POST("/topics/:topic/data", topicController.SendMessage)
+			protected.POST("/topics/:topic/import", topicController.ImportMessages)
+			protected.GET("/topics/:topic/export", topicController.ExportMessages)
+			protected.GET("/topics/:topic/table", topicTableController.GetTopicTable)
+			protected.GET("/topics/:topic/protobuf", protoController.GetTopicBinding)
+			protected.PUT("/topics/:topic/protobuf", protoController.BindTopic)
+			protected.DELETE("/topics/:topic/protobuf", protoController.UnbindTopic)
+
+			// Protobuf descriptor routes
+			protected.GET("/protobuf/descriptors", protoController.GetDescriptorSets)
+			protected.POST("/protobuf/descriptors", protoController.UploadDescriptorSet)
+			protected.DELETE("/protobuf/descriptors/:id", protoController.DeleteDescriptorSet)
+
+			// Search job routes
+			protected.POST("/topics/:topic/search-jobs", searchJobController.StartSearch)
+			protected.GET("/topics/:topic/search-jobs", searchJobController.GetSearchJobs)
+			protected.GET("/search-jobs/:id", searchJobController.GetSearchJob)
+			protected.GET("/search-jobs/:id/matches", searchJobController.GetSearchMatches)
+			protected.GET("/search-jobs/:id/stream", searchJobController.StreamSearchJob)
+			protected.POST("/search-jobs/:id/cancel", searchJobController.CancelSearchJob)
+			protected.DELETE("/search-jobs/:id", searchJobController.DeleteSearchJob)
+
+			// Replay job routes
+			protected.POST("/topics/:topic/replay-jobs", replayJobController.StartReplay)
+			protected.GET("/topics/:topic/replay-jobs", replayJobController.GetReplayJobs)
+			protected.GET("/replay-jobs/:id", replayJobController.GetReplayJob)
+			protected.POST("/replay-jobs/:id/cancel", replayJobController.CancelReplayJob)
+			protected.DELETE("/replay-jobs/:id", replayJobController.DeleteReplayJob)
+
+			// Backup routes
+			protected.POST("/topics/:topic/backups", backupController.StartBackup)
+			protected.GET("/backups", backupController.GetBackups)
+			protected.GET("/backups/:id", backupController.GetBackup)
+			protected.POST("/backups/:id/verify", backupController.VerifyBackup)
+			protected.POST("/backups/:id/restore", backupController.RestoreBackup)
+			protected.POST("/backups/:id/cancel", backupController.CancelBackup)
+			protected.DELETE("/backups/:id", backupController.DeleteBackup)
 
 			// Consumer Group routes
 			protected.GET("/consumerGroups", consumerGroupController.GetConsumerGroups)
@@ -186,6 +274,7 @@ func main() {
 			protected.GET("/clusters", clusterController.GetClusters)
 			protected.GET("/clusters/paging", clusterController.GetClustersPaged)
 			protected.GET("/clusters/:id", clusterController.GetCluster)
+			protected.GET("/clusters/:id/health", clusterController.GetClusterHealth)
 			protected.POST("/clusters", clusterController.CreateCluster)
 			protected.PUT("/clusters/:id", clusterController.UpdateCluster)
 			protected.DELETE("/clusters/:id", clusterController.DeleteCluster)
@@ -194,21 +283,73 @@ func main() {
 			protected.GET("/brokers", brokerController.GetBrokers)
 			protected.GET("/brokers/:id/configs", brokerController.GetBrokerConfigs)
 			protected.PUT("/brokers/:id/configs", brokerController.UpdateBrokerConfigs)
+			protected.GET("/brokers/:id/drain/plan", brokerController.PlanDrain)
+			protected.POST("/brokers/:id/drain", brokerController.DrainBroker)
+			protected.GET("/brokers/:id/drain", brokerController.GetDrain)
+			protected.POST("/brokers/:id/drain/cancel", brokerController.CancelDrain)
+			protected.GET("/leadership", brokerController.GetLeadership)
+			protected.POST("/leadership/elections", brokerController.ElectLeaders)
+
+			// Partition reassignment routes
+			protected.POST("/reassignments/plan", reassignmentController.PlanReassignment)
+			protected.POST("/reassignments", reassignmentController.StartReassignment)
+			protected.GET("/reassignments", reassignmentController.GetReassignment)
+			protected.POST("/reassignments/cancel", reassignmentController.CancelReassignment)
 
 			// Topic routes
 			protected.GET("/topics", topicController.GetTopics)
 			protected.GET("/topics/names", topicController.GetTopicNames)
 			protected.GET("/topics/:topic", topicController.GetTopicDetail)
 			protected.GET("/topics/:topic/partitions", topicController.GetTopicPartitions)
+			protected.GET("/topics/:topic/partitions/:partition/offsets/:offset", topicController.GetMessage)
 			protected.GET("/topics/:topic/brokers", topicController.GetTopicBrokers)
 			protected.POST("/topics", topicController.CreateTopic)
 			protected.POST("/topics/batch-delete", topicController.DeleteTopics)
 			protected.POST("/topics/:topic/partitions", topicController.ExpandPartitions)
+			protected.POST("/topics/:topic/replication-factor", reassignmentController.ChangeReplicationFactor)
+			protected.POST("/topics/:topic/delete-records", topicController.DeleteRecords)
 			protected.GET("/topics/:topic/configs", topicController.GetTopicConfigs)
 			protected.PUT("/topics/:topic/configs", topicController.UpdateTopicConfigs)
 			protected.GET("/topics/:topic/data/live", topicController.GetMessagesLive)
+			protected.GET("/topics/:topic/data/ws", topicController.GetMessagesSocket)
 			protected.GET("/topics/:topic/data", topicController.GetMessages)
 			protected.POST("/topics/:topic/data", topicController.SendMessage)
+			protected.POST("/topics/:topic/import", topicController.ImportMessages)
+			protected.GET("/topics/:topic/export", topicController.ExportMessages)
+			protected.GET("/topics/:topic/table", topicTableController.GetTopicTable)
+			protected.GET("/topics/:topic/protobuf", protoController.GetTopicBinding)
+			protected.PUT("/topics/:topic/protobuf", protoController.BindTopic)
+			protected.DELETE("/topics/:topic/protobuf", protoController.UnbindTopic)
+
+			// Protobuf descriptor routes
+			protected.GET("/protobuf/descriptors", protoController.GetDescriptorSets)
+			protected.POST("/protobuf/descriptors", protoController.UploadDescriptorSet)
+			protected.DELETE("/protobuf/descriptors/:id", protoController.DeleteDescriptorSet)
+
+			// Search job routes
+			protected.POST("/topics/:topic/search-jobs", searchJobController.StartSearch)
+			protected.GET("/topics/:topic/search-jobs", searchJobController.GetSearchJobs)
+			protected.GET("/search-jobs/:id", searchJobController.GetSearchJob)
+			protected.GET("/search-jobs/:id/matches", searchJobController.GetSearchMatches)
+			protected.GET("/search-jobs/:id/stream", searchJobController.StreamSearchJob)
+			protected.POST("/search-jobs/:id/cancel", searchJobController.CancelSearchJob)
+			protected.DELETE("/search-jobs/:id", searchJobController.DeleteSearchJob)
+
+			// Replay job routes
+			protected.POST("/topics/:topic/replay-jobs", replayJobController.StartReplay)
+			protected.GET("/topics/:topic/replay-jobs", replayJobController.GetReplayJobs)
+			protected.GET("/replay-jobs/:id", replayJobController.GetReplayJob)
+			protected.POST("/replay-jobs/:id/cancel", replayJobController.CancelReplayJob)
+			protected.DELETE("/replay-jobs/:id", replayJobController.DeleteReplayJob)
+
+			// Backup routes
+			protected.POST("/topics/:topic/backups", backupController.StartBackup)
+			protected.GET("/backups", backupController.GetBackups)
+			protected.GET("/backups/:id", backupController.GetBackup)
+			protected.POST("/backups/:id/verify", backupController.VerifyBackup)
+			protected.POST("/backups/:id/restore", backupController.RestoreBackup)
+			protected.POST("/backups/:id/cancel", backupController.CancelBackup)
+			protected.DELETE("/backups/:id", backupController.DeleteBackup)
 
 			// Consumer Group routes
 			protected.GET("/consumerGroups", consumerGroupController.GetConsumerGroups)
@@ -15,6 +15,7 @@ type Config struct {
 	Default           DefaultConfig            `yaml:"default"`
 	Cache             CacheConfig              `yaml:"cache"`
 	Auth              AuthConfig               `yaml:"auth"`
+	Backup            BackupConfig             `yaml:"backup"`
 	BootstrapClusters []BootstrapClusterConfig `yaml:"bootstrap_clusters"`
 }
 
@@ -40,6 +41,10 @@ type AuthConfig struct {
 	Disabled bool `yaml:"disabled"`
 }
 
+type BackupConfig struct {
+	Dir string `yaml:"dir"`
+}
+
 type BootstrapClusterConfig struct {
 	Name             string `yaml:"name"`
 	Servers          string `yaml:"servers"`
@@ -109,6 +114,10 @@ func applyEnvOverrides(cfg *Config) error {
 		cfg.Cache.MaxTokens = maxTokens
 	}
 
+	if v := strings.TrimSpace(os.Getenv("KAFKA_MAP_BACKUP_DIR")); v != "" {
+		cfg.Backup.Dir = v
+	}
+
 	if parseBoolEnv(os.Getenv("KAFKA_MAP_AUTH_DISABLED")) {
 		cfg.Auth.Disabled = true
 	}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type BackupController struct {
+	backupService *service.BackupService
+}
+
+func NewBackupController(backupService *service.BackupService) *BackupController {
+	return &BackupController{backupService: backupService}
+}
+
+// backupError responds with 404 for unknown backups and the given status otherwise.
+func backupError(ctx *gin.Context, status int, message string, err error) {
+	if errors.Is(err, service.ErrBackupNotFound) {
+		status = http.StatusNotFound
+	}
+	ctx.JSON(status, dto.Response{
+		Code:    status,
+		Message: message + ": " + err.Error(),
+	})
+}
+
+// StartBackup starts a backup of a topic to the local backup directory
+func (c *BackupController) StartBackup(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	backup, err := c.backupService.StartBackup(uint(clusterID), ctx.Param("topic"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to start backup: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Backup started",
+		Data:    backup,
+	})
+}
+
+// GetBackups lists the backups, optionally of one cluster ("clusterId") or topic ("topic")
+func (c *BackupController) GetBackups(ctx *gin.Context) {
+	var clusterID uint64
+	if value := ctx.Query("clusterId"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, dto.Response{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid cluster ID",
+			})
+			return
+		}
+		clusterID = id
+	}
+
+	backups, err := c.backupService.GetBackups(uint(clusterID), ctx.Query("topic"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve backups: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    backups,
+	})
+}
+
+// GetBackup returns the manifest of a backup, with live progress while it runs
+func (c *BackupController) GetBackup(ctx *gin.Context) {
+	backup, err := c.backupService.GetBackup(ctx.Param("id"))
+	if err != nil {
+		backupError(ctx, http.StatusInternalServerError, "Failed to retrieve backup", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    backup,
+	})
+}
+
+// VerifyBackup checks the partition files of a backup against their checksums
+func (c *BackupController) VerifyBackup(ctx *gin.Context) {
+	verification, err := c.backupService.VerifyBackup(ctx.Param("id"))
+	if err != nil {
+		backupError(ctx, http.StatusBadRequest, "Failed to verify backup", err)
+		return
+	}
+
+	message := "Backup is intact"
+	if !verification.Valid {
+		message = "Backup is corrupted"
+	}
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    verification,
+	})
+}
+
+// RestoreBackup restores a backup into a new topic
+func (c *BackupController) RestoreBackup(ctx *gin.Context) {
+	var req dto.RestoreBackupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := c.backupService.RestoreBackup(ctx.Request.Context(), ctx.Param("id"), &req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrBackupNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.JSON(status, dto.Response{
+			Code:    status,
+			Message: "Failed to restore backup: " + err.Error(),
+			Data:    result,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Backup restored",
+		Data:    result,
+	})
+}
+
+// CancelBackup stops a running backup
+func (c *BackupController) CancelBackup(ctx *gin.Context) {
+	if err := c.backupService.CancelBackup(ctx.Param("id")); err != nil {
+		backupError(ctx, http.StatusBadRequest, "Failed to cancel backup", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Backup cancelled",
+	})
+}
+
+// DeleteBackup removes a finished backup from disk
+func (c *BackupController) DeleteBackup(ctx *gin.Context) {
+	if err := c.backupService.DeleteBackup(ctx.Param("id")); err != nil {
+		backupError(ctx, http.StatusBadRequest, "Failed to delete backup", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Backup deleted successfully",
+	})
+}
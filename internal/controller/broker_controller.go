@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -122,3 +123,208 @@ func (c *BrokerController) UpdateBrokerConfigs(ctx *gin.Context) {
 		Message: "Broker configs updated successfully",
 	})
 }
+
+func parseClusterAndBroker(ctx *gin.Context) (uint, int32, bool) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return 0, 0, false
+	}
+	brokerID, err := strconv.ParseInt(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid broker ID",
+		})
+		return 0, 0, false
+	}
+	return uint(clusterID), int32(brokerID), true
+}
+
+// drainErrorStatus maps drain errors caused by the request to 400 and 404
+func drainErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrDrainRunning):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrNoDrain):
+		return http.StatusNotFound
+	default:
+		return reassignmentErrorStatus(err)
+	}
+}
+
+// PlanDrain shows how every replica would be moved off a broker
+func (c *BrokerController) PlanDrain(ctx *gin.Context) {
+	clusterID, brokerID, ok := parseClusterAndBroker(ctx)
+	if !ok {
+		return
+	}
+
+	plan, err := c.brokerService.PlanDrain(clusterID, brokerID)
+	if err != nil {
+		status := drainErrorStatus(err)
+		ctx.JSON(status, dto.Response{
+			Code:    status,
+			Message: "Failed to plan drain: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    plan,
+	})
+}
+
+// DrainBroker starts moving leadership and every replica off a broker
+func (c *BrokerController) DrainBroker(ctx *gin.Context) {
+	clusterID, brokerID, ok := parseClusterAndBroker(ctx)
+	if !ok {
+		return
+	}
+
+	var req dto.DrainRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, dto.Response{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	status, err := c.brokerService.DrainBroker(clusterID, brokerID, &req)
+	if err != nil {
+		code := drainErrorStatus(err)
+		ctx.JSON(code, dto.Response{
+			Code:    code,
+			Message: "Failed to drain broker: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Drain started",
+		Data:    status,
+	})
+}
+
+// GetDrain returns the drain progress of a broker and what it still hosts
+func (c *BrokerController) GetDrain(ctx *gin.Context) {
+	clusterID, brokerID, ok := parseClusterAndBroker(ctx)
+	if !ok {
+		return
+	}
+
+	status, err := c.brokerService.GetDrain(clusterID, brokerID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve drain: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    status,
+	})
+}
+
+// CancelDrain stops draining a broker
+func (c *BrokerController) CancelDrain(ctx *gin.Context) {
+	clusterID, brokerID, ok := parseClusterAndBroker(ctx)
+	if !ok {
+		return
+	}
+
+	status, err := c.brokerService.CancelDrain(clusterID, brokerID)
+	if err != nil {
+		code := drainErrorStatus(err)
+		ctx.JSON(code, dto.Response{
+			Code:    code,
+			Message: "Failed to cancel drain: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Drain cancelled",
+		Data:    status,
+	})
+}
+
+// GetLeadership reports partitions not led by their preferred leader and the leaders per broker
+func (c *BrokerController) GetLeadership(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	report, err := c.brokerService.GetLeadership(uint(clusterID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve leadership: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    report,
+	})
+}
+
+// ElectLeaders runs a preferred or unclean leader election on partitions, topics or the cluster
+func (c *BrokerController) ElectLeaders(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	var req dto.LeaderElectionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := c.brokerService.ElectLeaders(uint(clusterID), &req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidElection) {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, dto.Response{
+			Code:    status,
+			Message: "Failed to elect leaders: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    result,
+	})
+}
@@ -24,6 +24,10 @@ type clusterRequest struct {
 	SaslPassword     *string `json:"saslPassword"`
 	AuthUsername     *string `json:"authUsername"`
 	AuthPassword     *string `json:"authPassword"`
+
+	SchemaRegistryURL      *string `json:"schemaRegistryUrl"`
+	SchemaRegistryUsername *string `json:"schemaRegistryUsername"`
+	SchemaRegistryPassword *string `json:"schemaRegistryPassword"`
 }
 
 func NewClusterController(clusterService *service.ClusterService) *ClusterController {
@@ -110,6 +114,33 @@ func (c *ClusterController) GetCluster(ctx *gin.Context) {
 	})
 }
 
+// GetClusterHealth lists offline, under-min-ISR and under-replicated partitions by topic and broker
+func (c *ClusterController) GetClusterHealth(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	health, err := c.clusterService.GetClusterHealth(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to check cluster health: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    health,
+	})
+}
+
 // CreateCluster creates a new cluster
 func (c *ClusterController) CreateCluster(ctx *gin.Context) {
 	var req clusterRequest
@@ -203,7 +234,8 @@ func (c *ClusterController) UpdateCluster(ctx *gin.Context) {
 func (r clusterRequest) hasChanges() bool {
 	return r.Name != nil || r.Servers != nil || r.SecurityProtocol != nil ||
 		r.SaslMechanism != nil || r.SaslUsername != nil || r.SaslPassword != nil ||
-		r.AuthUsername != nil || r.AuthPassword != nil
+		r.AuthUsername != nil || r.AuthPassword != nil ||
+		r.SchemaRegistryURL != nil || r.SchemaRegistryUsername != nil || r.SchemaRegistryPassword != nil
 }
 
 func (r clusterRequest) applyTo(cluster *model.Cluster) {
@@ -231,6 +263,15 @@ func (r clusterRequest) applyTo(cluster *model.Cluster) {
 	if r.AuthPassword != nil {
 		cluster.SaslPassword = *r.AuthPassword
 	}
+	if r.SchemaRegistryURL != nil {
+		cluster.SchemaRegistryURL = strings.TrimSpace(*r.SchemaRegistryURL)
+	}
+	if r.SchemaRegistryUsername != nil {
+		cluster.SchemaRegistryUsername = strings.TrimSpace(*r.SchemaRegistryUsername)
+	}
+	if r.SchemaRegistryPassword != nil {
+		cluster.SchemaRegistryPassword = *r.SchemaRegistryPassword
+	}
 }
 
 // DeleteCluster deletes a cluster
@@ -0,0 +1,288 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/service"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// liveQueueCapacity bounds the records waiting for a slow WebSocket client.
+	liveQueueCapacity = 1000
+	// liveSampleEvery is the share of records kept in sample mode once the queue is half full.
+	liveSampleEvery = 10
+	// liveSocketBatch bounds the records sent in one messages event.
+	liveSocketBatch = 200
+)
+
+// Overflow modes of a WebSocket live tail
+const (
+	liveOverflowDrop   = "drop"
+	liveOverflowSample = "sample"
+)
+
+func parseOverflowMode(value string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case "", liveOverflowDrop:
+		return liveOverflowDrop, nil
+	case liveOverflowSample:
+		return liveOverflowSample, nil
+	default:
+		return "", fmt.Errorf("invalid overflow %q, expected drop or sample", value)
+	}
+}
+
+// liveQueue buffers records between the tail and a WebSocket client. When the client
+// falls behind, new records are dropped once the queue is full, or in sample mode only
+// one in liveSampleEvery is kept once the queue is half full. Records that current
+// rejects, read before a seek or from a partition no longer followed, are left out.
+type liveQueue struct {
+	mu       sync.Mutex
+	records  []service.LiveRecord
+	capacity int
+	sample   bool
+	seen     int
+	dropped  int64
+	current  func(service.LiveRecord) bool
+	ready    chan struct{}
+}
+
+func newLiveQueue(capacity int, mode string, current func(service.LiveRecord) bool) *liveQueue {
+	return &liveQueue{
+		capacity: capacity,
+		sample:   mode == liveOverflowSample,
+		current:  current,
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+func (q *liveQueue) push(record service.LiveRecord) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// checked under the lock, so a record is either rejected here or removed by reset
+	if !q.current(record) {
+		return
+	}
+	if len(q.records) >= q.capacity {
+		q.dropped++
+		return
+	}
+	if q.sample && len(q.records) >= q.capacity/2 {
+		q.seen++
+		if q.seen%liveSampleEvery != 0 {
+			q.dropped++
+			return
+		}
+	}
+	q.records = append(q.records, record)
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take removes up to max records and returns them with the number of records dropped so far.
+func (q *liveQueue) take(max int) ([]dto.MessageRecord, int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(q.records)
+	if n > max {
+		n = max
+	}
+	records := make([]dto.MessageRecord, 0, n)
+	for _, record := range q.records[:n] {
+		records = append(records, record.MessageRecord)
+	}
+	q.records = q.records[n:]
+	if len(q.records) == 0 {
+		q.records = nil
+		q.seen = 0
+	} else {
+		select {
+		case q.ready <- struct{}{}:
+		default:
+		}
+	}
+	return records, q.dropped
+}
+
+// reset discards the waiting records that are no longer current, e.g. after a seek.
+func (q *liveQueue) reset() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var kept []service.LiveRecord
+	for _, record := range q.records {
+		if q.current(record) {
+			kept = append(kept, record)
+		}
+	}
+	q.records = kept
+	q.seen = 0
+}
+
+func (q *liveQueue) droppedCount() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// serveLiveSocket runs a WebSocket live tail session. Commands are read on their own
+// goroutine and applied between writes, so every write happens on this goroutine.
+func serveLiveSocket(ws *websocket.Conn, tail *service.LiveTail, queue *liveQueue) {
+	commands := make(chan dto.LiveCommand)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var command dto.LiveCommand
+			if err := websocket.JSON.Receive(ws, &command); err != nil {
+				return
+			}
+			select {
+			case commands <- command:
+			case <-tail.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			select {
+			case record := <-tail.Records():
+				queue.push(record)
+			case <-tail.Done():
+				return
+			}
+		}
+	}()
+
+	send := func(event dto.LiveEvent) bool {
+		return websocket.JSON.Send(ws, event) == nil
+	}
+
+	if !send(dto.LiveEvent{Type: "connected", Partitions: tail.Positions()}) {
+		return
+	}
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-tail.Done():
+			send(dto.LiveEvent{Type: "end", Partitions: tail.Positions(), Dropped: queue.droppedCount()})
+			return
+		case <-heartbeat.C:
+			if !send(dto.LiveEvent{
+				Type:       "ping",
+				Partitions: tail.Positions(),
+				Paused:     tail.Paused(),
+				Dropped:    queue.droppedCount(),
+				Timestamp:  time.Now().UnixMilli(),
+			}) {
+				return
+			}
+		case <-queue.ready:
+			messages, dropped := queue.take(liveSocketBatch)
+			if len(messages) == 0 {
+				continue
+			}
+			if !send(dto.LiveEvent{
+				Type:       "messages",
+				Messages:   messages,
+				Partitions: tail.Positions(),
+				Paused:     tail.Paused(),
+				Dropped:    dropped,
+			}) {
+				return
+			}
+		case command := <-commands:
+			event := dto.LiveEvent{Type: "ack", Command: command.Type}
+			if err := applyLiveCommand(tail, queue, command); err != nil {
+				event = dto.LiveEvent{Type: "error", Command: command.Type, Message: err.Error()}
+			}
+			event.Partitions = tail.Positions()
+			event.Paused = tail.Paused()
+			event.Dropped = queue.droppedCount()
+			if !send(event) {
+				return
+			}
+		}
+	}
+}
+
+// applyLiveCommand changes a running tail as requested by the client.
+func applyLiveCommand(tail *service.LiveTail, queue *liveQueue, command dto.LiveCommand) error {
+	switch command.Type {
+	case dto.LiveCommandPause:
+		tail.Pause()
+	case dto.LiveCommandResume:
+		tail.Resume()
+	case dto.LiveCommandFilter:
+		return tail.SetFilter(dto.MessageQuery{
+			KeyFilter:   command.KeyFilter,
+			ValueFilter: command.ValueFilter,
+			JSONKey:     command.JSONKey,
+			JSONValue:   command.JSONValue,
+			Filter:      command.Filter,
+			JSONPath:    command.JSONPath,
+			JSONPathAny: strings.EqualFold(strings.TrimSpace(command.JSONPathMode), "or"),
+		})
+	case dto.LiveCommandSeek:
+		query, err := liveSeekQuery(command)
+		if err != nil {
+			return err
+		}
+		if err := tail.Seek(query); err != nil {
+			return err
+		}
+		queue.reset()
+	case dto.LiveCommandPartitions:
+		if err := tail.SetPartitions(command.Partitions); err != nil {
+			return err
+		}
+		queue.reset()
+	default:
+		return fmt.Errorf("unknown command %q", command.Type)
+	}
+	return nil
+}
+
+// liveSeekQuery turns a seek command into the positions to restart from.
+func liveSeekQuery(command dto.LiveCommand) (dto.MessageQuery, error) {
+	query := dto.MessageQuery{Offset: -1}
+	switch {
+	case command.Offset != nil:
+		if *command.Offset < 0 {
+			return query, errors.New("offset must not be negative")
+		}
+		if command.Partition != nil {
+			query.Offsets = map[int32]int64{*command.Partition: *command.Offset}
+		} else {
+			query.Offset = *command.Offset
+		}
+	case command.Timestamp > 0:
+		query.From = command.Timestamp
+		query.Offset = sarama.OffsetOldest
+	case command.Position == "beginning":
+		query.Offset = sarama.OffsetOldest
+	case command.Position == "end":
+		query.Offset = sarama.OffsetNewest
+	default:
+		return query, errors.New("seek needs an offset, a timestamp or a position of beginning or end")
+	}
+	return query, nil
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/service"
+)
+
+func liveRecord(offset int64) service.LiveRecord {
+	return service.LiveRecord{MessageRecord: dto.MessageRecord{Offset: offset}}
+}
+
+func allCurrent(service.LiveRecord) bool { return true }
+
+func TestLiveQueueOverflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		pushed      int
+		wantQueued  int
+		wantDropped int64
+	}{
+		{name: "drop keeps the first records", mode: liveOverflowDrop, pushed: 150, wantQueued: 100, wantDropped: 50},
+		// half of the queue fills up, then one in ten of the remaining 100 records is kept
+		{name: "sample thins out once half full", mode: liveOverflowSample, pushed: 150, wantQueued: 60, wantDropped: 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := newLiveQueue(100, tt.mode, allCurrent)
+			for i := 0; i < tt.pushed; i++ {
+				queue.push(liveRecord(int64(i)))
+			}
+
+			records, dropped := queue.take(1000)
+			if len(records) != tt.wantQueued || dropped != tt.wantDropped {
+				t.Fatalf("take() = %d records, %d dropped, want %d and %d", len(records), dropped, tt.wantQueued, tt.wantDropped)
+			}
+			for i := 1; i < len(records); i++ {
+				if records[i].Offset <= records[i-1].Offset {
+					t.Fatalf("records out of order at %d: %d after %d", i, records[i].Offset, records[i-1].Offset)
+				}
+			}
+		})
+	}
+}
+
+func TestLiveQueueTakeInBatches(t *testing.T) {
+	queue := newLiveQueue(10, liveOverflowDrop, allCurrent)
+	for i := 0; i < 5; i++ {
+		queue.push(liveRecord(int64(i)))
+	}
+
+	first, _ := queue.take(3)
+	if len(first) != 3 || first[0].Offset != 0 {
+		t.Fatalf("first batch = %v, want offsets 0-2", first)
+	}
+	select {
+	case <-queue.ready:
+	default:
+		t.Fatal("queue not ready with records left")
+	}
+	rest, _ := queue.take(3)
+	if len(rest) != 2 || rest[0].Offset != 3 {
+		t.Fatalf("second batch = %v, want offsets 3-4", rest)
+	}
+}
+
+func TestLiveQueueDropsStaleRecords(t *testing.T) {
+	// records below the cutoff stand for the ones read before a seek
+	cutoff := int64(0)
+	queue := newLiveQueue(10, liveOverflowDrop, func(record service.LiveRecord) bool { return record.Offset >= cutoff })
+	queue.push(liveRecord(1))
+	queue.push(liveRecord(2))
+
+	cutoff = 2
+	queue.push(liveRecord(1))
+	queue.push(liveRecord(3))
+	queue.reset()
+	records, dropped := queue.take(10)
+	if len(records) != 2 || records[0].Offset != 2 || records[1].Offset != 3 || dropped != 0 {
+		t.Fatalf("take() after reset = %v with %d dropped, want offsets 2 and 3", records, dropped)
+	}
+}
+
+func TestLiveSeekQuery(t *testing.T) {
+	offset := int64(42)
+	negative := int64(-3)
+	partition := int32(2)
+
+	tests := []struct {
+		name        string
+		command     dto.LiveCommand
+		wantOffset  int64
+		wantOffsets map[int32]int64
+		wantFrom    int64
+		wantErr     bool
+	}{
+		{name: "offset on every partition", command: dto.LiveCommand{Offset: &offset}, wantOffset: 42},
+		{name: "offset on one partition", command: dto.LiveCommand{Offset: &offset, Partition: &partition}, wantOffset: -1, wantOffsets: map[int32]int64{2: 42}},
+		{name: "timestamp", command: dto.LiveCommand{Timestamp: 1700000000000}, wantOffset: sarama.OffsetOldest, wantFrom: 1700000000000},
+		{name: "beginning", command: dto.LiveCommand{Position: "beginning"}, wantOffset: sarama.OffsetOldest},
+		{name: "end", command: dto.LiveCommand{Position: "end"}, wantOffset: sarama.OffsetNewest},
+		{name: "negative offset", command: dto.LiveCommand{Offset: &negative}, wantErr: true},
+		{name: "no position", command: dto.LiveCommand{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := liveSeekQuery(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("liveSeekQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if query.Offset != tt.wantOffset || query.From != tt.wantFrom || len(query.Offsets) != len(tt.wantOffsets) {
+				t.Fatalf("liveSeekQuery() = %+v, want offset %d, offsets %v, from %d", query, tt.wantOffset, tt.wantOffsets, tt.wantFrom)
+			}
+			for p, o := range tt.wantOffsets {
+				if query.Offsets[p] != o {
+					t.Fatalf("Offsets[%d] = %d, want %d", p, query.Offsets[p], o)
+				}
+			}
+		})
+	}
+}
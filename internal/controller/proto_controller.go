@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxDescriptorSetSize bounds uploaded descriptor sets.
+	maxDescriptorSetSize = 16 << 20
+	// maxDescriptorFormOverhead leaves room for the multipart headers and the name field.
+	maxDescriptorFormOverhead = 1 << 20
+)
+
+type ProtoController struct {
+	protoService *service.ProtoService
+}
+
+func NewProtoController(protoService *service.ProtoService) *ProtoController {
+	return &ProtoController{protoService: protoService}
+}
+
+// GetDescriptorSets lists the protobuf descriptor sets uploaded for a cluster
+func (c *ProtoController) GetDescriptorSets(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	sets, err := c.protoService.GetDescriptorSets(uint(clusterID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve descriptor sets: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    sets,
+	})
+}
+
+// UploadDescriptorSet stores a FileDescriptorSet uploaded as the multipart "file" field
+func (c *ProtoController) UploadDescriptorSet(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	// oversized uploads are rejected while the form is read instead of once it was spooled
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxDescriptorSetSize+maxDescriptorFormOverhead)
+	fileHeader, err := ctx.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Descriptor set is too large",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if fileHeader.Size > maxDescriptorSetSize {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Descriptor set is too large",
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxDescriptorSetSize))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	name := ctx.PostForm("name")
+	if name == "" {
+		name = fileHeader.Filename
+	}
+
+	set, err := c.protoService.UploadDescriptorSet(uint(clusterID), name, data)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to upload descriptor set: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Descriptor set uploaded successfully",
+		Data:    set,
+	})
+}
+
+// DeleteDescriptorSet removes a descriptor set that is not bound to any topic
+func (c *ProtoController) DeleteDescriptorSet(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid descriptor set ID",
+		})
+		return
+	}
+
+	if err := c.protoService.DeleteDescriptorSet(uint(clusterID), uint(id)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrDescriptorSetNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.JSON(status, dto.Response{
+			Code:    status,
+			Message: "Failed to delete descriptor set: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Descriptor set deleted successfully",
+	})
+}
+
+// GetTopicBinding returns the protobuf message types bound to a topic
+func (c *ProtoController) GetTopicBinding(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	binding, err := c.protoService.GetTopicBinding(uint(clusterID), ctx.Param("topic"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve protobuf binding: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    binding,
+	})
+}
+
+// BindTopic binds protobuf message types to a topic's keys and/or values
+func (c *ProtoController) BindTopic(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	var req dto.TopicProtoBindingRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	binding, err := c.protoService.BindTopic(uint(clusterID), ctx.Param("topic"), &req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to bind topic: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Protobuf binding saved successfully",
+		Data:    binding,
+	})
+}
+
+// UnbindTopic removes the protobuf binding of a topic
+func (c *ProtoController) UnbindTopic(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	if err := c.protoService.UnbindTopic(uint(clusterID), ctx.Param("topic")); err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to remove protobuf binding: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Protobuf binding removed successfully",
+	})
+}
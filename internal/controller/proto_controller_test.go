@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUploadDescriptorSetRejectsOversizedBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "huge.desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(bytes.Repeat([]byte{0}, maxDescriptorSetSize+maxDescriptorFormOverhead))
+	form.Close()
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/api/proto/descriptor-sets?clusterId=1", &body)
+	ctx.Request.Header.Set("Content-Type", form.FormDataContentType())
+
+	// the size check comes before the service is used
+	(&ProtoController{}).UploadDescriptorSet(ctx)
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), "too large") {
+		t.Fatalf("response = %d %s, want 400 for a too large descriptor set", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ReassignmentController struct {
+	reassignmentService *service.ReassignmentService
+}
+
+func NewReassignmentController(reassignmentService *service.ReassignmentService) *ReassignmentController {
+	return &ReassignmentController{reassignmentService: reassignmentService}
+}
+
+// reassignmentErrorStatus maps reassignment errors caused by the request to 400
+func reassignmentErrorStatus(err error) int {
+	if errors.Is(err, service.ErrInvalidReassignment) || errors.Is(err, service.ErrReassignmentRunning) ||
+		errors.Is(err, service.ErrReassignmentBusy) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, service.ErrNoReassignment) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// PlanReassignment proposes a balanced reassignment of topics over brokers
+func (c *ReassignmentController) PlanReassignment(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	var req dto.ReassignmentPlanRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	plan, err := c.reassignmentService.PlanReassignment(uint(clusterID), &req)
+	if err != nil {
+		status := reassignmentErrorStatus(err)
+		ctx.JSON(status, dto.Response{
+			Code:    status,
+			Message: "Failed to plan reassignment: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    plan,
+	})
+}
+
+// StartReassignment moves partitions to their target replicas
+func (c *ReassignmentController) StartReassignment(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	var req dto.ReassignmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if err := service.ValidateReassignment(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	reassignment, err := c.reassignmentService.StartReassignment(uint(clusterID), &req)
+	if err != nil {
+		code := reassignmentErrorStatus(err)
+		ctx.JSON(code, dto.Response{
+			Code:    code,
+			Message: "Failed to start reassignment: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Reassignment started",
+		Data:    reassignment.Status(),
+	})
+}
+
+// GetReassignment returns the progress of the last reassignment of a cluster
+func (c *ReassignmentController) GetReassignment(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	status, err := c.reassignmentService.GetReassignment(uint(clusterID))
+	if err != nil {
+		code := reassignmentErrorStatus(err)
+		ctx.JSON(code, dto.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    status,
+	})
+}
+
+// CancelReassignment stops the partitions of the running reassignment that are still moving
+func (c *ReassignmentController) CancelReassignment(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	status, err := c.reassignmentService.CancelReassignment(uint(clusterID))
+	if err != nil {
+		code := reassignmentErrorStatus(err)
+		ctx.JSON(code, dto.Response{
+			Code:    code,
+			Message: "Failed to cancel reassignment: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Reassignment cancelled",
+		Data:    status,
+	})
+}
+
+// ChangeReplicationFactor adds or removes replicas of every partition of a topic
+func (c *ReassignmentController) ChangeReplicationFactor(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	var req dto.ReplicationFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	change, err := c.reassignmentService.ChangeReplicationFactor(uint(clusterID), ctx.Param("topic"), &req)
+	if err != nil {
+		status := reassignmentErrorStatus(err)
+		ctx.JSON(status, dto.Response{
+			Code:    status,
+			Message: "Failed to change replication factor: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    change,
+	})
+}
@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ReplayJobController struct {
+	replayService *service.ReplayJobService
+}
+
+func NewReplayJobController(replayService *service.ReplayJobService) *ReplayJobController {
+	return &ReplayJobController{replayService: replayService}
+}
+
+func parseReplayJobID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid replay job ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// StartReplay starts copying records of a topic to another topic
+func (c *ReplayJobController) StartReplay(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	var req dto.ReplayJobRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	encoding, err := parseEncoding(req.Encoding)
+	if err == nil {
+		req.Encoding = encoding
+		err = service.ValidateReplayJob(&req)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	job, err := c.replayService.StartReplay(uint(clusterID), ctx.Param("topic"), &req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to start replay: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Replay started",
+		Data:    job,
+	})
+}
+
+// GetReplayJobs lists the replay jobs reading from a topic
+func (c *ReplayJobController) GetReplayJobs(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	jobs, err := c.replayService.GetReplayJobs(uint(clusterID), ctx.Param("topic"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve replay jobs: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    jobs,
+	})
+}
+
+// GetReplayJob returns the status and progress of a replay job
+func (c *ReplayJobController) GetReplayJob(ctx *gin.Context) {
+	id, ok := parseReplayJobID(ctx)
+	if !ok {
+		return
+	}
+
+	job, err := c.replayService.GetReplayJob(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, dto.Response{
+			Code:    http.StatusNotFound,
+			Message: "Replay job not found",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    job,
+	})
+}
+
+// CancelReplayJob stops a running replay job, keeping the records copied so far
+func (c *ReplayJobController) CancelReplayJob(ctx *gin.Context) {
+	id, ok := parseReplayJobID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.replayService.CancelReplayJob(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to cancel replay job: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Replay job cancelled",
+	})
+}
+
+// DeleteReplayJob removes a finished replay job
+func (c *ReplayJobController) DeleteReplayJob(ctx *gin.Context) {
+	id, ok := parseReplayJobID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.replayService.DeleteReplayJob(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to delete replay job: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Replay job deleted successfully",
+	})
+}
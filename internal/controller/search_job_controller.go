@@ -0,0 +1,321 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchMatchLimit = 100
+	maxSearchMatchLimit     = 1000
+	searchStreamInterval    = 500 * time.Millisecond
+)
+
+type SearchJobController struct {
+	searchService *service.SearchJobService
+}
+
+func NewSearchJobController(searchService *service.SearchJobService) *SearchJobController {
+	return &SearchJobController{searchService: searchService}
+}
+
+func parseSearchJobID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid search job ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// validateSearchRequest checks the encoding, filters and time range before a job is started
+func validateSearchRequest(req *dto.SearchJobRequest) error {
+	encoding, err := parseEncoding(req.Encoding)
+	if err != nil {
+		return err
+	}
+	req.Encoding = encoding
+
+	if strings.TrimSpace(req.Filter) != "" {
+		if _, err := service.CompileFilterExpression(req.Filter); err != nil {
+			return err
+		}
+	}
+	if _, err := service.ParseJSONPathFilter(req.JSONPath, req.JSONPathMode); err != nil {
+		return err
+	}
+	if req.From > 0 && req.To > 0 && req.From > req.To {
+		return errors.New("from must not be after to")
+	}
+	return nil
+}
+
+// StartSearch starts a background search over a topic
+func (c *SearchJobController) StartSearch(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	var req dto.SearchJobRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if err := validateSearchRequest(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	job, err := c.searchService.StartSearch(uint(clusterID), ctx.Param("topic"), &req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to start search: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Search started",
+		Data:    job,
+	})
+}
+
+// GetSearchJobs lists the search jobs of a topic
+func (c *SearchJobController) GetSearchJobs(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	jobs, err := c.searchService.GetSearchJobs(uint(clusterID), ctx.Param("topic"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve search jobs: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    jobs,
+	})
+}
+
+// GetSearchJob returns the status and progress of a search job
+func (c *SearchJobController) GetSearchJob(ctx *gin.Context) {
+	id, ok := parseSearchJobID(ctx)
+	if !ok {
+		return
+	}
+
+	job, err := c.searchService.GetSearchJob(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, dto.Response{
+			Code:    http.StatusNotFound,
+			Message: "Search job not found",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    job,
+	})
+}
+
+// GetSearchMatches pages through the matches of a search job, starting after the match ID in "after"
+func (c *SearchJobController) GetSearchMatches(ctx *gin.Context) {
+	id, ok := parseSearchJobID(ctx)
+	if !ok {
+		return
+	}
+
+	afterID, err := strconv.ParseUint(ctx.DefaultQuery("after", "0"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid after",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultSearchMatchLimit)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid limit",
+		})
+		return
+	}
+	if limit > maxSearchMatchLimit {
+		limit = maxSearchMatchLimit
+	}
+
+	matches, err := c.searchService.GetSearchMatches(id, uint(afterID), limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve matches: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    matches,
+	})
+}
+
+// StreamSearchJob streams new matches and progress of a search job as server-sent events until it finishes
+func (c *SearchJobController) StreamSearchJob(ctx *gin.Context) {
+	id, ok := parseSearchJobID(ctx)
+	if !ok {
+		return
+	}
+
+	afterID, err := strconv.ParseUint(ctx.DefaultQuery("after", "0"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid after",
+		})
+		return
+	}
+
+	if _, err := c.searchService.GetSearchJob(id); err != nil {
+		ctx.JSON(http.StatusNotFound, dto.Response{
+			Code:    http.StatusNotFound,
+			Message: "Search job not found",
+		})
+		return
+	}
+
+	ctx.Writer.Header().Set("Content-Type", "text/event-stream")
+	ctx.Writer.Header().Set("Cache-Control", "no-cache")
+	ctx.Writer.Header().Set("Connection", "keep-alive")
+	ctx.Writer.Header().Set("X-Accel-Buffering", "no")
+
+	flusher, ok := ctx.Writer.(http.Flusher)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Streaming unsupported",
+		})
+		return
+	}
+
+	sendEvent := func(name string, data any) {
+		ctx.SSEvent(name, data)
+		flusher.Flush()
+	}
+
+	ticker := time.NewTicker(searchStreamInterval)
+	defer ticker.Stop()
+
+	lastID := uint(afterID)
+	for {
+		// read the job before its matches so no match of a finished job is missed
+		job, err := c.searchService.GetSearchJob(id)
+		if err != nil {
+			sendEvent("error", gin.H{"message": "Failed to retrieve search job: " + err.Error()})
+			return
+		}
+
+		for {
+			matches, err := c.searchService.GetSearchMatches(id, lastID, maxSearchMatchLimit)
+			if err != nil {
+				sendEvent("error", gin.H{"message": "Failed to retrieve matches: " + err.Error()})
+				return
+			}
+			if len(matches) == 0 {
+				break
+			}
+			lastID = matches[len(matches)-1].ID
+			sendEvent("matches", matches)
+		}
+
+		sendEvent("progress", job)
+		if job.Status != dto.SearchJobRunning {
+			sendEvent("done", job)
+			return
+		}
+
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CancelSearchJob stops a running search job, keeping the matches found so far
+func (c *SearchJobController) CancelSearchJob(ctx *gin.Context) {
+	id, ok := parseSearchJobID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.searchService.CancelSearchJob(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to cancel search job: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Search job cancelled",
+	})
+}
+
+// DeleteSearchJob removes a finished search job and its matches
+func (c *SearchJobController) DeleteSearchJob(ctx *gin.Context) {
+	id, ok := parseSearchJobID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.searchService.DeleteSearchJob(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to delete search job: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Search job deleted successfully",
+	})
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+func TestValidateSearchRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     dto.SearchJobRequest
+		wantErr bool
+	}{
+		{name: "any json path", req: dto.SearchJobRequest{JSONPath: []string{"$.id == 1"}, JSONPathMode: " OR "}},
+		{name: "unknown json path mode", req: dto.SearchJobRequest{JSONPath: []string{"$.id == 1"}, JSONPathMode: "xor"}, wantErr: true},
+		{name: "time range", req: dto.SearchJobRequest{From: 1000, To: 2000}},
+		{name: "from after to", req: dto.SearchJobRequest{From: 2000, To: 1000}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSearchRequest(&tt.req); (err != nil) != tt.wantErr {
+				t.Fatalf("validateSearchRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
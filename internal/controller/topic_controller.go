@@ -1,30 +1,29 @@
 package controller
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"mime/multipart"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
 	"github.com/bingfengfeifei/kafka-map-go/internal/service"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
 )
 
+// liveHeartbeatInterval is how often a live tail sends a ping with its positions.
+const liveHeartbeatInterval = 5 * time.Second
+
 type TopicController struct {
 	topicService *service.TopicService
 }
 
-type messageQuery struct {
-	partition   int32
-	offset      int64
-	limit       int
-	keyFilter   string
-	valueFilter string
-	jsonKey     string
-	jsonValue   string
-}
-
 func NewTopicController(topicService *service.TopicService) *TopicController {
 	return &TopicController{topicService: topicService}
 }
@@ -76,11 +75,14 @@ func normalizeCreateTopicRequest(req *dto.CreateTopicRequest) error {
 	return nil
 }
 
-func parseMessageQuery(ctx *gin.Context, defaultLimit int) messageQuery {
+func parseMessageQuery(ctx *gin.Context, defaultLimit int) (dto.MessageQuery, error) {
 	partition, err := strconv.ParseInt(ctx.Query("partition"), 10, 32)
-	if err != nil {
+	if err != nil || partition < 0 {
 		partition = 0
 	}
+	if value := ctx.Query("partition"); value == "all" || value == "-1" {
+		partition = int64(dto.AllPartitions)
+	}
 
 	offset, err := strconv.ParseInt(ctx.Query("offset"), 10, 64)
 	if err != nil {
@@ -95,15 +97,175 @@ func parseMessageQuery(ctx *gin.Context, defaultLimit int) messageQuery {
 		}
 	}
 
-	return messageQuery{
-		partition:   int32(partition),
-		offset:      offset,
-		limit:       limit,
-		keyFilter:   ctx.Query("keyFilter"),
-		valueFilter: ctx.Query("valueFilter"),
-		jsonKey:     ctx.Query("jsonKey"),
-		jsonValue:   ctx.Query("jsonValue"),
+	fromValue := ctx.Query("from")
+	if fromValue == "" {
+		fromValue = ctx.Query("timestamp")
+	}
+	from, err := parseTimestampParam(fromValue)
+	if err != nil {
+		return dto.MessageQuery{}, fmt.Errorf("invalid from: %w", err)
+	}
+	to, err := parseTimestampParam(ctx.Query("to"))
+	if err != nil {
+		return dto.MessageQuery{}, fmt.Errorf("invalid to: %w", err)
+	}
+	if from > 0 && to > 0 && from > to {
+		return dto.MessageQuery{}, errors.New("from must not be after to")
+	}
+	offsets, err := parsePartitionOffsets(ctx.Query("offsets"))
+	if err != nil {
+		return dto.MessageQuery{}, fmt.Errorf("invalid offsets: %w", err)
+	}
+
+	var cursor *dto.MessageCursor
+	if token := ctx.Query("cursor"); token != "" {
+		decoded, err := service.DecodeMessageCursor(token)
+		if err != nil {
+			return dto.MessageQuery{}, fmt.Errorf("invalid cursor: %w", err)
+		}
+		cursor = &decoded
+	}
+
+	encoding, err := parseEncoding(ctx.Query("encoding"))
+	if err != nil {
+		return dto.MessageQuery{}, err
+	}
+
+	filter := strings.TrimSpace(ctx.Query("filter"))
+	if filter != "" {
+		if _, err := service.CompileFilterExpression(filter); err != nil {
+			return dto.MessageQuery{}, err
+		}
+	}
+
+	jsonPath := ctx.QueryArray("jsonPath")
+	jsonPathMode := ctx.Query("jsonPathMode")
+	if _, err := service.ParseJSONPathFilter(jsonPath, jsonPathMode); err != nil {
+		return dto.MessageQuery{}, err
 	}
+
+	readCommitted, err := parseIsolation(ctx.Query("isolation"))
+	if err != nil {
+		return dto.MessageQuery{}, err
+	}
+
+	query := dto.MessageQuery{
+		Partition:   int32(partition),
+		Offset:      offset,
+		Offsets:     offsets,
+		Limit:       limit,
+		From:        from,
+		To:          to,
+		KeyFilter:   ctx.Query("keyFilter"),
+		ValueFilter: ctx.Query("valueFilter"),
+		JSONKey:     ctx.Query("jsonKey"),
+		JSONValue:   ctx.Query("jsonValue"),
+		Filter:      filter,
+		JSONPath:    jsonPath,
+		JSONPathAny: strings.EqualFold(strings.TrimSpace(jsonPathMode), "or"),
+		Encoding:    encoding,
+
+		ReadCommitted: readCommitted,
+		Batches:       ctx.Query("batches") == "true",
+		Ascending:     strings.EqualFold(strings.TrimSpace(ctx.Query("order")), "asc"),
+	}
+	if cursor != nil {
+		// a cursor carries the partition selection, positions and filters of the previous page
+		service.ApplyMessageCursor(&query, *cursor)
+	}
+	return query, nil
+}
+
+// parseIsolation reports whether the requested isolation level is read_committed.
+func parseIsolation(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "read_uncommitted":
+		return false, nil
+	case "read_committed":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown isolation %q, use read_committed or read_uncommitted", value)
+	}
+}
+
+// parseEncoding validates the requested rendering of raw message bytes.
+func parseEncoding(value string) (string, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(value)); encoding {
+	case "":
+		return dto.EncodingAuto, nil
+	case dto.EncodingAuto, dto.EncodingUTF8, dto.EncodingHex, dto.EncodingBase64:
+		return encoding, nil
+	case "utf-8":
+		return dto.EncodingUTF8, nil
+	default:
+		return "", fmt.Errorf("invalid encoding %q, expected utf8, hex, base64 or auto", value)
+	}
+}
+
+// parsePartitionList parses a comma separated list of partitions such as "0,2,5".
+func parsePartitionList(value string) ([]int32, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	var partitions []int32
+	for _, part := range strings.Split(value, ",") {
+		partition, err := strconv.ParseInt(strings.TrimSpace(part), 10, 32)
+		if err != nil || partition < 0 {
+			return nil, fmt.Errorf("invalid partition %q", part)
+		}
+		partitions = append(partitions, int32(partition))
+	}
+	return partitions, nil
+}
+
+// parsePartitionOffsets parses per-partition start offsets such as "0:15,1:20", as
+// returned in the partitions of the previous page.
+func parsePartitionOffsets(value string) (map[int32]int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	offsets := make(map[int32]int64)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		partitionStr, offsetStr, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected partition:offset, got %q", part)
+		}
+		partition, err := strconv.ParseInt(strings.TrimSpace(partitionStr), 10, 32)
+		if err != nil || partition < 0 {
+			return nil, fmt.Errorf("invalid partition %q", partitionStr)
+		}
+		offset, err := strconv.ParseInt(strings.TrimSpace(offsetStr), 10, 64)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("invalid offset %q", offsetStr)
+		}
+		offsets[int32(partition)] = offset
+	}
+	return offsets, nil
+}
+
+// parseTimestampParam accepts Unix millis or an RFC 3339 time and returns Unix millis, 0 when empty.
+func parseTimestampParam(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if millis < 0 {
+			return 0, errors.New("timestamp must not be negative")
+		}
+		return millis, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, errors.New("expected Unix milliseconds or RFC 3339 time")
+	}
+	return t.UnixMilli(), nil
 }
 
 // GetTopics retrieves all topics for a cluster
@@ -390,6 +552,60 @@ func (c *TopicController) ExpandPartitions(ctx *gin.Context) {
 	})
 }
 
+// DeleteRecords truncates partitions before an offset or timestamp, or previews it with dryRun
+func (c *TopicController) DeleteRecords(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	var req dto.DeleteRecordsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if err := service.ValidateDeleteRecords(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := c.topicService.DeleteRecords(uint(clusterID), ctx.Param("topic"), &req)
+	if errors.Is(err, service.ErrInvalidDeleteRecords) {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete records: " + err.Error(),
+		})
+		return
+	}
+
+	message := "Records deleted successfully"
+	if req.DryRun {
+		message = "Success"
+	}
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    result,
+	})
+}
+
 // GetTopicConfigs returns topic configuration entries.
 func (c *TopicController) GetTopicConfigs(ctx *gin.Context) {
 	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
@@ -466,9 +682,16 @@ func (c *TopicController) GetMessages(ctx *gin.Context) {
 	}
 
 	topicName := ctx.Param("topic")
-	query := parseMessageQuery(ctx, 100)
+	query, err := parseMessageQuery(ctx, 100)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
 
-	messages, err := c.topicService.GetMessages(uint(clusterID), topicName, query.partition, query.offset, query.limit, query.keyFilter, query.valueFilter, query.jsonKey, query.jsonValue)
+	page, err := c.topicService.GetMessages(uint(clusterID), topicName, query)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, dto.Response{
 			Code:    http.StatusInternalServerError,
@@ -480,11 +703,82 @@ func (c *TopicController) GetMessages(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, dto.Response{
 		Code:    http.StatusOK,
 		Message: "Success",
-		Data:    messages,
+		Data:    page,
+	})
+}
+
+// GetMessage retrieves the single message at a partition and offset
+func (c *TopicController) GetMessage(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	partition, err := strconv.ParseInt(ctx.Param("partition"), 10, 32)
+	if err != nil || partition < 0 {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid partition",
+		})
+		return
+	}
+	offset, err := strconv.ParseInt(ctx.Param("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid offset",
+		})
+		return
+	}
+	encoding, err := parseEncoding(ctx.Query("encoding"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	readCommitted, err := parseIsolation(ctx.Query("isolation"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	message, err := c.topicService.GetMessage(uint(clusterID), ctx.Param("topic"), int32(partition), offset, encoding, readCommitted)
+	if errors.Is(err, service.ErrMessageNotFound) {
+		ctx.JSON(http.StatusNotFound, dto.Response{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("No message at partition %d offset %d", partition, offset),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve message: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    message,
 	})
 }
 
-// GetMessagesLive streams newly consumed messages from a topic partition.
+// GetMessagesLive streams new messages of one or more partitions as server-sent events.
+// A single consumer follows the partitions for the lifetime of the connection; records are
+// pushed as they arrive and a ping carrying the partition positions is sent every
+// liveHeartbeatInterval. The stream stops when the client disconnects.
 func (c *TopicController) GetMessagesLive(ctx *gin.Context) {
 	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
 	if err != nil {
@@ -496,12 +790,22 @@ func (c *TopicController) GetMessagesLive(ctx *gin.Context) {
 	}
 
 	topicName := ctx.Param("topic")
-	query := parseMessageQuery(ctx, 20)
-
-	ctx.Writer.Header().Set("Content-Type", "text/event-stream")
-	ctx.Writer.Header().Set("Cache-Control", "no-cache")
-	ctx.Writer.Header().Set("Connection", "keep-alive")
-	ctx.Writer.Header().Set("X-Accel-Buffering", "no")
+	query, err := parseMessageQuery(ctx, 20)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	partitions, err := parsePartitionList(ctx.Query("partitions"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
 
 	flusher, ok := ctx.Writer.(http.Flusher)
 	if !ok {
@@ -512,58 +816,123 @@ func (c *TopicController) GetMessagesLive(ctx *gin.Context) {
 		return
 	}
 
+	tail, err := c.topicService.StartLiveTail(ctx.Request.Context(), uint(clusterID), topicName, partitions, query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to start live tail: " + err.Error(),
+		})
+		return
+	}
+	defer tail.Close()
+
+	ctx.Writer.Header().Set("Content-Type", "text/event-stream")
+	ctx.Writer.Header().Set("Cache-Control", "no-cache")
+	ctx.Writer.Header().Set("Connection", "keep-alive")
+	ctx.Writer.Header().Set("X-Accel-Buffering", "no")
+
 	sendEvent := func(name string, data any) {
 		ctx.SSEvent(name, data)
 		flusher.Flush()
 	}
 
-	currentOffset := query.offset
-	sendEvent("connected", gin.H{"status": "connected"})
+	sendEvent("connected", gin.H{"status": "connected", "partitions": tail.Positions()})
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
 
 	for {
 		select {
 		case <-ctx.Request.Context().Done():
 			return
-		default:
-		}
-
-		messages, err := c.topicService.GetMessages(uint(clusterID), topicName, query.partition, currentOffset, query.limit, query.keyFilter, query.valueFilter, query.jsonKey, query.jsonValue)
-		if err != nil {
-			sendEvent("error", gin.H{"message": "Failed to retrieve messages: " + err.Error()})
+		case <-heartbeat.C:
+			sendEvent("ping", gin.H{
+				"partitions": tail.Positions(),
+				"timestamp":  time.Now().UnixMilli(),
+			})
+		case <-tail.Done():
+			sendEvent("end", gin.H{"partitions": tail.Positions()})
 			return
-		}
-
-		beginningOffset, endOffset, err := c.topicService.GetPartitionOffsets(uint(clusterID), topicName, query.partition)
-		if err != nil {
-			beginningOffset = -1
-			endOffset = -1
-		}
+		case record := <-tail.Records():
+			// send the records that are already waiting together
+			messages := []dto.MessageRecord{record.MessageRecord}
+		drain:
+			for len(messages) < query.Limit {
+				select {
+				case record := <-tail.Records():
+					messages = append(messages, record.MessageRecord)
+				default:
+					break drain
+				}
+			}
 
-		if len(messages) == 0 {
-			sendEvent("ping", gin.H{
-				"partition":       query.partition,
-				"beginningOffset": beginningOffset,
-				"endOffset":       endOffset,
-				"timestamp":       time.Now().UnixMilli(),
+			sendEvent("topic-message-event", gin.H{
+				"partitions": tail.Positions(),
+				"messages":   messages,
 			})
-			continue
 		}
+	}
+}
 
-		maxOffset := currentOffset
-		for _, message := range messages {
-			if message.Offset >= maxOffset {
-				maxOffset = message.Offset + 1
-			}
-		}
-		currentOffset = maxOffset
+// GetMessagesSocket runs a live tail over a WebSocket. It takes the same parameters as
+// GetMessagesLive plus "overflow" (drop or sample) for clients that fall behind, and
+// accepts commands to pause, resume, change the filter, seek and switch partitions.
+func (c *TopicController) GetMessagesSocket(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	query, err := parseMessageQuery(ctx, 20)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	partitions, err := parsePartitionList(ctx.Query("partitions"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	overflow, err := parseOverflowMode(ctx.Query("overflow"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
 
-		sendEvent("topic-message-event", gin.H{
-			"partition":       query.partition,
-			"beginningOffset": beginningOffset,
-			"endOffset":       endOffset,
-			"messages":        messages,
+	// the tail outlives the request context once the connection is hijacked
+	tailCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tail, err := c.topicService.StartLiveTail(tailCtx, uint(clusterID), ctx.Param("topic"), partitions, query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to start live tail: " + err.Error(),
 		})
+		return
+	}
+	defer tail.Close()
+
+	server := websocket.Server{
+		// authentication is handled by the middleware and CORS is open, so any origin is accepted
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			serveLiveSocket(ws, tail, newLiveQueue(liveQueueCapacity, overflow, tail.Current))
+		},
 	}
+	server.ServeHTTP(ctx.Writer, ctx.Request)
 }
 
 // SendMessage sends a message to a topic
@@ -588,7 +957,16 @@ func (c *TopicController) SendMessage(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.topicService.SendMessage(uint(clusterID), topicName, &req); err != nil {
+	if err := service.ValidateSendMessage(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := c.topicService.SendMessage(uint(clusterID), topicName, &req)
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, dto.Response{
 			Code:    http.StatusInternalServerError,
 			Message: "Failed to send message: " + err.Error(),
@@ -596,8 +974,234 @@ func (c *TopicController) SendMessage(ctx *gin.Context) {
 		return
 	}
 
+	if result.Failed == len(result.Records) {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to send message: " + result.Records[0].Error,
+			Data:    result,
+		})
+		return
+	}
+
+	message := "Message sent successfully"
+	if result.Failed > 0 {
+		message = fmt.Sprintf("%d of %d records failed", result.Failed, len(result.Records))
+	}
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    result,
+	})
+}
+
+// importFormat picks the import format from the query or else the file extension.
+func importFormat(value, filename string) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(value))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".jsonl", ".ndjson", ".json":
+			format = dto.ImportFormatJSONL
+		case ".csv":
+			format = dto.ImportFormatCSV
+		}
+	}
+	switch format {
+	case dto.ImportFormatJSONL, dto.ImportFormatCSV:
+		return format, nil
+	case "":
+		return "", fmt.Errorf("cannot tell the format of %q, pass format=jsonl or format=csv", filename)
+	default:
+		return "", fmt.Errorf("invalid format %q, expected jsonl or csv", value)
+	}
+}
+
+func parseImportOptions(ctx *gin.Context) (dto.ImportOptions, error) {
+	options := dto.ImportOptions{Compression: ctx.Query("compression")}
+	if value := ctx.Query("dryRun"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, fmt.Errorf("invalid dryRun %q", value)
+		}
+		options.DryRun = dryRun
+	}
+	if value := ctx.Query("rate"); value != "" {
+		rate, err := strconv.Atoi(value)
+		if err != nil || rate < 0 {
+			return options, fmt.Errorf("invalid rate %q, expected records per second", value)
+		}
+		options.Rate = rate
+	}
+	return options, nil
+}
+
+// ImportMessages streams a JSONL or CSV file uploaded as the multipart "file" field into a topic
+func (c *TopicController) ImportMessages(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	options, err := parseImportOptions(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	// read the part straight from the request instead of spooling the whole file
+	reader, err := ctx.Request.MultipartReader()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	var part *multipart.Part
+	for {
+		part, err = reader.NextPart()
+		if err != nil || part.FormName() == "file" {
+			break
+		}
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: missing file",
+		})
+		return
+	}
+	defer part.Close()
+
+	if options.Format, err = importFormat(ctx.Query("format"), part.FileName()); err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := c.topicService.ImportMessages(ctx.Request.Context(), uint(clusterID), ctx.Param("topic"), part, options)
+	if errors.Is(err, service.ErrInvalidImportFile) {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to import messages: " + err.Error(),
+			Data:    result,
+		})
+		return
+	}
+
+	message := "Import finished"
+	if options.DryRun {
+		message = "Dry run finished"
+	}
 	ctx.JSON(http.StatusOK, dto.Response{
 		Code:    http.StatusOK,
-		Message: "Message sent successfully",
+		Message: message,
+		Data:    result,
 	})
 }
+
+// exportContentTypes maps export formats to the content type and file extension of the download.
+var exportContentTypes = map[string][2]string{
+	dto.ExportFormatJSONL: {"application/x-ndjson", "jsonl"},
+	dto.ExportFormatCSV:   {"text/csv; charset=utf-8", "csv"},
+	dto.ExportFormatRaw:   {"application/octet-stream", "txt"},
+}
+
+func parseExportFormat(value string) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(value))
+	if format == "" {
+		return dto.ExportFormatJSONL, nil
+	}
+	if _, ok := exportContentTypes[format]; !ok {
+		return "", fmt.Errorf("invalid format %q, expected jsonl, csv or raw", value)
+	}
+	return format, nil
+}
+
+// ExportMessages streams every record matching the GetMessages filters as a download.
+// Records are written as they are read, so the response uses chunked transfer; the
+// record count and any error that happened mid-stream are sent as trailers.
+func (c *TopicController) ExportMessages(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	format, err := parseExportFormat(ctx.Query("format"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	// without a limit everything in range is exported
+	query, err := parseMessageQuery(ctx, 0)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if ctx.Query("partition") == "" && ctx.Query("cursor") == "" {
+		query.Partition = dto.AllPartitions
+	}
+	query.Backward = false
+	if value := ctx.Query("endOffset"); value != "" {
+		until, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || until <= 0 {
+			ctx.JSON(http.StatusBadRequest, dto.Response{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid request: invalid endOffset",
+			})
+			return
+		}
+		query.Until = until
+	}
+
+	topicName := ctx.Param("topic")
+	export, err := c.topicService.PrepareExport(uint(clusterID), topicName, query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to export messages: " + err.Error(),
+		})
+		return
+	}
+	defer export.Close()
+
+	contentType := exportContentTypes[format]
+	filename := fmt.Sprintf("%s-%s.%s", topicName, time.Now().Format("20060102-150405"), contentType[1])
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", contentType[0])
+	header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	header.Set("X-Accel-Buffering", "no")
+	header.Set("Trailer", "X-Export-Records, X-Export-Error")
+	ctx.Status(http.StatusOK)
+
+	written, err := export.WriteTo(ctx.Request.Context(), ctx.Writer, format)
+	header.Set("X-Export-Records", strconv.FormatInt(written, 10))
+	if err != nil && ctx.Request.Context().Err() == nil {
+		header.Set("X-Export-Error", err.Error())
+	}
+}
@@ -78,3 +78,169 @@ func TestNormalizeCreateTopicRequestRejectsMissingPartitions(t *testing.T) {
 		t.Fatal("normalizeCreateTopicRequest() error = nil, want error")
 	}
 }
+
+func TestParseTimestampParam(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			value: "",
+			want:  0,
+		},
+		{
+			name:  "unix millis",
+			value: "1700000000000",
+			want:  1700000000000,
+		},
+		{
+			name:  "rfc3339",
+			value: "2023-11-14T22:13:20Z",
+			want:  1700000000000,
+		},
+		{
+			name:    "negative millis",
+			value:   "-5",
+			wantErr: true,
+		},
+		{
+			name:    "garbage",
+			value:   "yesterday",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTimestampParam(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTimestampParam() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("parseTimestampParam() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePartitionOffsets(t *testing.T) {
+	got, err := parsePartitionOffsets("0:15, 3:20")
+	if err != nil {
+		t.Fatalf("parsePartitionOffsets() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != 15 || got[3] != 20 {
+		t.Fatalf("parsePartitionOffsets() = %v, want map[0:15 3:20]", got)
+	}
+
+	for _, value := range []string{"0", "x:1", "0:-1"} {
+		if _, err := parsePartitionOffsets(value); err == nil {
+			t.Fatalf("parsePartitionOffsets(%q) error = nil, want error", value)
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", value: "", want: dto.EncodingAuto},
+		{name: "hex", value: "hex", want: dto.EncodingHex},
+		{name: "case insensitive", value: " Base64 ", want: dto.EncodingBase64},
+		{name: "utf-8 alias", value: "UTF-8", want: dto.EncodingUTF8},
+		{name: "decoded encodings are not selectable", value: "avro", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEncoding(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEncoding() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("parseEncoding() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIsolation(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{value: "", want: false},
+		{value: "read_uncommitted", want: false},
+		{value: " READ_COMMITTED ", want: true},
+		{value: "serializable", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseIsolation(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseIsolation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("parseIsolation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		filename string
+		want     string
+		wantErr  bool
+	}{
+		{name: "explicit format wins", value: "CSV", filename: "records.jsonl", want: dto.ImportFormatCSV},
+		{name: "jsonl extension", filename: "capture.ndjson", want: dto.ImportFormatJSONL},
+		{name: "csv extension", filename: "Fixtures.CSV", want: dto.ImportFormatCSV},
+		{name: "unknown extension", filename: "records.txt", wantErr: true},
+		{name: "unknown format", value: "avro", filename: "records.csv", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := importFormat(tt.value, tt.filename)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("importFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("importFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: dto.ExportFormatJSONL},
+		{value: "CSV", want: dto.ExportFormatCSV},
+		{value: "raw", want: dto.ExportFormatRaw},
+		{value: "parquet", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseExportFormat(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseExportFormat(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Fatalf("parseExportFormat(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const maxTablePageSize = 500
+
+type TopicTableController struct {
+	tableService *service.TopicTableService
+}
+
+func NewTopicTableController(tableService *service.TopicTableService) *TopicTableController {
+	return &TopicTableController{tableService: tableService}
+}
+
+// GetTopicTable returns a page of the latest value per key of a compacted topic
+func (c *TopicTableController) GetTopicTable(ctx *gin.Context) {
+	clusterID, err := strconv.ParseUint(ctx.Query("clusterId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid cluster ID",
+		})
+		return
+	}
+
+	pageIndex, err := strconv.Atoi(ctx.DefaultQuery("pageIndex", "1"))
+	if err != nil || pageIndex < 1 {
+		pageIndex = 1
+	}
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > maxTablePageSize {
+		pageSize = maxTablePageSize
+	}
+
+	encoding, err := parseEncoding(ctx.Query("encoding"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, dto.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	table, err := c.tableService.GetTable(ctx.Request.Context(), uint(clusterID), ctx.Param("topic"), dto.TopicTableQuery{
+		Key:       ctx.Query("key"),
+		PageIndex: pageIndex,
+		PageSize:  pageSize,
+		Encoding:  encoding,
+		Refresh:   ctx.Query("refresh") == "true",
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrTopicNotCompacted) {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, dto.Response{
+			Code:    status,
+			Message: "Failed to build table view: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    table,
+	})
+}
@@ -4,19 +4,20 @@ import "time"
 
 // ClusterInfo represents cluster information with statistics
 type ClusterInfo struct {
-	ID               uint      `json:"id"`
-	Name             string    `json:"name"`
-	Servers          string    `json:"servers"`
-	SecurityProtocol string    `json:"securityProtocol"`
-	SaslMechanism    string    `json:"saslMechanism"`
-	SaslUsername     string    `json:"saslUsername"`
-	CreatedAt        time.Time `json:"createdAt"`
-	UpdatedAt        time.Time `json:"updatedAt"`
-	BrokerCount      int       `json:"brokerCount"`
-	TopicCount       int       `json:"topicCount"`
-	ConsumerCount    int       `json:"consumerCount"`
-	PartitionCount   int       `json:"partitionCount"`
-	ReplicaCount     int       `json:"replicaCount"`
+	ID                uint      `json:"id"`
+	Name              string    `json:"name"`
+	Servers           string    `json:"servers"`
+	SecurityProtocol  string    `json:"securityProtocol"`
+	SaslMechanism     string    `json:"saslMechanism"`
+	SaslUsername      string    `json:"saslUsername"`
+	SchemaRegistryURL string    `json:"schemaRegistryUrl"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+	BrokerCount       int       `json:"brokerCount"`
+	TopicCount        int       `json:"topicCount"`
+	ConsumerCount     int       `json:"consumerCount"`
+	PartitionCount    int       `json:"partitionCount"`
+	ReplicaCount      int       `json:"replicaCount"`
 }
 
 // BrokerInfo is a lightweight broker descriptor used in various places.
@@ -174,21 +175,256 @@ type ConsumerGroupOffset struct {
 	Lag       int64  `json:"lag"`
 }
 
+// Encodings used to render message keys, values and header values. Auto renders
+// printable UTF-8 as text and anything else as hex. Avro and Protobuf are reported
+// for payloads decoded to JSON and cannot be requested.
+const (
+	EncodingAuto     = "auto"
+	EncodingUTF8     = "utf8"
+	EncodingHex      = "hex"
+	EncodingBase64   = "base64"
+	EncodingAvro     = "avro"
+	EncodingProtobuf = "protobuf"
+)
+
 // MessageRecord represents a Kafka message
 type MessageRecord struct {
-	Partition int32             `json:"partition"`
-	Offset    int64             `json:"offset"`
+	Partition     int32           `json:"partition"`
+	Offset        int64           `json:"offset"`
+	Key           string          `json:"key"`
+	KeyEncoding   string          `json:"keyEncoding"`
+	Value         string          `json:"value"`
+	ValueEncoding string          `json:"valueEncoding"`
+	Timestamp     int64           `json:"timestamp"`
+	Headers       []MessageHeader `json:"headers"`
+	KeySchemaID   *int32          `json:"keySchemaId,omitempty"`   // set when the key was decoded with a registry schema
+	ValueSchemaID *int32          `json:"valueSchemaId,omitempty"` // set when the value was decoded with a registry schema
+	DecodeError   string          `json:"decodeError,omitempty"`
+	TimestampType string          `json:"timestampType,omitempty"` // CreateTime or LogAppendTime, set when read with batch metadata
+	Batch         *RecordBatch    `json:"batch,omitempty"`         // batch the record was written in, set when read with batch metadata
+}
+
+// Record timestamp types
+const (
+	TimestampCreateTime    = "CreateTime"
+	TimestampLogAppendTime = "LogAppendTime"
+)
+
+// RecordBatch is the metadata of the record batch a record was written in.
+// Producer fields are -1 for records that were not written by an idempotent producer.
+type RecordBatch struct {
+	BaseOffset           int64  `json:"baseOffset"`
+	LastOffset           int64  `json:"lastOffset"`
+	RecordCount          int    `json:"recordCount"`
+	Magic                int8   `json:"magic"`
+	PartitionLeaderEpoch int32  `json:"partitionLeaderEpoch"`
+	ProducerID           int64  `json:"producerId"`
+	ProducerEpoch        int16  `json:"producerEpoch"`
+	BaseSequence         int32  `json:"baseSequence"`
+	Transactional        bool   `json:"transactional"`
+	Control              bool   `json:"control"`
+	Compression          string `json:"compression"`
+	FirstTimestamp       int64  `json:"firstTimestamp"`
+	MaxTimestamp         int64  `json:"maxTimestamp"`
+}
+
+// MessageHeader is a record header. Headers keep their log order and may repeat keys.
+type MessageHeader struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Encoding string `json:"encoding"`
+}
+
+// AllPartitions selects every partition of a topic in a MessageQuery.
+const AllPartitions int32 = -1
+
+// MessageQuery describes which records to read from a topic.
+type MessageQuery struct {
+	Partition   int32
+	Offset      int64
+	Offsets     map[int32]int64 // per-partition start offsets, taking precedence over Offset
+	Backward    bool            // read the records right before the start offsets instead of from them
+	Limit       int
+	From        int64 // inclusive lower timestamp bound in Unix millis, 0 when unset
+	To          int64 // inclusive upper timestamp bound in Unix millis, 0 when unset
+	Until       int64 // exclusive upper offset bound on every partition, 0 when unset
+	KeyFilter   string
+	ValueFilter string
+	JSONKey     string
+	JSONValue   string
+	Filter      string   // filter expression, see service.FilterExpression
+	JSONPath    []string // JSONPath conditions on the value, see service.JSONPathCondition
+	JSONPathAny bool     // combine JSONPath conditions with OR instead of AND
+	Encoding    string   // rendering of raw keys, values and headers, EncodingAuto when empty
+	// ReadCommitted reads with read_committed isolation: records of aborted and open
+	// transactions are skipped and reads end at the last stable offset
+	ReadCommitted bool
+	Batches       bool // attach the timestamp type and batch metadata to every record
+	Ascending     bool // list a page oldest first instead of newest first
+}
+
+// MessagePage is a page of messages together with the offset to continue from on each
+// partition and opaque cursors for the next and previous page.
+type MessagePage struct {
+	Messages   []MessageRecord     `json:"messages"`
+	Partitions []PartitionPosition `json:"partitions"`
+	NextCursor string              `json:"nextCursor"`
+	PrevCursor string              `json:"prevCursor"`
+}
+
+// PartitionPosition is the next offset to read on a partition.
+type PartitionPosition struct {
+	Partition int32 `json:"partition"`
+	Offset    int64 `json:"offset"`
+}
+
+// LivePartition is the position of a live tail on a partition.
+type LivePartition struct {
+	Partition int32 `json:"partition"`
+	Offset    int64 `json:"offset"`    // next offset the tail reads
+	EndOffset int64 `json:"endOffset"` // current end offset of the partition
+}
+
+// Live tail WebSocket command types
+const (
+	LiveCommandPause      = "pause"
+	LiveCommandResume     = "resume"
+	LiveCommandFilter     = "filter"
+	LiveCommandSeek       = "seek"
+	LiveCommandPartitions = "partitions"
+)
+
+// LiveCommand is sent by a WebSocket live tail client to change the running tail.
+type LiveCommand struct {
+	Type string `json:"type"`
+
+	// filter
+	KeyFilter    string   `json:"keyFilter"`
+	ValueFilter  string   `json:"valueFilter"`
+	JSONKey      string   `json:"jsonKey"`
+	JSONValue    string   `json:"jsonValue"`
+	Filter       string   `json:"filter"`
+	JSONPath     []string `json:"jsonPath"`
+	JSONPathMode string   `json:"jsonPathMode"`
+
+	// seek: a position ("beginning" or "end"), an offset on one or every partition, or a timestamp
+	Position  string `json:"position"`
+	Offset    *int64 `json:"offset"`
+	Partition *int32 `json:"partition"`
+	Timestamp int64  `json:"timestamp"`
+
+	// partitions to follow, every partition when empty
+	Partitions []int32 `json:"partitions"`
+}
+
+// LiveEvent is sent to a WebSocket live tail client.
+type LiveEvent struct {
+	Type       string          `json:"type"` // connected, messages, ping, ack, error or end
+	Command    string          `json:"command,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Messages   []MessageRecord `json:"messages,omitempty"`
+	Partitions []LivePartition `json:"partitions,omitempty"`
+	Paused     bool            `json:"paused"`
+	Dropped    int64           `json:"dropped"` // records skipped so far because the client fell behind
+	Timestamp  int64           `json:"timestamp,omitempty"`
+}
+
+// Cursor directions
+const (
+	CursorNext = "next"
+	CursorPrev = "prev"
+)
+
+// MessageCursor is the decoded form of the cursors handed out with a MessagePage. It
+// carries the time range and filters of the query it was issued for, so the following
+// pages select the same records without the client sending them again.
+type MessageCursor struct {
+	Partition int32           `json:"p"`
+	Direction string          `json:"d"`
+	Offsets   map[int32]int64 `json:"o"`
+
+	From          int64    `json:"from,omitempty"`
+	To            int64    `json:"to,omitempty"`
+	KeyFilter     string   `json:"kf,omitempty"`
+	ValueFilter   string   `json:"vf,omitempty"`
+	JSONKey       string   `json:"jk,omitempty"`
+	JSONValue     string   `json:"jv,omitempty"`
+	Filter        string   `json:"f,omitempty"`
+	JSONPath      []string `json:"jp,omitempty"`
+	JSONPathAny   bool     `json:"jpa,omitempty"`
+	Encoding      string   `json:"e,omitempty"`
+	ReadCommitted bool     `json:"rc,omitempty"`
+	Ascending     bool     `json:"asc,omitempty"`
+}
+
+// ProduceRecord is one record of a send request. A tombstone has a null value and must
+// be asked for explicitly, so a record missing its value is rejected rather than deleting the key.
+type ProduceRecord struct {
 	Key       string            `json:"key"`
-	Value     string            `json:"value"`
-	Timestamp int64             `json:"timestamp"`
-	Headers   map[string]string `json:"headers,omitempty"`
+	Value     *string           `json:"value"`
+	Tombstone bool              `json:"tombstone"`
+	Headers   map[string]string `json:"headers"`
+	Partition *int32            `json:"partition"` // chosen by the key hash when omitted
+	Timestamp int64             `json:"timestamp"` // milliseconds, the producer's clock when zero
 }
 
-// SendMessageRequest represents message send request
+// SendMessageRequest represents message send request: either a single record or a batch in Records
 type SendMessageRequest struct {
-	Key     string            `json:"key"`
-	Value   string            `json:"value" binding:"required"`
-	Headers map[string]string `json:"headers"`
+	ProduceRecord
+	Records     []ProduceRecord `json:"records"`
+	Compression string          `json:"compression"` // none, gzip, snappy, lz4 or zstd
+}
+
+// ProducedRecord is where a record of a send request landed, or why it failed.
+type ProducedRecord struct {
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SendMessageResult lists the outcome of each record in request order.
+type SendMessageResult struct {
+	Records []ProducedRecord `json:"records"`
+	Failed  int              `json:"failed"`
+}
+
+// Export formats
+const (
+	ExportFormatJSONL = "jsonl"
+	ExportFormatCSV   = "csv"
+	ExportFormatRaw   = "raw" // raw values, one per line
+)
+
+// Import file formats
+const (
+	ImportFormatJSONL = "jsonl"
+	ImportFormatCSV   = "csv"
+)
+
+// ImportOptions controls a bulk import of records from a file.
+type ImportOptions struct {
+	Format      string
+	DryRun      bool // parse and validate every line without producing
+	Rate        int  // records per second, unlimited when zero
+	Compression string
+}
+
+// ImportLineError reports why a line of an import file was not produced.
+type ImportLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportResult summarizes a bulk import.
+type ImportResult struct {
+	DryRun     bool              `json:"dryRun"`
+	Lines      int               `json:"lines"`    // JSONL lines or CSV rows read, blank lines and the header excluded
+	Records    int               `json:"records"`  // lines parsed into valid records
+	Produced   int               `json:"produced"` // records acknowledged by the brokers
+	Failed     int               `json:"failed"`   // lines that failed to parse, validate or produce
+	Errors     []ImportLineError `json:"errors"`
+	Truncated  bool              `json:"truncated"` // more errors occurred than are listed
+	DurationMs int64             `json:"durationMs"`
 }
 
 // OffsetResetRequest represents offset reset request
@@ -197,3 +433,561 @@ type OffsetResetRequest struct {
 	Seek   string `json:"seek"`
 	Offset int64  `json:"offset"`
 }
+
+// ProtoDescriptorSetInfo describes an uploaded protobuf descriptor set.
+type ProtoDescriptorSetInfo struct {
+	ID           uint      `json:"id"`
+	ClusterID    uint      `json:"clusterId"`
+	Name         string    `json:"name"`
+	MessageTypes []string  `json:"messageTypes"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// TopicProtoBindingRequest binds message types of a descriptor set to a topic.
+type TopicProtoBindingRequest struct {
+	DescriptorSetID  uint   `json:"descriptorSetId" binding:"required"`
+	KeyMessageType   string `json:"keyMessageType"`
+	ValueMessageType string `json:"valueMessageType"`
+}
+
+// Search job states
+const (
+	SearchJobRunning   = "running"
+	SearchJobCompleted = "completed"
+	SearchJobCancelled = "cancelled"
+	SearchJobFailed    = "failed"
+)
+
+// SearchJobRequest starts a background search over a whole topic or a time range of it.
+type SearchJobRequest struct {
+	Partitions   []int32  `json:"partitions"` // empty to search every partition
+	From         int64    `json:"from"`       // inclusive lower timestamp bound in Unix millis, 0 when unset
+	To           int64    `json:"to"`         // inclusive upper timestamp bound in Unix millis, 0 when unset
+	KeyFilter    string   `json:"keyFilter"`
+	ValueFilter  string   `json:"valueFilter"`
+	JSONKey      string   `json:"jsonKey"`
+	JSONValue    string   `json:"jsonValue"`
+	Filter       string   `json:"filter"`
+	JSONPath     []string `json:"jsonPath"`
+	JSONPathMode string   `json:"jsonPathMode"`
+	Encoding     string   `json:"encoding"`
+	MaxMatches   int      `json:"maxMatches"` // stop after this many matches
+}
+
+// SearchJobInfo describes a search job and its progress.
+type SearchJobInfo struct {
+	ID             uint                      `json:"id"`
+	ClusterID      uint                      `json:"clusterId"`
+	Topic          string                    `json:"topic"`
+	Status         string                    `json:"status"`
+	Error          string                    `json:"error,omitempty"`
+	Request        SearchJobRequest          `json:"request"`
+	ScannedRecords int64                     `json:"scannedRecords"` // offsets scanned so far
+	TotalRecords   int64                     `json:"totalRecords"`   // offsets to scan in total
+	MatchCount     int64                     `json:"matchCount"`
+	Truncated      bool                      `json:"truncated"` // more records matched than maxMatches, the scan stopped early
+	Partitions     []SearchPartitionProgress `json:"partitions"`
+	CreatedAt      time.Time                 `json:"createdAt"`
+	FinishedAt     *time.Time                `json:"finishedAt,omitempty"`
+}
+
+// SearchPartitionProgress is the scan position of a search job on one partition.
+type SearchPartitionProgress struct {
+	Partition     int32 `json:"partition"`
+	StartOffset   int64 `json:"startOffset"`
+	EndOffset     int64 `json:"endOffset"`
+	CurrentOffset int64 `json:"currentOffset"` // next offset to scan
+	Matches       int64 `json:"matches"`
+}
+
+// SearchMatch is a record found by a search job. IDs increase in the order matches
+// were found, so clients can poll for matches after the last ID they have seen.
+type SearchMatch struct {
+	ID     uint          `json:"id"`
+	Record MessageRecord `json:"record"`
+}
+
+// Backup states
+const (
+	BackupRunning   = "running"
+	BackupCompleted = "completed"
+	BackupFailed    = "failed"
+)
+
+// BackupManifestVersion is the layout version of the backups written by this build.
+const BackupManifestVersion = 1
+
+// BackupManifest describes a topic backup. It is stored as manifest.json next to one
+// gzipped JSONL file of BackupRecord lines per partition.
+type BackupManifest struct {
+	Version           int               `json:"version"`
+	ID                string            `json:"id"`
+	ClusterID         uint              `json:"clusterId"`
+	ClusterName       string            `json:"clusterName"`
+	Topic             string            `json:"topic"`
+	Status            string            `json:"status"`
+	Error             string            `json:"error,omitempty"`
+	ReplicationFactor int16             `json:"replicationFactor"`
+	Configs           []TopicConfig     `json:"configs"`
+	Partitions        []BackupPartition `json:"partitions"`
+	Records           int64             `json:"records"`
+	Size              int64             `json:"size"` // bytes of all partition files
+	CreatedAt         time.Time         `json:"createdAt"`
+	FinishedAt        *time.Time        `json:"finishedAt,omitempty"`
+}
+
+// BackupPartition is the part of a backup holding one partition.
+type BackupPartition struct {
+	Partition   int32  `json:"partition"`
+	StartOffset int64  `json:"startOffset"`
+	EndOffset   int64  `json:"endOffset"`  // exclusive
+	LastOffset  int64  `json:"lastOffset"` // last offset read, -1 before the first record
+	Records     int64  `json:"records"`
+	File        string `json:"file"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+// BackupRecord is one record of a backup with its original offset. A null key or
+// value is kept apart from an empty one.
+type BackupRecord struct {
+	Offset    int64          `json:"offset"`
+	Timestamp int64          `json:"timestamp"`
+	Key       []byte         `json:"key"`
+	Value     []byte         `json:"value"`
+	Headers   []BackupHeader `json:"headers,omitempty"`
+}
+
+// BackupHeader is a record header of a backup.
+type BackupHeader struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// BackupVerification is the result of checking the partition files of a backup against
+// the checksums in its manifest.
+type BackupVerification struct {
+	ID         string                 `json:"id"`
+	Valid      bool                   `json:"valid"`
+	Partitions []BackupPartitionCheck `json:"partitions"`
+}
+
+// BackupPartitionCheck is the checksum comparison of one partition file.
+type BackupPartitionCheck struct {
+	Partition int32  `json:"partition"`
+	File      string `json:"file"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual,omitempty"`
+	Valid     bool   `json:"valid"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RestoreBackupRequest restores a backup into a new topic, keeping every record on the
+// partition it was backed up from.
+type RestoreBackupRequest struct {
+	ClusterID         uint   `json:"clusterId"` // target cluster, the backup's cluster when zero
+	Topic             string `json:"topic" binding:"required"`
+	ReplicationFactor int16  `json:"replicationFactor"` // the backed up topic's when zero
+	SkipConfigs       bool   `json:"skipConfigs"`       // create the topic without the backed up configs
+	Compression       string `json:"compression"`
+}
+
+// RestoreResult summarizes a restored backup.
+type RestoreResult struct {
+	ID         string              `json:"id"`
+	ClusterID  uint                `json:"clusterId"`
+	Topic      string              `json:"topic"`
+	Records    int64               `json:"records"`
+	Partitions []RestoredPartition `json:"partitions"`
+	DurationMs int64               `json:"durationMs"`
+}
+
+// RestoredPartition counts the records restored into a partition.
+type RestoredPartition struct {
+	Partition int32 `json:"partition"`
+	Records   int64 `json:"records"`
+}
+
+// Replay job states
+const (
+	ReplayJobRunning   = "running"
+	ReplayJobCompleted = "completed"
+	ReplayJobCancelled = "cancelled"
+	ReplayJobFailed    = "failed"
+)
+
+// Partition strategies of a replay job
+const (
+	ReplayPartitionPreserve   = "preserve"    // same partition number as in the source topic
+	ReplayPartitionKey        = "key"         // hash of the key, like the default partitioner
+	ReplayPartitionRoundRobin = "round-robin" // spread evenly over the target partitions
+)
+
+// ReplayJobRequest copies an offset or time range of a topic to another topic, on the same
+// or another cluster, optionally keeping only the records that match the filters.
+type ReplayJobRequest struct {
+	Partitions        []int32  `json:"partitions"`  // source partitions, empty for every partition
+	StartOffset       *int64   `json:"startOffset"` // first offset on every partition, the beginning when unset
+	EndOffset         *int64   `json:"endOffset"`   // exclusive upper offset, the current end when unset
+	From              int64    `json:"from"`        // inclusive lower timestamp bound in Unix millis, 0 when unset
+	To                int64    `json:"to"`          // inclusive upper timestamp bound in Unix millis, 0 when unset
+	KeyFilter         string   `json:"keyFilter"`
+	ValueFilter       string   `json:"valueFilter"`
+	JSONKey           string   `json:"jsonKey"`
+	JSONValue         string   `json:"jsonValue"`
+	Filter            string   `json:"filter"`
+	JSONPath          []string `json:"jsonPath"`
+	JSONPathMode      string   `json:"jsonPathMode"`
+	Encoding          string   `json:"encoding"`        // accepted like the data query, filters match the payload bytes
+	TargetClusterID   uint     `json:"targetClusterId"` // the source cluster when zero
+	TargetTopic       string   `json:"targetTopic" binding:"required"`
+	PartitionStrategy string   `json:"partitionStrategy"` // preserve, key or round-robin, key when empty
+	Rate              int      `json:"rate"`              // records per second, 0 for no limit
+	Compression       string   `json:"compression"`
+	ResetTimestamps   bool     `json:"resetTimestamps"` // stamp records with the replay time instead of the original one
+}
+
+// ReplayJobInfo describes a replay job and its progress.
+type ReplayJobInfo struct {
+	ID              uint                      `json:"id"`
+	ClusterID       uint                      `json:"clusterId"`
+	Topic           string                    `json:"topic"`
+	TargetClusterID uint                      `json:"targetClusterId"`
+	TargetTopic     string                    `json:"targetTopic"`
+	Status          string                    `json:"status"`
+	Error           string                    `json:"error,omitempty"`
+	Request         ReplayJobRequest          `json:"request"`
+	ScannedRecords  int64                     `json:"scannedRecords"` // offsets scanned so far
+	TotalRecords    int64                     `json:"totalRecords"`   // offsets to scan in total
+	Copied          int64                     `json:"copied"`
+	Skipped         int64                     `json:"skipped"` // records left out by the filters
+	Partitions      []ReplayPartitionProgress `json:"partitions"`
+	CreatedAt       time.Time                 `json:"createdAt"`
+	FinishedAt      *time.Time                `json:"finishedAt,omitempty"`
+}
+
+// ReplayPartitionProgress is the position of a replay job on one source partition.
+type ReplayPartitionProgress struct {
+	Partition     int32 `json:"partition"`
+	StartOffset   int64 `json:"startOffset"`
+	EndOffset     int64 `json:"endOffset"`
+	CurrentOffset int64 `json:"currentOffset"` // next offset to copy
+	Copied        int64 `json:"copied"`
+	Skipped       int64 `json:"skipped"`
+}
+
+// TopicTableQuery selects a page of the table view of a compacted topic.
+type TopicTableQuery struct {
+	Key       string // keeps the keys containing this text, all keys when empty
+	PageIndex int    // 1-based
+	PageSize  int
+	Encoding  string
+	Refresh   bool // rebuild the table even when the topic did not change
+}
+
+// TopicTable is a page of the latest record per key of a compacted topic. Keys whose
+// latest record is a tombstone are counted but not listed.
+type TopicTable struct {
+	Topic          string              `json:"topic"`
+	LiveKeys       int64               `json:"liveKeys"`
+	TombstonedKeys int64               `json:"tombstonedKeys"`
+	NullKeys       int64               `json:"nullKeys"`       // records without a key, which compaction cannot place
+	ScannedRecords int64               `json:"scannedRecords"` // records read to build the table
+	Total          int64               `json:"total"`          // live keys matching the key search
+	PageIndex      int                 `json:"pageIndex"`
+	PageSize       int                 `json:"pageSize"`
+	Items          []MessageRecord     `json:"items"`
+	EndOffsets     []PartitionPosition `json:"endOffsets"` // end offsets the table was built up to
+	BuiltAt        time.Time           `json:"builtAt"`
+	Cached         bool                `json:"cached"` // served from the cache without reading the topic
+}
+
+// DeleteRecordsRequest truncates partitions of a topic before an offset or a timestamp,
+// or purges them. Exactly one of Offset, Timestamp and Purge must be set.
+type DeleteRecordsRequest struct {
+	Partitions []int32 `json:"partitions"` // empty for every partition
+	Offset     *int64  `json:"offset"`     // delete the records before this offset
+	Timestamp  int64   `json:"timestamp"`  // delete the records written before this time in Unix millis
+	Purge      bool    `json:"purge"`      // delete every record
+	DryRun     bool    `json:"dryRun"`     // only preview what would be deleted
+}
+
+// DeleteRecordsResult is the preview or outcome of deleting records.
+type DeleteRecordsResult struct {
+	Topic      string                   `json:"topic"`
+	DryRun     bool                     `json:"dryRun"`
+	Records    int64                    `json:"records"` // records deleted, or that would be, on all partitions
+	Partitions []DeleteRecordsPartition `json:"partitions"`
+}
+
+// DeleteRecordsPartition is what deleting records does to one partition. Records counts
+// offsets, so on compacted or transactional topics fewer actual records may go.
+type DeleteRecordsPartition struct {
+	Partition   int32 `json:"partition"`
+	StartOffset int64 `json:"startOffset"` // log start offset before the deletion
+	EndOffset   int64 `json:"endOffset"`
+	DeleteTo    int64 `json:"deleteTo"` // the new log start offset, records before it are deleted
+	Records     int64 `json:"records"`
+}
+
+// ReassignmentPlanRequest asks for a plan spreading the replicas of topics evenly over
+// a set of brokers.
+type ReassignmentPlanRequest struct {
+	Topics  []string `json:"topics" binding:"required"`
+	Brokers []int32  `json:"brokers" binding:"required"` // brokers that may hold the replicas
+}
+
+// ReassignmentPlan is a proposed reassignment shown as a diff of the replicas of every
+// partition that moves, with the replica and leader counts per broker before and after.
+type ReassignmentPlan struct {
+	Topics     []string                 `json:"topics"`
+	Brokers    []int32                  `json:"brokers"`
+	Partitions []PartitionReassignment  `json:"partitions"` // only the partitions that change
+	Moves      int                      `json:"moves"`      // replicas copied to a new broker
+	Load       []ReassignmentBrokerLoad `json:"load"`
+}
+
+// PartitionReassignment is the move of one partition from its current to its target replicas.
+type PartitionReassignment struct {
+	Topic     string  `json:"topic"`
+	Partition int32   `json:"partition"`
+	Current   []int32 `json:"current,omitempty"`
+	Target    []int32 `json:"target"` // the first replica is the preferred leader
+	Adding    []int32 `json:"adding,omitempty"`
+	Removing  []int32 `json:"removing,omitempty"`
+}
+
+// ReassignmentBrokerLoad counts the replicas and preferred leaders of the planned topics on a broker.
+type ReassignmentBrokerLoad struct {
+	Broker         int32 `json:"broker"`
+	ReplicasBefore int   `json:"replicasBefore"`
+	ReplicasAfter  int   `json:"replicasAfter"`
+	LeadersBefore  int   `json:"leadersBefore"`
+	LeadersAfter   int   `json:"leadersAfter"`
+}
+
+// ReassignmentRequest executes a reassignment, usually the partitions of a plan.
+type ReassignmentRequest struct {
+	Partitions []PartitionReassignment `json:"partitions" binding:"required"`
+	Throttle   int64                   `json:"throttle"` // replication bytes per second during the move, 0 for no limit
+}
+
+// Reassignment states
+const (
+	ReassignmentRunning   = "running"
+	ReassignmentCompleted = "completed"
+	ReassignmentCancelled = "cancelled"
+	ReassignmentFailed    = "failed"
+)
+
+// ReassignmentStatus is the progress of the reassignment started on a cluster.
+type ReassignmentStatus struct {
+	ClusterID  uint                   `json:"clusterId"`
+	Status     string                 `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	Throttle   int64                  `json:"throttle"`
+	Total      int                    `json:"total"`
+	Completed  int                    `json:"completed"`
+	Partitions []ReassignmentProgress `json:"partitions"`
+	StartedAt  time.Time              `json:"startedAt"`
+	CheckedAt  time.Time              `json:"checkedAt"` // last time the cluster was polled
+	FinishedAt *time.Time             `json:"finishedAt,omitempty"`
+}
+
+// ReassignmentProgress is the state of one moving partition as reported by the controller.
+type ReassignmentProgress struct {
+	Topic     string  `json:"topic"`
+	Partition int32   `json:"partition"`
+	Target    []int32 `json:"target"`
+	Replicas  []int32 `json:"replicas"`           // current replicas, including the ones being added
+	Adding    []int32 `json:"adding,omitempty"`   // replicas still catching up
+	Removing  []int32 `json:"removing,omitempty"` // replicas dropped once the new ones are in sync
+	Done      bool    `json:"done"`
+}
+
+// DrainPlan moves every replica off a broker. The leadership step only reorders replicas
+// so the broker stops being the preferred leader; the moves then copy its replicas away.
+type DrainPlan struct {
+	Broker     int32                   `json:"broker"`
+	Leadership []PartitionReassignment `json:"leadership"`
+	Moves      []PartitionReassignment `json:"moves"`
+}
+
+// DrainRequest starts draining a broker.
+type DrainRequest struct {
+	Throttle int64 `json:"throttle"` // replication bytes per second while moving replicas, 0 for no limit
+}
+
+// Drain states
+const (
+	DrainLeadership = "leadership" // moving leadership away
+	DrainReplicas   = "replicas"   // moving replicas away
+	DrainCompleted  = "completed"
+	DrainCancelled  = "cancelled"
+	DrainFailed     = "failed"
+)
+
+// DrainStatus is the state of a broker drain together with what the broker still hosts.
+type DrainStatus struct {
+	Broker       int32               `json:"broker"`
+	Status       string              `json:"status,omitempty"` // empty when no drain was started
+	Error        string              `json:"error,omitempty"`
+	Leaders      int                 `json:"leaders"`  // partitions the broker currently leads
+	Replicas     int                 `json:"replicas"` // replicas the broker currently hosts
+	Drained      bool                `json:"drained"`  // the broker hosts no partitions anymore
+	Reassignment *ReassignmentStatus `json:"reassignment,omitempty"`
+	StartedAt    *time.Time          `json:"startedAt,omitempty"`
+	FinishedAt   *time.Time          `json:"finishedAt,omitempty"`
+}
+
+// LeadershipReport shows how far partition leadership has drifted from the preferred
+// leaders, the first replica of every partition.
+type LeadershipReport struct {
+	Partitions   int                   `json:"partitions"`
+	NotPreferred int                   `json:"notPreferred"` // partitions led by another broker than their preferred leader
+	Leaderless   int                   `json:"leaderless"`   // partitions without a leader
+	Brokers      []BrokerLeadership    `json:"brokers"`
+	Skewed       []PartitionLeadership `json:"skewed"` // the partitions not led by their preferred leader
+}
+
+// BrokerLeadership counts the partitions a broker leads against the ones it should lead.
+type BrokerLeadership struct {
+	Broker    int32   `json:"broker"`
+	Leaders   int     `json:"leaders"`   // partitions the broker leads
+	Preferred int     `json:"preferred"` // partitions the broker is the preferred leader of
+	Skew      int     `json:"skew"`      // leaders minus preferred, positive when the broker leads too much
+	Imbalance float64 `json:"imbalance"` // percentage of its preferred partitions the broker does not lead
+}
+
+// PartitionLeadership is a partition whose leader is not its preferred leader.
+type PartitionLeadership struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Leader    int32  `json:"leader"` // -1 when the partition has no leader
+	Preferred int32  `json:"preferred"`
+	Electable bool   `json:"electable"` // the preferred leader is in sync, so a preferred election can move leadership back
+}
+
+// Leader election types
+const (
+	PreferredElection = "preferred"
+	UncleanElection   = "unclean"
+)
+
+// TopicPartition names one partition of a topic.
+type TopicPartition struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+}
+
+// LeaderElectionRequest elects the leaders of selected partitions, of whole topics or of
+// the entire cluster. An unclean election may pick a replica that is out of sync and lose
+// records, so it runs only when ConfirmUnclean is set.
+type LeaderElectionRequest struct {
+	Type           string           `json:"type"` // preferred (the default) or unclean
+	All            bool             `json:"all"`  // every partition of the cluster
+	Topics         []string         `json:"topics"`
+	Partitions     []TopicPartition `json:"partitions"`
+	ConfirmUnclean bool             `json:"confirmUnclean"`
+}
+
+// LeaderElectionResult is the outcome of a leader election.
+type LeaderElectionResult struct {
+	Type       string              `json:"type"`
+	Elected    int                 `json:"elected"`
+	NotNeeded  int                 `json:"notNeeded"` // partitions already led by the broker the election would pick
+	Failed     int                 `json:"failed"`
+	Partitions []PartitionElection `json:"partitions"`
+}
+
+// Partition election states
+const (
+	ElectionElected   = "elected"
+	ElectionNotNeeded = "not-needed"
+	ElectionFailed    = "failed"
+)
+
+// PartitionElection is the outcome of the election of one partition.
+type PartitionElection struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ReplicationFactorRequest changes the number of replicas of every partition of a topic.
+type ReplicationFactorRequest struct {
+	ReplicationFactor int   `json:"replicationFactor" binding:"required"`
+	Throttle          int64 `json:"throttle"` // replication bytes per second while copying new replicas, 0 for no limit
+	DryRun            bool  `json:"dryRun"`   // only plan the new replicas
+}
+
+// ReplicationFactorChange is the plan changing the replication factor of a topic and,
+// once started, the reassignment carrying it out.
+type ReplicationFactorChange struct {
+	Topic             string                  `json:"topic"`
+	ReplicationFactor int                     `json:"replicationFactor"`
+	DryRun            bool                    `json:"dryRun"`
+	Partitions        []PartitionReassignment `json:"partitions"` // only the partitions that change
+	Reassignment      *ReassignmentStatus     `json:"reassignment,omitempty"`
+}
+
+// Health severities
+const (
+	HealthOK       = "ok"
+	HealthWarning  = "warning"  // redundancy is reduced but clients are not affected yet
+	HealthCritical = "critical" // clients fail to read or write
+)
+
+// Partition health issues
+const (
+	HealthOffline              = "offline"             // no leader, the partition cannot be read or written
+	HealthUnderMinISR          = "under-min-isr"       // fewer in-sync replicas than min.insync.replicas, acks=all writes fail
+	HealthUnderReplicated      = "under-replicated"    // fewer in-sync replicas than replicas
+	HealthUnregisteredReplicas = "unregistered-broker" // replicas on brokers that are not registered in the cluster
+)
+
+// ClusterHealth lists the partitions of a cluster that are not healthy, grouped by topic
+// and by broker. Severity is the most severe issue found.
+type ClusterHealth struct {
+	ClusterID            uint           `json:"clusterId"`
+	Severity             string         `json:"severity"`
+	Partitions           int            `json:"partitions"` // partitions scanned
+	Offline              int            `json:"offline"`
+	UnderMinISR          int            `json:"underMinIsr"`
+	UnderReplicated      int            `json:"underReplicated"`
+	UnregisteredReplicas int            `json:"unregisteredReplicas"` // partitions with replicas on unregistered brokers
+	Topics               []TopicHealth  `json:"topics"`               // only topics with issues, the most severe first
+	Brokers              []BrokerHealth `json:"brokers"`
+	CheckedAt            time.Time      `json:"checkedAt"`
+}
+
+// TopicHealth is the unhealthy partitions of a topic.
+type TopicHealth struct {
+	Topic             string            `json:"topic"`
+	Severity          string            `json:"severity"`
+	MinInSyncReplicas int               `json:"minInSyncReplicas"`
+	Partitions        []PartitionHealth `json:"partitions"`
+}
+
+// BrokerHealth is the unhealthy partitions with a replica on a broker that is out of sync,
+// offline or, for a broker that is not registered, any replica at all.
+type BrokerHealth struct {
+	Broker     int32             `json:"broker"`
+	Registered bool              `json:"registered"`
+	Severity   string            `json:"severity"`
+	Partitions []PartitionHealth `json:"partitions"`
+}
+
+// PartitionHealth is a partition with at least one issue.
+type PartitionHealth struct {
+	Topic        string   `json:"topic"`
+	Partition    int32    `json:"partition"`
+	Severity     string   `json:"severity"`
+	Issues       []string `json:"issues"`
+	Leader       int32    `json:"leader"` // -1 when the partition is offline
+	Replicas     []int32  `json:"replicas"`
+	Isr          []int32  `json:"isr"`
+	Offline      []int32  `json:"offline,omitempty"`      // replicas on brokers that are down
+	Unregistered []int32  `json:"unregistered,omitempty"` // replicas on brokers that are not registered
+}
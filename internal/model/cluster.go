@@ -17,6 +17,10 @@ type Cluster struct {
 	SaslMechanism    string         `json:"saslMechanism"`           // PLAIN, SCRAM-SHA-256, SCRAM-SHA-512
 	SaslUsername     string         `json:"saslUsername"`
 	SaslPassword     string         `json:"-"`
+
+	SchemaRegistryURL      string `json:"schemaRegistryUrl"` // Confluent compatible registry used to decode Avro records
+	SchemaRegistryUsername string `json:"schemaRegistryUsername"`
+	SchemaRegistryPassword string `json:"-"`
 }
 
 func (Cluster) TableName() string {
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProtoDescriptorSet is an uploaded, serialized google.protobuf.FileDescriptorSet.
+type ProtoDescriptorSet struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ClusterID uint           `gorm:"index" json:"clusterId"`
+	Name      string         `gorm:"not null" json:"name"`
+	Data      []byte         `gorm:"not null" json:"-"`
+}
+
+func (ProtoDescriptorSet) TableName() string {
+	return "proto_descriptor_sets"
+}
+
+// TopicProtoBinding binds message types of a descriptor set to the keys and values of a topic.
+type TopicProtoBinding struct {
+	ID               uint      `gorm:"primarykey" json:"id"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+	ClusterID        uint      `gorm:"uniqueIndex:idx_topic_proto_binding" json:"clusterId"`
+	Topic            string    `gorm:"uniqueIndex:idx_topic_proto_binding;not null" json:"topic"`
+	DescriptorSetID  uint      `gorm:"index" json:"descriptorSetId"`
+	KeyMessageType   string    `json:"keyMessageType"`   // fully qualified message name, empty to keep keys raw
+	ValueMessageType string    `json:"valueMessageType"` // fully qualified message name, empty to keep values raw
+}
+
+func (TopicProtoBinding) TableName() string {
+	return "topic_proto_bindings"
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// ReplayJob is a background copy of records from one topic to another.
+type ReplayJob struct {
+	ID              uint       `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+	ClusterID       uint       `gorm:"index" json:"clusterId"`
+	Topic           string     `gorm:"index;not null" json:"topic"`
+	TargetClusterID uint       `json:"targetClusterId"`
+	TargetTopic     string     `gorm:"not null" json:"targetTopic"`
+	Status          string     `gorm:"not null" json:"status"`
+	Error           string     `json:"error"`
+	Request         string     `json:"request"`  // JSON encoded dto.ReplayJobRequest
+	Progress        string     `json:"progress"` // JSON encoded []dto.ReplayPartitionProgress
+	Copied          int64      `json:"copied"`
+	FinishedAt      *time.Time `json:"finishedAt"`
+}
+
+func (ReplayJob) TableName() string {
+	return "replay_jobs"
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// ReplicationThrottle is a broker or topic config set by a reassignment throttle, with the
+// value it had before. It is deleted once that value is restored, so the rows left behind
+// by a stopped server name the throttles still to be removed.
+type ReplicationThrottle struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ClusterID    uint      `gorm:"index" json:"clusterId"`
+	ResourceType int8      `json:"resourceType"` // sarama.ConfigResourceType
+	ResourceName string    `gorm:"not null" json:"resourceName"`
+	Name         string    `gorm:"not null" json:"name"`
+	Previous     *string   `json:"previous"` // nil when the config was not set
+}
+
+func (ReplicationThrottle) TableName() string {
+	return "replication_throttles"
+}
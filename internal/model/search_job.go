@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+)
+
+// SearchJob is a background scan of a topic for records matching a filter.
+type SearchJob struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	ClusterID  uint       `gorm:"index" json:"clusterId"`
+	Topic      string     `gorm:"index;not null" json:"topic"`
+	Status     string     `gorm:"not null" json:"status"`
+	Error      string     `json:"error"`
+	Request    string     `json:"request"`  // JSON encoded dto.SearchJobRequest
+	Progress   string     `json:"progress"` // JSON encoded []dto.SearchPartitionProgress
+	MatchCount int64      `json:"matchCount"`
+	Truncated  bool       `json:"truncated"` // stopped at the match limit before the end of the range
+	FinishedAt *time.Time `json:"finishedAt"`
+}
+
+func (SearchJob) TableName() string {
+	return "search_jobs"
+}
+
+// SearchMatch is a record found by a search job.
+type SearchMatch struct {
+	ID        uint   `gorm:"primarykey" json:"id"`
+	JobID     uint   `gorm:"index" json:"jobId"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Record    string `json:"record"` // JSON encoded dto.MessageRecord
+}
+
+func (SearchMatch) TableName() string {
+	return "search_matches"
+}
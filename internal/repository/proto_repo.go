@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"gorm.io/gorm"
+)
+
+type ProtoRepository struct {
+	db *gorm.DB
+}
+
+func NewProtoRepository(db *gorm.DB) *ProtoRepository {
+	return &ProtoRepository{db: db}
+}
+
+func (r *ProtoRepository) CreateDescriptorSet(set *model.ProtoDescriptorSet) error {
+	return r.db.Create(set).Error
+}
+
+func (r *ProtoRepository) FindDescriptorSet(clusterID, id uint) (*model.ProtoDescriptorSet, error) {
+	var set model.ProtoDescriptorSet
+	err := r.db.Where("cluster_id = ?", clusterID).First(&set, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+func (r *ProtoRepository) FindDescriptorSetsByCluster(clusterID uint) ([]model.ProtoDescriptorSet, error) {
+	var sets []model.ProtoDescriptorSet
+	err := r.db.Where("cluster_id = ?", clusterID).Order("id").Find(&sets).Error
+	return sets, err
+}
+
+func (r *ProtoRepository) DeleteDescriptorSet(clusterID, id uint) error {
+	result := r.db.Where("cluster_id = ?", clusterID).Delete(&model.ProtoDescriptorSet{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *ProtoRepository) CountBindingsByDescriptorSet(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.TopicProtoBinding{}).Where("descriptor_set_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *ProtoRepository) FindBinding(clusterID uint, topic string) (*model.TopicProtoBinding, error) {
+	var binding model.TopicProtoBinding
+	err := r.db.Where("cluster_id = ? AND topic = ?", clusterID, topic).First(&binding).Error
+	if err != nil {
+		return nil, err
+	}
+	return &binding, nil
+}
+
+func (r *ProtoRepository) UpsertBinding(binding *model.TopicProtoBinding) error {
+	return r.db.Model(&model.TopicProtoBinding{}).
+		Where("cluster_id = ? AND topic = ?", binding.ClusterID, binding.Topic).
+		Assign(map[string]interface{}{
+			"descriptor_set_id":  binding.DescriptorSetID,
+			"key_message_type":   binding.KeyMessageType,
+			"value_message_type": binding.ValueMessageType,
+		}).FirstOrCreate(binding).Error
+}
+
+func (r *ProtoRepository) DeleteBinding(clusterID uint, topic string) error {
+	return r.db.Where("cluster_id = ? AND topic = ?", clusterID, topic).Delete(&model.TopicProtoBinding{}).Error
+}
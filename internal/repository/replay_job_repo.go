@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"gorm.io/gorm"
+)
+
+type ReplayJobRepository struct {
+	db *gorm.DB
+}
+
+func NewReplayJobRepository(db *gorm.DB) *ReplayJobRepository {
+	return &ReplayJobRepository{db: db}
+}
+
+func (r *ReplayJobRepository) Create(job *model.ReplayJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *ReplayJobRepository) FindByID(id uint) (*model.ReplayJob, error) {
+	var job model.ReplayJob
+	err := r.db.First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FindByClusterAndTopic returns the jobs reading from a cluster, optionally from one topic.
+func (r *ReplayJobRepository) FindByClusterAndTopic(clusterID uint, topic string) ([]model.ReplayJob, error) {
+	var jobs []model.ReplayJob
+	query := r.db.Where("cluster_id = ?", clusterID)
+	if topic != "" {
+		query = query.Where("topic = ?", topic)
+	}
+	err := query.Order("id DESC").Find(&jobs).Error
+	return jobs, err
+}
+
+// UpdateProgress stores the copy positions and copied record count of a job.
+func (r *ReplayJobRepository) UpdateProgress(id uint, progress string, copied int64) error {
+	return r.db.Model(&model.ReplayJob{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"progress": progress,
+		"copied":   copied,
+	}).Error
+}
+
+func (r *ReplayJobRepository) Finish(job *model.ReplayJob) error {
+	return r.db.Model(&model.ReplayJob{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":      job.Status,
+		"error":       job.Error,
+		"progress":    job.Progress,
+		"copied":      job.Copied,
+		"finished_at": job.FinishedAt,
+	}).Error
+}
+
+// FailRunning marks jobs left running by a previous process as failed.
+func (r *ReplayJobRepository) FailRunning(running, failed, reason string) error {
+	return r.db.Model(&model.ReplayJob{}).Where("status = ?", running).Updates(map[string]interface{}{
+		"status": failed,
+		"error":  reason,
+	}).Error
+}
+
+func (r *ReplayJobRepository) Delete(id uint) error {
+	return r.db.Delete(&model.ReplayJob{}, id).Error
+}
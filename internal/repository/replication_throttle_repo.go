@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"gorm.io/gorm"
+)
+
+type ReplicationThrottleRepository struct {
+	db *gorm.DB
+}
+
+func NewReplicationThrottleRepository(db *gorm.DB) *ReplicationThrottleRepository {
+	return &ReplicationThrottleRepository{db: db}
+}
+
+func (r *ReplicationThrottleRepository) Create(throttles []model.ReplicationThrottle) error {
+	if len(throttles) == 0 {
+		return nil
+	}
+	return r.db.Create(&throttles).Error
+}
+
+func (r *ReplicationThrottleRepository) FindAll() ([]model.ReplicationThrottle, error) {
+	var throttles []model.ReplicationThrottle
+	err := r.db.Order("id").Find(&throttles).Error
+	return throttles, err
+}
+
+func (r *ReplicationThrottleRepository) Delete(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Delete(&model.ReplicationThrottle{}, ids).Error
+}
+
+// DeleteByCluster removes the throttles saved for a cluster.
+func (r *ReplicationThrottleRepository) DeleteByCluster(clusterID uint) error {
+	return r.db.Where("cluster_id = ?", clusterID).Delete(&model.ReplicationThrottle{}).Error
+}
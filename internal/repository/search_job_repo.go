@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"gorm.io/gorm"
+)
+
+type SearchJobRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchJobRepository(db *gorm.DB) *SearchJobRepository {
+	return &SearchJobRepository{db: db}
+}
+
+func (r *SearchJobRepository) Create(job *model.SearchJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *SearchJobRepository) FindByID(id uint) (*model.SearchJob, error) {
+	var job model.SearchJob
+	err := r.db.First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *SearchJobRepository) FindByClusterAndTopic(clusterID uint, topic string) ([]model.SearchJob, error) {
+	var jobs []model.SearchJob
+	query := r.db.Where("cluster_id = ?", clusterID)
+	if topic != "" {
+		query = query.Where("topic = ?", topic)
+	}
+	err := query.Order("id DESC").Find(&jobs).Error
+	return jobs, err
+}
+
+// UpdateProgress stores the scan positions and match count of a job.
+func (r *SearchJobRepository) UpdateProgress(id uint, progress string, matchCount int64) error {
+	return r.db.Model(&model.SearchJob{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"progress":    progress,
+		"match_count": matchCount,
+	}).Error
+}
+
+func (r *SearchJobRepository) Finish(job *model.SearchJob) error {
+	return r.db.Model(&model.SearchJob{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":      job.Status,
+		"error":       job.Error,
+		"progress":    job.Progress,
+		"match_count": job.MatchCount,
+		"truncated":   job.Truncated,
+		"finished_at": job.FinishedAt,
+	}).Error
+}
+
+// FailRunning marks jobs left running by a previous process as failed.
+func (r *SearchJobRepository) FailRunning(running, failed, reason string) error {
+	return r.db.Model(&model.SearchJob{}).Where("status = ?", running).Updates(map[string]interface{}{
+		"status": failed,
+		"error":  reason,
+	}).Error
+}
+
+func (r *SearchJobRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("job_id = ?", id).Delete(&model.SearchMatch{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.SearchJob{}, id).Error
+	})
+}
+
+func (r *SearchJobRepository) CreateMatches(matches []model.SearchMatch) error {
+	return r.db.Create(&matches).Error
+}
+
+// FindMatches returns up to limit matches of a job with an ID greater than afterID.
+func (r *SearchJobRepository) FindMatches(jobID, afterID uint, limit int) ([]model.SearchMatch, error) {
+	var matches []model.SearchMatch
+	err := r.db.Where("job_id = ? AND id > ?", jobID, afterID).Order("id").Limit(limit).Find(&matches).Error
+	return matches, err
+}
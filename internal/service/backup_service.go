@@ -0,0 +1,690 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/repository"
+	"github.com/bingfengfeifei/kafka-map-go/internal/util"
+)
+
+const (
+	backupManifestFile = "manifest.json"
+	// backupIdleTimeout ends a partition backup that stopped receiving records before its end offset.
+	backupIdleTimeout = 10 * time.Second
+	// restoreTopicTimeout bounds the wait for a restored topic to get partition leaders.
+	restoreTopicTimeout = 30 * time.Second
+)
+
+// ErrBackupNotFound is returned for unknown backup IDs.
+var ErrBackupNotFound = errors.New("backup not found")
+
+// backupIDPattern keeps backup IDs usable as directory names.
+var backupIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// BackupService snapshots topics to local archives and restores them. Each backup is a
+// directory holding a manifest and one gzipped JSONL file per partition, so backups are
+// listed from disk and survive restarts without any database state.
+type BackupService struct {
+	clusterRepo  *repository.ClusterRepository
+	kafkaManager *util.KafkaClientManager
+	topicService *TopicService
+	dir          string
+	running      map[string]*backupRun
+	mu           sync.Mutex
+}
+
+func NewBackupService(clusterRepo *repository.ClusterRepository, kafkaManager *util.KafkaClientManager, topicService *TopicService, dir string) *BackupService {
+	return &BackupService{
+		clusterRepo:  clusterRepo,
+		kafkaManager: kafkaManager,
+		topicService: topicService,
+		dir:          dir,
+		running:      make(map[string]*backupRun),
+	}
+}
+
+// backupRun is the in-memory state of a running backup.
+type backupRun struct {
+	cancel    context.CancelFunc
+	mu        sync.Mutex
+	manifest  dto.BackupManifest
+	cancelled bool
+}
+
+func (r *backupRun) setProgress(index int, records, lastOffset int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.manifest.Partitions[index].Records = records
+	r.manifest.Partitions[index].LastOffset = lastOffset
+}
+
+func (r *backupRun) snapshot() dto.BackupManifest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	manifest := r.manifest
+	manifest.Partitions = append([]dto.BackupPartition(nil), r.manifest.Partitions...)
+	manifest.Records = 0
+	for _, partition := range manifest.Partitions {
+		manifest.Records += partition.Records
+	}
+	return manifest
+}
+
+func (s *BackupService) backupPath(id string) (string, error) {
+	if !backupIDPattern.MatchString(id) || id == "." || id == ".." {
+		return "", ErrBackupNotFound
+	}
+	return filepath.Join(s.dir, id), nil
+}
+
+func readBackupManifest(dir string) (*dto.BackupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, backupManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBackupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest dto.BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+	for _, partition := range manifest.Partitions {
+		// the manifest names files inside the backup directory only
+		if partition.File != filepath.Base(partition.File) {
+			return nil, fmt.Errorf("invalid file %q in backup manifest", partition.File)
+		}
+	}
+	return &manifest, nil
+}
+
+// writeBackupManifest replaces the manifest atomically, so a crash never leaves half of one.
+func writeBackupManifest(dir string, manifest *dto.BackupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, backupManifestFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, backupManifestFile))
+}
+
+// FailInterruptedBackups marks backups that were running when the server stopped as failed.
+func (s *BackupService) FailInterruptedBackups() error {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.dir, entry.Name())
+		manifest, err := readBackupManifest(dir)
+		if err != nil || manifest.Status != dto.BackupRunning {
+			continue
+		}
+		manifest.Status = dto.BackupFailed
+		manifest.Error = "interrupted by server restart"
+		if err := writeBackupManifest(dir, manifest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newBackupDir creates the directory of a new backup named after the topic and the time.
+func (s *BackupService) newBackupDir(topicName string) (string, string, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	base := fmt.Sprintf("%s-%s", topicName, time.Now().Format("20060102-150405"))
+	for i := 1; ; i++ {
+		id := base
+		if i > 1 {
+			id = fmt.Sprintf("%s-%d", base, i)
+		}
+		dir, err := s.backupPath(id)
+		if err != nil {
+			return "", "", fmt.Errorf("cannot name a backup of topic %q", topicName)
+		}
+		err = os.Mkdir(dir, 0o755)
+		if err == nil {
+			return id, dir, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", "", fmt.Errorf("failed to create backup directory: %w", err)
+		}
+	}
+}
+
+// StartBackup snapshots every partition of a topic up to its current end offsets in the background.
+func (s *BackupService) StartBackup(clusterID uint, topicName string) (*dto.BackupManifest, error) {
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	configs, err := s.topicService.GetTopicConfigs(clusterID, topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.kafkaManager.CreateClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := searchRanges(client, topicName, nil, dto.MessageQuery{Offset: sarama.OffsetOldest})
+	if err == nil && len(ranges) == 0 {
+		err = fmt.Errorf("topic %s has no partitions", topicName)
+	}
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	replicas, err := client.Replicas(topicName, ranges[0].partition)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to get replicas: %w", err)
+	}
+
+	id, dir, err := s.newBackupDir(topicName)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	manifest := dto.BackupManifest{
+		Version:           dto.BackupManifestVersion,
+		ID:                id,
+		ClusterID:         clusterID,
+		ClusterName:       cluster.Name,
+		Topic:             topicName,
+		Status:            dto.BackupRunning,
+		ReplicationFactor: int16(len(replicas)),
+		Configs:           configs,
+		Partitions:        make([]dto.BackupPartition, len(ranges)),
+		CreatedAt:         time.Now(),
+	}
+	for i, rng := range ranges {
+		manifest.Partitions[i] = dto.BackupPartition{
+			Partition:   rng.partition,
+			StartOffset: rng.start,
+			EndOffset:   rng.stop,
+			LastOffset:  -1,
+			File:        fmt.Sprintf("partition-%d.jsonl.gz", rng.partition),
+		}
+	}
+	if err := writeBackupManifest(dir, &manifest); err != nil {
+		client.Close()
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &backupRun{cancel: cancel, manifest: manifest}
+	s.mu.Lock()
+	s.running[id] = run
+	s.mu.Unlock()
+
+	go s.run(ctx, run, dir, client, ranges)
+
+	snapshot := run.snapshot()
+	return &snapshot, nil
+}
+
+func (s *BackupService) run(ctx context.Context, run *backupRun, dir string, client sarama.Client, ranges []partitionRange) {
+	defer client.Close()
+	defer run.cancel()
+
+	topicName := run.manifest.Topic
+	err := func() error {
+		consumer, err := sarama.NewConsumerFromClient(client)
+		if err != nil {
+			return fmt.Errorf("failed to create consumer: %w", err)
+		}
+		defer consumer.Close()
+
+		for i, rng := range ranges {
+			path := filepath.Join(dir, run.manifest.Partitions[i].File)
+			records, size, sum, err := writeBackupPartition(ctx, consumer, remainingIn(client), topicName, path, rng, func(records, lastOffset int64) {
+				run.setProgress(i, records, lastOffset)
+			})
+			if err != nil {
+				return err
+			}
+			run.mu.Lock()
+			run.manifest.Partitions[i].Records = records
+			run.manifest.Partitions[i].Size = size
+			run.manifest.Partitions[i].SHA256 = sum
+			run.mu.Unlock()
+		}
+		return nil
+	}()
+
+	run.mu.Lock()
+	if run.cancelled {
+		err = errors.New("cancelled")
+	}
+	run.mu.Unlock()
+
+	manifest := run.snapshot()
+	finishedAt := time.Now()
+	manifest.FinishedAt = &finishedAt
+	manifest.Status = dto.BackupCompleted
+	if err != nil {
+		manifest.Status = dto.BackupFailed
+		manifest.Error = err.Error()
+	}
+	for _, partition := range manifest.Partitions {
+		manifest.Size += partition.Size
+	}
+	if err := writeBackupManifest(dir, &manifest); err != nil {
+		log.Printf("[Backup] failed to write manifest of backup %s: %v", manifest.ID, err)
+	}
+
+	s.mu.Lock()
+	delete(s.running, manifest.ID)
+	s.mu.Unlock()
+}
+
+// writeBackupPartition writes the records of a partition range to a gzipped JSONL file and
+// returns the record count, the file size and its SHA-256 checksum. progress gets the count
+// and the last offset written after every record. A partition that ends short of the stop
+// offset fails the backup unless only transaction markers or aborted records are left.
+func writeBackupPartition(ctx context.Context, consumer sarama.Consumer, remaining remainingCheck, topicName, path string, rng partitionRange, progress func(records, lastOffset int64)) (int64, int64, string, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	zw := gzip.NewWriter(io.MultiWriter(file, hash))
+	encoder := json.NewEncoder(zw)
+
+	var records int64
+	var encodeErr error
+	exhausted, err := scanRange(ctx, consumer, remaining, topicName, rng, backupIdleTimeout, func(msg *sarama.ConsumerMessage) bool {
+		record := dto.BackupRecord{Offset: msg.Offset, Key: msg.Key, Value: msg.Value}
+		if !msg.Timestamp.IsZero() {
+			record.Timestamp = msg.Timestamp.UnixMilli()
+		}
+		for _, header := range msg.Headers {
+			if header != nil {
+				record.Headers = append(record.Headers, dto.BackupHeader{Key: string(header.Key), Value: header.Value})
+			}
+		}
+		if encodeErr = encoder.Encode(record); encodeErr != nil {
+			return false
+		}
+		records++
+		progress(records, msg.Offset)
+		return true
+	})
+	if err == nil {
+		err = encodeErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil && !exhausted {
+		err = fmt.Errorf("partition %d stopped before offset %d", rng.partition, rng.stop)
+	}
+	if err != nil {
+		err = fmt.Errorf("backup of partition %d is incomplete: %w", rng.partition, err)
+	}
+	if err != nil {
+		return records, 0, "", err
+	}
+
+	if err := zw.Close(); err != nil {
+		return records, 0, "", fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return records, 0, "", fmt.Errorf("failed to write backup file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return records, 0, "", err
+	}
+	return records, info.Size(), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// GetBackups lists the backups on disk, newest first, optionally of one cluster or topic.
+func (s *BackupService) GetBackups(clusterID uint, topicName string) ([]dto.BackupManifest, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []dto.BackupManifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]dto.BackupManifest, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		manifest, err := s.GetBackup(entry.Name())
+		if err != nil {
+			// not a backup, or one whose manifest cannot be read
+			continue
+		}
+		if (clusterID != 0 && manifest.ClusterID != clusterID) || (topicName != "" && manifest.Topic != topicName) {
+			continue
+		}
+		backups = append(backups, *manifest)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// GetBackup returns the manifest of a backup, with live progress while it runs.
+func (s *BackupService) GetBackup(id string) (*dto.BackupManifest, error) {
+	s.mu.Lock()
+	run, running := s.running[id]
+	s.mu.Unlock()
+	if running {
+		manifest := run.snapshot()
+		return &manifest, nil
+	}
+
+	dir, err := s.backupPath(id)
+	if err != nil {
+		return nil, err
+	}
+	return readBackupManifest(dir)
+}
+
+// CancelBackup stops a running backup, which then ends as failed.
+func (s *BackupService) CancelBackup(id string) error {
+	s.mu.Lock()
+	run, running := s.running[id]
+	s.mu.Unlock()
+	if !running {
+		if _, err := s.GetBackup(id); err != nil {
+			return err
+		}
+		return fmt.Errorf("backup %s is not running", id)
+	}
+
+	run.mu.Lock()
+	run.cancelled = true
+	run.mu.Unlock()
+	run.cancel()
+	return nil
+}
+
+// DeleteBackup removes a finished backup from disk.
+func (s *BackupService) DeleteBackup(id string) error {
+	manifest, err := s.GetBackup(id)
+	if err != nil {
+		return err
+	}
+	if manifest.Status == dto.BackupRunning {
+		return fmt.Errorf("backup %s is still running, cancel it first", id)
+	}
+	dir, err := s.backupPath(id)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// VerifyBackup recomputes the checksum of every partition file of a completed backup.
+func (s *BackupService) VerifyBackup(id string) (*dto.BackupVerification, error) {
+	manifest, err := s.GetBackup(id)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Status != dto.BackupCompleted {
+		return nil, fmt.Errorf("backup %s is %s, only completed backups can be verified", id, manifest.Status)
+	}
+	dir, err := s.backupPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &dto.BackupVerification{ID: id, Valid: true, Partitions: make([]dto.BackupPartitionCheck, 0, len(manifest.Partitions))}
+	for _, partition := range manifest.Partitions {
+		check := dto.BackupPartitionCheck{Partition: partition.Partition, File: partition.File, Expected: partition.SHA256}
+		sum, err := fileSHA256(filepath.Join(dir, partition.File))
+		if err != nil {
+			check.Error = err.Error()
+		} else {
+			check.Actual = sum
+			check.Valid = sum == partition.SHA256
+		}
+		if !check.Valid {
+			verification.Valid = false
+		}
+		verification.Partitions = append(verification.Partitions, check)
+	}
+	return verification, nil
+}
+
+// restorableConfigs returns the topic configs that were set explicitly and can be set again.
+func restorableConfigs(configs []dto.TopicConfig) map[string]string {
+	result := make(map[string]string)
+	for _, config := range configs {
+		if !config.Default && !config.ReadOnly && !config.Sensitive {
+			result[config.Name] = config.Value
+		}
+	}
+	return result
+}
+
+// RestoreBackup verifies a backup and restores it into a new topic on the same or another
+// cluster. Every record goes back to the partition it was backed up from with its original
+// key, value, headers and timestamp; offsets are assigned anew by the target topic.
+func (s *BackupService) RestoreBackup(ctx context.Context, id string, req *dto.RestoreBackupRequest) (*dto.RestoreResult, error) {
+	started := time.Now()
+	verification, err := s.VerifyBackup(id)
+	if err != nil {
+		return nil, err
+	}
+	if !verification.Valid {
+		return nil, fmt.Errorf("backup %s failed checksum verification", id)
+	}
+	manifest, err := s.GetBackup(id)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := s.backupPath(id)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := parseCompression(req.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterID := req.ClusterID
+	if clusterID == 0 {
+		clusterID = manifest.ClusterID
+	}
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	var partitions int32
+	for _, partition := range manifest.Partitions {
+		if partition.Partition+1 > partitions {
+			partitions = partition.Partition + 1
+		}
+	}
+	replicationFactor := req.ReplicationFactor
+	if replicationFactor <= 0 {
+		replicationFactor = manifest.ReplicationFactor
+	}
+	var configs map[string]string
+	if !req.SkipConfigs {
+		configs = restorableConfigs(manifest.Configs)
+	}
+	if err := s.topicService.CreateTopic(clusterID, &dto.CreateTopicRequest{
+		Name:              req.Topic,
+		Partitions:        partitions,
+		ReplicationFactor: replicationFactor,
+		Configs:           configs,
+	}); err != nil {
+		return nil, err
+	}
+
+	client, err := s.kafkaManager.CreateClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	if err := waitForTopic(ctx, client, req.Topic, partitions, restoreTopicTimeout); err != nil {
+		return nil, err
+	}
+
+	producer, err := s.topicService.createRecordProducer(cluster, codec)
+	if err != nil {
+		return nil, err
+	}
+	defer producer.Close()
+
+	result := &dto.RestoreResult{ID: id, ClusterID: clusterID, Topic: req.Topic, Partitions: make([]dto.RestoredPartition, 0, len(manifest.Partitions))}
+	for _, partition := range manifest.Partitions {
+		records, err := restorePartition(ctx, producer, req.Topic, filepath.Join(dir, partition.File), partition.Partition)
+		result.Records += records
+		result.Partitions = append(result.Partitions, dto.RestoredPartition{Partition: partition.Partition, Records: records})
+		if err != nil {
+			return result, fmt.Errorf("failed to restore partition %d: %w", partition.Partition, err)
+		}
+	}
+	result.DurationMs = time.Since(started).Milliseconds()
+	return result, nil
+}
+
+// waitForTopic waits until every partition of a newly created topic has a leader.
+func waitForTopic(ctx context.Context, client sarama.Client, topicName string, partitions int32, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := client.RefreshMetadata(topicName); err == nil {
+			if writable, err := client.WritablePartitions(topicName); err == nil && int32(len(writable)) >= partitions {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("topic %s has no leader on every partition after %v", topicName, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// restorePartition produces the records of a partition file to one partition of a topic.
+func restorePartition(ctx context.Context, producer sarama.SyncProducer, topicName, path string, partition int32) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+
+	var restored int64
+	batch := make([]*sarama.ProducerMessage, 0, importBatchSize)
+	offsets := make([]int64, 0, importBatchSize) // original offsets of the batch, for errors
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := sendMessages(producer, batch)
+		if err != nil {
+			return err
+		}
+		for i, record := range result.Records {
+			if record.Error != "" {
+				return fmt.Errorf("record at offset %d: %s", offsets[i], record.Error)
+			}
+		}
+		restored += int64(len(batch))
+		batch = batch[:0]
+		offsets = offsets[:0]
+		return ctx.Err()
+	}
+
+	decoder := json.NewDecoder(zr)
+	for {
+		var record dto.BackupRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return restored, fmt.Errorf("failed to read backup file: %w", err)
+		}
+
+		msg := &sarama.ProducerMessage{
+			Topic:    topicName,
+			Metadata: targetPartition(partition),
+		}
+		// byte encoders keep a null key or value apart from an empty one
+		if record.Key != nil {
+			msg.Key = sarama.ByteEncoder(record.Key)
+		}
+		if record.Value != nil {
+			msg.Value = sarama.ByteEncoder(record.Value)
+		}
+		if record.Timestamp > 0 {
+			msg.Timestamp = time.UnixMilli(record.Timestamp)
+		}
+		for _, header := range record.Headers {
+			msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(header.Key), Value: header.Value})
+		}
+
+		batch = append(batch, msg)
+		offsets = append(offsets, record.Offset)
+		if len(batch) >= importBatchSize {
+			if err := flush(); err != nil {
+				return restored, err
+			}
+		}
+	}
+	return restored, flush()
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+func TestBackupPartitionRoundTrip(t *testing.T) {
+	timestamp := time.UnixMilli(1_700_000_000_000)
+	consumer := mocks.NewConsumer(t, nil)
+	partitionConsumer := consumer.ExpectConsumePartition("orders", 2, 5)
+	partitionConsumer.YieldMessage(&sarama.ConsumerMessage{Key: []byte("a"), Value: []byte("one"), Timestamp: timestamp,
+		Headers: []*sarama.RecordHeader{{Key: []byte("trace"), Value: []byte{0xff, 0x00}}}})
+	partitionConsumer.YieldMessage(&sarama.ConsumerMessage{Key: []byte("a"), Timestamp: timestamp.Add(time.Second)})
+	partitionConsumer.YieldMessage(&sarama.ConsumerMessage{Key: []byte{}, Value: []byte{}, Timestamp: timestamp})
+	partitionConsumer.YieldMessage(&sarama.ConsumerMessage{Value: []byte("past the end offset")})
+
+	path := filepath.Join(t.TempDir(), "partition-2.jsonl.gz")
+	var progress, lastOffset int64
+	records, size, sum, err := writeBackupPartition(context.Background(), consumer, noRemaining, "orders", path,
+		partitionRange{partition: 2, start: 5, stop: 8}, func(n, offset int64) { progress, lastOffset = n, offset })
+	if err != nil {
+		t.Fatalf("writeBackupPartition() error = %v", err)
+	}
+	if records != 3 || progress != 3 || lastOffset != 7 {
+		t.Fatalf("records = %d, progress = %d up to offset %d, want 3 up to offset 7", records, progress, lastOffset)
+	}
+	if actual, _ := fileSHA256(path); actual != sum || size == 0 {
+		t.Fatalf("checksum = %s, size = %d, want the file's checksum %s", sum, size, actual)
+	}
+
+	producer := &partialProducer{partitioner: newExplicitPartitioner("copy"), partitions: 3}
+	var sent []*sarama.ProducerMessage
+	restored, err := restorePartition(context.Background(), &capturingProducer{partialProducer: producer, sent: &sent}, "copy", path, 2)
+	if err != nil {
+		t.Fatalf("restorePartition() error = %v", err)
+	}
+	if restored != 3 || len(sent) != 3 {
+		t.Fatalf("restored %d records, sent %d, want 3", restored, len(sent))
+	}
+
+	for _, msg := range sent {
+		if msg.Topic != "copy" || msg.Partition != 2 {
+			t.Fatalf("message went to %s/%d, want copy/2", msg.Topic, msg.Partition)
+		}
+	}
+	if first := sent[0]; !first.Timestamp.Equal(timestamp) || len(first.Headers) != 1 || string(first.Headers[0].Value) != "\xff\x00" {
+		t.Fatalf("first message = %+v, want the original timestamp and binary header", first)
+	}
+	if tombstone := sent[1]; tombstone.Value != nil || tombstone.Key == nil {
+		t.Fatalf("second message value = %v, want a null value with a key", tombstone.Value)
+	}
+	if empty := sent[2]; empty.Key == nil || empty.Value == nil || empty.Key.Length() != 0 || empty.Value.Length() != 0 {
+		t.Fatalf("third message = %+v, want an empty key and value rather than null", empty)
+	}
+}
+
+// capturingProducer keeps the messages sent through a partialProducer.
+type capturingProducer struct {
+	*partialProducer
+	sent *[]*sarama.ProducerMessage
+}
+
+func (p *capturingProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	*p.sent = append(*p.sent, msgs...)
+	return p.partialProducer.SendMessages(msgs)
+}
+
+func writeTestBackup(t *testing.T, dir string, manifest dto.BackupManifest, files map[string]string) {
+	t.Helper()
+	backupDir := filepath.Join(dir, manifest.ID)
+	if err := os.MkdirAll(backupDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(backupDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeBackupManifest(backupDir, &manifest); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackupServiceListVerifyAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	backups := NewBackupService(nil, nil, nil, dir)
+
+	// sha256 of "data"
+	const dataSum = "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"
+	writeTestBackup(t, dir, dto.BackupManifest{
+		ID: "orders-1", ClusterID: 1, Topic: "orders", Status: dto.BackupCompleted, CreatedAt: time.Unix(100, 0),
+		Partitions: []dto.BackupPartition{{Partition: 0, File: "partition-0.jsonl.gz", SHA256: dataSum}},
+	}, map[string]string{"partition-0.jsonl.gz": "data"})
+	writeTestBackup(t, dir, dto.BackupManifest{
+		ID: "orders-2", ClusterID: 1, Topic: "orders", Status: dto.BackupCompleted, CreatedAt: time.Unix(200, 0),
+		Partitions: []dto.BackupPartition{{Partition: 0, File: "partition-0.jsonl.gz", SHA256: dataSum}},
+	}, map[string]string{"partition-0.jsonl.gz": "tampered"})
+	writeTestBackup(t, dir, dto.BackupManifest{
+		ID: "payments-1", ClusterID: 2, Topic: "payments", Status: dto.BackupRunning, CreatedAt: time.Unix(300, 0),
+	}, nil)
+
+	if err := backups.FailInterruptedBackups(); err != nil {
+		t.Fatalf("FailInterruptedBackups() error = %v", err)
+	}
+	if backup, _ := backups.GetBackup("payments-1"); backup.Status != dto.BackupFailed {
+		t.Fatalf("interrupted backup status = %s, want failed", backup.Status)
+	}
+
+	listed, err := backups.GetBackups(1, "")
+	if err != nil {
+		t.Fatalf("GetBackups() error = %v", err)
+	}
+	if len(listed) != 2 || listed[0].ID != "orders-2" {
+		t.Fatalf("GetBackups() = %v, want orders-2 and orders-1, newest first", listed)
+	}
+
+	if verification, err := backups.VerifyBackup("orders-1"); err != nil || !verification.Valid {
+		t.Fatalf("VerifyBackup(orders-1) = %+v, %v, want valid", verification, err)
+	}
+	if verification, err := backups.VerifyBackup("orders-2"); err != nil || verification.Valid || verification.Partitions[0].Actual == dataSum {
+		t.Fatalf("VerifyBackup(orders-2) = %+v, %v, want a checksum mismatch", verification, err)
+	}
+
+	for _, id := range []string{"missing", "../orders-1"} {
+		if _, err := backups.GetBackup(id); err != ErrBackupNotFound {
+			t.Fatalf("GetBackup(%q) error = %v, want ErrBackupNotFound", id, err)
+		}
+	}
+
+	if err := backups.DeleteBackup("orders-1"); err != nil {
+		t.Fatalf("DeleteBackup() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "orders-1")); !os.IsNotExist(err) {
+		t.Fatalf("backup directory still exists: %v", err)
+	}
+}
+
+func TestRestorableConfigs(t *testing.T) {
+	configs := restorableConfigs([]dto.TopicConfig{
+		{Name: "cleanup.policy", Value: "compact"},
+		{Name: "retention.ms", Value: "604800000", Default: true},
+		{Name: "message.format.version", Value: "3.0", ReadOnly: true},
+		{Name: "secret", Sensitive: true},
+	})
+	if len(configs) != 1 || configs["cleanup.policy"] != "compact" {
+		t.Fatalf("restorableConfigs() = %v, want only cleanup.policy", configs)
+	}
+}
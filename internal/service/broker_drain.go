@@ -0,0 +1,428 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+var (
+	// ErrDrainRunning is returned when a drain is started while another one runs on the cluster.
+	ErrDrainRunning = errors.New("a broker drain is already running on this cluster")
+	// ErrNoDrain is returned when cancelling a broker that is not being drained.
+	ErrNoDrain = errors.New("broker is not being drained")
+)
+
+// drainRun is the state of a broker drain running in the background.
+type drainRun struct {
+	cancel context.CancelFunc
+	status dto.DrainStatus
+	mu     sync.Mutex
+}
+
+func (r *drainRun) update(apply func(status *dto.DrainStatus)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	apply(&r.status)
+}
+
+func (r *drainRun) snapshot() dto.DrainStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+func (r *drainRun) active() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status.Status == dto.DrainLeadership || r.status.Status == dto.DrainReplicas
+}
+
+// clusterPartition is the metadata of one partition of the cluster.
+type clusterPartition struct {
+	topic    string
+	id       int32
+	leader   int32
+	replicas []int32
+	isr      []int32
+	offline  []int32
+}
+
+// describeClusterPartitions returns the metadata of every partition of every topic,
+// internal topics included, sorted by topic and partition.
+func describeClusterPartitions(admin sarama.ClusterAdmin) ([]clusterPartition, error) {
+	topics, err := admin.ListTopics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list topics: %w", err)
+	}
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	metadata, err := admin.DescribeTopics(names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topics: %w", err)
+	}
+	var partitions []clusterPartition
+	for _, topic := range metadata {
+		if topic == nil || topic.Err != sarama.ErrNoError {
+			continue
+		}
+		for _, partition := range topic.Partitions {
+			if partition == nil {
+				continue
+			}
+			partitions = append(partitions, clusterPartition{
+				topic:    topic.Name,
+				id:       partition.ID,
+				leader:   partition.Leader,
+				replicas: partition.Replicas,
+				isr:      partition.Isr,
+				offline:  partition.OfflineReplicas,
+			})
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].topic != partitions[j].topic {
+			return partitions[i].topic < partitions[j].topic
+		}
+		return partitions[i].id < partitions[j].id
+	})
+	return partitions, nil
+}
+
+// planDrain moves every replica off a broker while keeping the replication factor. Each
+// replica is replaced on a broker of a rack the other replicas of the partition do not use,
+// the drained broker's own rack first so the rack spread stays the same, then on the broker
+// holding the fewest replicas. The leadership step puts the drained broker last in the
+// replica order, so a preferred leader election moves leadership away before any data moves.
+func planDrain(partitions []clusterPartition, drained int32, racks map[int32]string) (*dto.DrainPlan, error) {
+	if _, ok := racks[drained]; !ok {
+		return nil, fmt.Errorf("%w: broker %d does not exist", ErrInvalidReassignment, drained)
+	}
+	candidates := make([]int32, 0, len(racks))
+	for broker := range racks {
+		if broker != drained {
+			candidates = append(candidates, broker)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+
+	load := make(map[int32]int, len(racks))
+	for _, p := range partitions {
+		for _, broker := range p.replicas {
+			load[broker]++
+		}
+	}
+
+	plan := &dto.DrainPlan{Broker: drained, Leadership: []dto.PartitionReassignment{}, Moves: []dto.PartitionReassignment{}}
+	for _, p := range partitions {
+		if !containsBroker(p.replicas, drained) {
+			continue
+		}
+		remaining := make([]int32, 0, len(p.replicas))
+		usedRacks := make(map[string]bool)
+		for _, broker := range p.replicas {
+			if broker != drained {
+				remaining = append(remaining, broker)
+				if racks[broker] != "" {
+					usedRacks[racks[broker]] = true
+				}
+			}
+		}
+
+		if p.replicas[0] == drained && len(remaining) > 0 {
+			reordered := append(append([]int32(nil), remaining...), drained)
+			plan.Leadership = append(plan.Leadership, newPartitionReassignment(p.topic, p.id, p.replicas, reordered))
+		}
+
+		best := int32(-1)
+		for _, broker := range candidates {
+			if containsBroker(remaining, broker) {
+				continue
+			}
+			if best < 0 || betterDrainTarget(broker, best, racks[drained], load, usedRacks, racks) {
+				best = broker
+			}
+		}
+		if best < 0 {
+			return nil, fmt.Errorf("%w: no broker is left to take over %s-%d from broker %d",
+				ErrInvalidReassignment, p.topic, p.id, drained)
+		}
+		load[best]++
+		load[drained]--
+		plan.Moves = append(plan.Moves, newPartitionReassignment(p.topic, p.id, p.replicas, append(remaining, best)))
+	}
+	return plan, nil
+}
+
+// betterDrainTarget reports whether broker is a better replacement than best for a replica
+// of the drained broker, which sat on drainedRack.
+func betterDrainTarget(broker, best int32, drainedRack string, load map[int32]int, usedRacks map[string]bool, racks map[int32]string) bool {
+	brokerSpreads := racks[broker] == "" || !usedRacks[racks[broker]]
+	bestSpreads := racks[best] == "" || !usedRacks[racks[best]]
+	if brokerSpreads != bestSpreads {
+		return brokerSpreads
+	}
+	brokerSameRack := drainedRack != "" && racks[broker] == drainedRack
+	bestSameRack := drainedRack != "" && racks[best] == drainedRack
+	if brokerSameRack != bestSameRack {
+		return brokerSameRack
+	}
+	if load[broker] != load[best] {
+		return load[broker] < load[best]
+	}
+	return broker < best
+}
+
+// PlanDrain computes how every replica is moved off a broker.
+func (s *BrokerService) PlanDrain(clusterID uint, brokerID int32) (*dto.DrainPlan, error) {
+	admin, err := s.adminClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	racks, err := brokerRacks(admin)
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := describeClusterPartitions(admin)
+	if err != nil {
+		return nil, err
+	}
+	return planDrain(partitions, brokerID, racks)
+}
+
+// DrainBroker moves every replica off a broker in the background: leadership first, by
+// putting the broker last in the replica order and electing the preferred leaders, then
+// the replicas themselves, throttled when req.Throttle is set.
+func (s *BrokerService) DrainBroker(clusterID uint, brokerID int32, req *dto.DrainRequest) (*dto.DrainStatus, error) {
+	if req.Throttle < 0 {
+		return nil, fmt.Errorf("%w: throttle must not be negative", ErrInvalidReassignment)
+	}
+	plan, err := s.PlanDrain(clusterID, brokerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if current, err := s.reassignments.GetReassignment(clusterID); err == nil && current.Status == dto.ReassignmentRunning {
+		return nil, ErrReassignmentRunning
+	}
+
+	s.mu.Lock()
+	if run, ok := s.drains[clusterID]; ok && run.active() {
+		s.mu.Unlock()
+		return nil, ErrDrainRunning
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+	run := &drainRun{
+		cancel: cancel,
+		status: dto.DrainStatus{Broker: brokerID, Status: dto.DrainLeadership, StartedAt: &now},
+	}
+	s.drains[clusterID] = run
+	s.mu.Unlock()
+
+	go s.drain(ctx, clusterID, plan, req.Throttle, run)
+
+	return s.GetDrain(clusterID, brokerID)
+}
+
+func (s *BrokerService) drain(ctx context.Context, clusterID uint, plan *dto.DrainPlan, throttle int64, run *drainRun) {
+	defer run.cancel()
+
+	err := s.drainLeadership(ctx, clusterID, plan)
+	if err == nil {
+		run.update(func(status *dto.DrainStatus) { status.Status = dto.DrainReplicas })
+		err = s.runReassignment(ctx, clusterID, plan.Moves, throttle, run)
+	}
+
+	hosted := -1
+	if admin, adminErr := s.adminClient(clusterID); adminErr == nil {
+		if leaders, replicas, countErr := brokerPartitionCounts(admin, plan.Broker); countErr == nil {
+			hosted = replicas
+			run.update(func(status *dto.DrainStatus) { status.Leaders, status.Replicas = leaders, replicas })
+		}
+	}
+
+	run.update(func(status *dto.DrainStatus) {
+		now := time.Now()
+		status.FinishedAt = &now
+		switch {
+		case ctx.Err() != nil:
+			status.Status = dto.DrainCancelled
+		case err != nil:
+			status.Status = dto.DrainFailed
+			status.Error = err.Error()
+		default:
+			status.Status = dto.DrainCompleted
+			status.Drained = hosted == 0
+		}
+	})
+}
+
+// drainLeadership moves the preferred leadership away from the drained broker and elects
+// the preferred leaders of every partition it leads.
+func (s *BrokerService) drainLeadership(ctx context.Context, clusterID uint, plan *dto.DrainPlan) error {
+	if err := s.runReassignment(ctx, clusterID, plan.Leadership, 0, nil); err != nil {
+		return err
+	}
+
+	admin, err := s.adminClient(clusterID)
+	if err != nil {
+		return err
+	}
+	partitions, err := describeClusterPartitions(admin)
+	if err != nil {
+		return err
+	}
+	elect := make(map[string][]int32)
+	for _, p := range partitions {
+		if p.leader == plan.Broker && len(p.replicas) > 1 {
+			elect[p.topic] = append(elect[p.topic], p.id)
+		}
+	}
+	if len(elect) == 0 {
+		return nil
+	}
+	results, err := admin.ElectLeaders(sarama.PreferredElection, elect)
+	if err != nil {
+		return fmt.Errorf("failed to elect preferred leaders: %w", err)
+	}
+	for topic, partitionResults := range results {
+		for partition, result := range partitionResults {
+			// the replica moves below take over the partitions that could not switch leaders yet
+			if result != nil && result.ErrorCode != sarama.ErrNoError && result.ErrorCode != sarama.ErrElectionNotNeeded {
+				log.Printf("[BrokerDrain] Failed to move leadership of %s-%d off broker %d: %v", topic, partition, plan.Broker, result.ErrorCode)
+			}
+		}
+	}
+	return nil
+}
+
+// runReassignment executes one step of a drain and waits for it to end.
+func (s *BrokerService) runReassignment(ctx context.Context, clusterID uint, partitions []dto.PartitionReassignment, throttle int64, run *drainRun) error {
+	if len(partitions) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	req := &dto.ReassignmentRequest{Partitions: partitions, Throttle: throttle}
+	if err := ValidateReassignment(req); err != nil {
+		return err
+	}
+	reassignment, err := s.reassignments.StartReassignment(clusterID, req)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		// CancelDrain ran while the reassignment was being started and found nothing to cancel
+		if _, cancelErr := s.reassignments.CancelReassignment(clusterID); cancelErr != nil && !ignoredCancelError(cancelErr) {
+			return cancelErr
+		}
+		return err
+	}
+
+	status, err := reassignment.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	if run != nil {
+		run.update(func(drain *dto.DrainStatus) { drain.Reassignment = status })
+	}
+	if status.Status != dto.ReassignmentCompleted {
+		return fmt.Errorf("reassignment %s: %s", status.Status, status.Error)
+	}
+	return nil
+}
+
+// GetDrain returns the drain of a broker, if one was started, and what the broker still hosts.
+func (s *BrokerService) GetDrain(clusterID uint, brokerID int32) (*dto.DrainStatus, error) {
+	admin, err := s.adminClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	leaders, replicas, err := brokerPartitionCounts(admin, brokerID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := dto.DrainStatus{Broker: brokerID}
+	s.mu.Lock()
+	run, ok := s.drains[clusterID]
+	s.mu.Unlock()
+	if ok && run.snapshot().Broker == brokerID {
+		status = run.snapshot()
+		if status.Reassignment == nil || status.Status == dto.DrainLeadership || status.Status == dto.DrainReplicas {
+			if current, err := s.reassignments.GetReassignment(clusterID); err == nil && current.Status == dto.ReassignmentRunning {
+				status.Reassignment = current
+			}
+		}
+	}
+	status.Leaders = leaders
+	status.Replicas = replicas
+	status.Drained = replicas == 0
+	return &status, nil
+}
+
+// CancelDrain stops a running drain together with the replica moves still in progress.
+// Partitions that already moved stay on their new brokers.
+func (s *BrokerService) CancelDrain(clusterID uint, brokerID int32) (*dto.DrainStatus, error) {
+	s.mu.Lock()
+	run, ok := s.drains[clusterID]
+	s.mu.Unlock()
+	if !ok || !run.active() || run.snapshot().Broker != brokerID {
+		return nil, ErrNoDrain
+	}
+
+	run.cancel()
+	// a reassignment the drain is still starting is cancelled by the drain itself
+	if _, err := s.reassignments.CancelReassignment(clusterID); err != nil && !ignoredCancelError(err) {
+		return nil, err
+	}
+	return s.GetDrain(clusterID, brokerID)
+}
+
+// ignoredCancelError reports whether cancelling the reassignment of a drain failed only
+// because there was none, or because another request is starting or cancelling it.
+func ignoredCancelError(err error) bool {
+	return errors.Is(err, ErrNoReassignment) || errors.Is(err, ErrReassignmentBusy)
+}
+
+// brokerPartitionCounts counts the partitions a broker leads and the replicas it hosts.
+func brokerPartitionCounts(admin sarama.ClusterAdmin, brokerID int32) (int, int, error) {
+	partitions, err := describeClusterPartitions(admin)
+	if err != nil {
+		return 0, 0, err
+	}
+	leaders, replicas := 0, 0
+	for _, p := range partitions {
+		if p.leader == brokerID {
+			leaders++
+		}
+		if containsBroker(p.replicas, brokerID) {
+			replicas++
+		}
+	}
+	return leaders, replicas, nil
+}
+
+func (s *BrokerService) adminClient(clusterID uint) (sarama.ClusterAdmin, error) {
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return s.kafkaManager.GetAdminClient(cluster)
+}
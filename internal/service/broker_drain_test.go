@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlanDrain(t *testing.T) {
+	partitions := []clusterPartition{
+		{topic: "orders", id: 0, leader: 1, replicas: []int32{1, 2}},
+		{topic: "orders", id: 1, leader: 2, replicas: []int32{2, 3}},
+		{topic: "orders", id: 2, leader: 3, replicas: []int32{3, 1}},
+		{topic: "payments", id: 0, leader: 4, replicas: []int32{4}},
+	}
+	racks := map[int32]string{1: "a", 2: "b", 3: "b", 4: "a"}
+
+	plan, err := planDrain(partitions, 1, racks)
+	if err != nil {
+		t.Fatalf("planDrain() error = %v", err)
+	}
+
+	// only orders-0 has broker 1 as preferred leader
+	if len(plan.Leadership) != 1 || !reflect.DeepEqual(plan.Leadership[0].Target, []int32{2, 1}) || len(plan.Leadership[0].Adding) != 0 {
+		t.Fatalf("leadership = %+v, want orders-0 reordered to [2 1] without new replicas", plan.Leadership)
+	}
+
+	// broker 4 shares rack a with broker 1, so the rack spread of both partitions stays the same
+	want := map[int32][]int32{0: {2, 4}, 2: {3, 4}}
+	if len(plan.Moves) != len(want) {
+		t.Fatalf("moves = %+v, want %v", plan.Moves, want)
+	}
+	for _, move := range plan.Moves {
+		if move.Topic != "orders" || !reflect.DeepEqual(move.Target, want[move.Partition]) {
+			t.Fatalf("move of %s-%d = %v, want %v", move.Topic, move.Partition, move.Target, want[move.Partition])
+		}
+		if !reflect.DeepEqual(move.Removing, []int32{1}) {
+			t.Fatalf("move of %s-%d removes %v, want [1]", move.Topic, move.Partition, move.Removing)
+		}
+	}
+}
+
+func TestPlanDrainSpreadsLoadWithoutRacks(t *testing.T) {
+	partitions := []clusterPartition{
+		{topic: "orders", id: 0, replicas: []int32{1, 2}},
+		{topic: "orders", id: 1, replicas: []int32{1, 2}},
+		{topic: "orders", id: 2, replicas: []int32{3, 4}},
+	}
+	racks := map[int32]string{1: "", 2: "", 3: "", 4: ""}
+
+	plan, err := planDrain(partitions, 1, racks)
+	if err != nil {
+		t.Fatalf("planDrain() error = %v", err)
+	}
+	var got [][]int32
+	for _, move := range plan.Moves {
+		got = append(got, move.Target)
+	}
+	// broker 3 and 4 hold one replica each, so the two replicas of broker 1 are split between them
+	want := [][]int32{{2, 3}, {2, 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("targets = %v, want %v", got, want)
+	}
+}
+
+func TestPlanDrainRejects(t *testing.T) {
+	tests := []struct {
+		name       string
+		partitions []clusterPartition
+		broker     int32
+	}{
+		{name: "unknown broker", broker: 9},
+		{name: "no broker left", partitions: []clusterPartition{{topic: "orders", replicas: []int32{1, 2}}}, broker: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planDrain(tt.partitions, tt.broker, map[int32]string{1: "", 2: ""})
+			if !errors.Is(err, ErrInvalidReassignment) {
+				t.Fatalf("planDrain() error = %v, want ErrInvalidReassignment", err)
+			}
+		})
+	}
+}
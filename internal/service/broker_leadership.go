@@ -0,0 +1,236 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+// ErrInvalidElection is returned when a leader election request is malformed or names
+// partitions that do not exist.
+var ErrInvalidElection = errors.New("invalid leader election")
+
+// leadershipReport counts the partitions led by another broker than their preferred
+// leader and compares the leaders of every broker with the partitions it should lead.
+func leadershipReport(partitions []clusterPartition, brokers []int32) *dto.LeadershipReport {
+	report := &dto.LeadershipReport{Partitions: len(partitions), Skewed: []dto.PartitionLeadership{}}
+	loads := make(map[int32]*dto.BrokerLeadership, len(brokers))
+	load := func(broker int32) *dto.BrokerLeadership {
+		if loads[broker] == nil {
+			loads[broker] = &dto.BrokerLeadership{Broker: broker}
+		}
+		return loads[broker]
+	}
+	for _, broker := range brokers {
+		load(broker)
+	}
+
+	for _, p := range partitions {
+		if p.leader < 0 {
+			report.Leaderless++
+		} else {
+			load(p.leader).Leaders++
+		}
+		if len(p.replicas) == 0 {
+			continue
+		}
+		preferred := p.replicas[0]
+		load(preferred).Preferred++
+		if p.leader == preferred {
+			continue
+		}
+		report.NotPreferred++
+		report.Skewed = append(report.Skewed, dto.PartitionLeadership{
+			Topic:     p.topic,
+			Partition: p.id,
+			Leader:    p.leader,
+			Preferred: preferred,
+			Electable: containsBroker(p.isr, preferred) && !containsBroker(p.offline, preferred),
+		})
+	}
+
+	report.Brokers = make([]dto.BrokerLeadership, 0, len(loads))
+	for _, broker := range loads {
+		broker.Skew = broker.Leaders - broker.Preferred
+		report.Brokers = append(report.Brokers, *broker)
+	}
+	// a broker only misses the partitions it is preferred for but does not lead
+	for i := range report.Brokers {
+		broker := &report.Brokers[i]
+		if broker.Preferred == 0 {
+			continue
+		}
+		missed := 0
+		for _, skewed := range report.Skewed {
+			if skewed.Preferred == broker.Broker {
+				missed++
+			}
+		}
+		broker.Imbalance = float64(missed) * 100 / float64(broker.Preferred)
+	}
+	sort.Slice(report.Brokers, func(i, j int) bool { return report.Brokers[i].Broker < report.Brokers[j].Broker })
+	return report
+}
+
+// GetLeadership reports the leadership skew of the cluster.
+func (s *BrokerService) GetLeadership(clusterID uint) (*dto.LeadershipReport, error) {
+	admin, err := s.adminClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	racks, err := brokerRacks(admin)
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := describeClusterPartitions(admin)
+	if err != nil {
+		return nil, err
+	}
+	brokers := make([]int32, 0, len(racks))
+	for broker := range racks {
+		brokers = append(brokers, broker)
+	}
+	return leadershipReport(partitions, brokers), nil
+}
+
+// electionTargets resolves the partitions selected by an election request. Partitions
+// that an election would not change are returned apart: a preferred election only moves
+// partitions not led by their first replica, an unclean one only partitions without a leader.
+func electionTargets(partitions []clusterPartition, req *dto.LeaderElectionRequest) (map[string][]int32, []dto.PartitionElection, error) {
+	switch req.Type {
+	case "":
+		req.Type = dto.PreferredElection
+	case dto.PreferredElection:
+	case dto.UncleanElection:
+		if !req.ConfirmUnclean {
+			return nil, nil, fmt.Errorf("%w: an unclean election may lose records and must be confirmed", ErrInvalidElection)
+		}
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown election type %q", ErrInvalidElection, req.Type)
+	}
+	if req.All == (len(req.Topics) > 0 || len(req.Partitions) > 0) {
+		return nil, nil, fmt.Errorf("%w: select either the entire cluster or topics and partitions", ErrInvalidElection)
+	}
+
+	byPartition := make(map[dto.TopicPartition]clusterPartition, len(partitions))
+	byTopic := make(map[string][]clusterPartition)
+	for _, p := range partitions {
+		byPartition[dto.TopicPartition{Topic: p.topic, Partition: p.id}] = p
+		byTopic[p.topic] = append(byTopic[p.topic], p)
+	}
+
+	var selected []clusterPartition
+	if req.All {
+		selected = partitions
+	}
+	seen := make(map[dto.TopicPartition]bool)
+	for _, topic := range req.Topics {
+		topic = strings.TrimSpace(topic)
+		topicPartitions, ok := byTopic[topic]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: topic %s does not exist", ErrInvalidElection, topic)
+		}
+		for _, p := range topicPartitions {
+			key := dto.TopicPartition{Topic: p.topic, Partition: p.id}
+			if !seen[key] {
+				seen[key] = true
+				selected = append(selected, p)
+			}
+		}
+	}
+	for _, partition := range req.Partitions {
+		partition.Topic = strings.TrimSpace(partition.Topic)
+		p, ok := byPartition[partition]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: partition %s-%d does not exist", ErrInvalidElection, partition.Topic, partition.Partition)
+		}
+		if !seen[partition] {
+			seen[partition] = true
+			selected = append(selected, p)
+		}
+	}
+
+	targets := make(map[string][]int32)
+	var notNeeded []dto.PartitionElection
+	for _, p := range selected {
+		needed := p.leader < 0
+		if req.Type == dto.PreferredElection {
+			needed = len(p.replicas) > 0 && p.leader != p.replicas[0]
+		}
+		if needed {
+			targets[p.topic] = append(targets[p.topic], p.id)
+		} else {
+			notNeeded = append(notNeeded, dto.PartitionElection{Topic: p.topic, Partition: p.id, Status: dto.ElectionNotNeeded})
+		}
+	}
+	return targets, notNeeded, nil
+}
+
+// ElectLeaders runs a preferred or unclean leader election on the selected partitions.
+func (s *BrokerService) ElectLeaders(clusterID uint, req *dto.LeaderElectionRequest) (*dto.LeaderElectionResult, error) {
+	admin, err := s.adminClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := describeClusterPartitions(admin)
+	if err != nil {
+		return nil, err
+	}
+	targets, elections, err := electionTargets(partitions, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targets) > 0 {
+		electionType := sarama.PreferredElection
+		if req.Type == dto.UncleanElection {
+			electionType = sarama.UncleanElection
+		}
+		results, err := admin.ElectLeaders(electionType, targets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to elect leaders: %w", err)
+		}
+		for topic, ids := range targets {
+			for _, id := range ids {
+				election := dto.PartitionElection{Topic: topic, Partition: id, Status: dto.ElectionElected}
+				if result := results[topic][id]; result == nil {
+					election.Status = dto.ElectionFailed
+					election.Error = "no result returned by the controller"
+				} else if result.ErrorCode == sarama.ErrElectionNotNeeded {
+					election.Status = dto.ElectionNotNeeded
+				} else if result.ErrorCode != sarama.ErrNoError {
+					election.Status = dto.ElectionFailed
+					election.Error = result.ErrorCode.Error()
+					if result.ErrorMessage != nil && *result.ErrorMessage != "" {
+						election.Error = *result.ErrorMessage
+					}
+				}
+				elections = append(elections, election)
+			}
+		}
+	}
+
+	result := &dto.LeaderElectionResult{Type: req.Type, Partitions: make([]dto.PartitionElection, 0, len(elections))}
+	for _, election := range elections {
+		switch election.Status {
+		case dto.ElectionElected:
+			result.Elected++
+		case dto.ElectionNotNeeded:
+			result.NotNeeded++
+		default:
+			result.Failed++
+		}
+		result.Partitions = append(result.Partitions, election)
+	}
+	sort.Slice(result.Partitions, func(i, j int) bool {
+		if result.Partitions[i].Topic != result.Partitions[j].Topic {
+			return result.Partitions[i].Topic < result.Partitions[j].Topic
+		}
+		return result.Partitions[i].Partition < result.Partitions[j].Partition
+	})
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+func TestLeadershipReport(t *testing.T) {
+	partitions := []clusterPartition{
+		{topic: "orders", id: 0, leader: 1, replicas: []int32{1, 2}, isr: []int32{1, 2}},
+		{topic: "orders", id: 1, leader: 1, replicas: []int32{2, 1}, isr: []int32{1, 2}},
+		{topic: "orders", id: 2, leader: 1, replicas: []int32{3, 1}, isr: []int32{1}},
+		{topic: "payments", id: 0, leader: -1, replicas: []int32{3}, offline: []int32{3}},
+	}
+
+	report := leadershipReport(partitions, []int32{1, 2, 3})
+	if report.Partitions != 4 || report.NotPreferred != 3 || report.Leaderless != 1 {
+		t.Fatalf("report = %d partitions, %d not preferred, %d leaderless, want 4, 3, 1",
+			report.Partitions, report.NotPreferred, report.Leaderless)
+	}
+
+	wantBrokers := []dto.BrokerLeadership{
+		{Broker: 1, Leaders: 3, Preferred: 1, Skew: 2},
+		{Broker: 2, Leaders: 0, Preferred: 1, Skew: -1, Imbalance: 100},
+		{Broker: 3, Leaders: 0, Preferred: 2, Skew: -2, Imbalance: 100},
+	}
+	if !reflect.DeepEqual(report.Brokers, wantBrokers) {
+		t.Fatalf("brokers = %+v, want %+v", report.Brokers, wantBrokers)
+	}
+
+	wantSkewed := []dto.PartitionLeadership{
+		{Topic: "orders", Partition: 1, Leader: 1, Preferred: 2, Electable: true},
+		{Topic: "orders", Partition: 2, Leader: 1, Preferred: 3},
+		{Topic: "payments", Partition: 0, Leader: -1, Preferred: 3},
+	}
+	if !reflect.DeepEqual(report.Skewed, wantSkewed) {
+		t.Fatalf("skewed = %+v, want %+v", report.Skewed, wantSkewed)
+	}
+}
+
+func TestElectionTargets(t *testing.T) {
+	partitions := []clusterPartition{
+		{topic: "orders", id: 0, leader: 1, replicas: []int32{1, 2}},
+		{topic: "orders", id: 1, leader: 1, replicas: []int32{2, 1}},
+		{topic: "payments", id: 0, leader: -1, replicas: []int32{3, 1}},
+	}
+
+	tests := []struct {
+		name          string
+		req           dto.LeaderElectionRequest
+		want          map[string][]int32
+		wantNotNeeded int
+		wantErr       bool
+	}{
+		{
+			name: "preferred on the cluster",
+			req:  dto.LeaderElectionRequest{All: true},
+			want: map[string][]int32{"orders": {1}, "payments": {0}},
+			// orders-0 is already led by its preferred leader
+			wantNotNeeded: 1,
+		},
+		{
+			name:          "preferred on a topic and a partition",
+			req:           dto.LeaderElectionRequest{Type: dto.PreferredElection, Topics: []string{"orders"}, Partitions: []dto.TopicPartition{{Topic: "orders", Partition: 1}}},
+			want:          map[string][]int32{"orders": {1}},
+			wantNotNeeded: 1,
+		},
+		{
+			name:          "unclean only elects leaderless partitions",
+			req:           dto.LeaderElectionRequest{Type: dto.UncleanElection, All: true, ConfirmUnclean: true},
+			want:          map[string][]int32{"payments": {0}},
+			wantNotNeeded: 2,
+		},
+		{name: "unclean without confirmation", req: dto.LeaderElectionRequest{Type: dto.UncleanElection, All: true}, wantErr: true},
+		{name: "unknown type", req: dto.LeaderElectionRequest{Type: "random", All: true}, wantErr: true},
+		{name: "nothing selected", req: dto.LeaderElectionRequest{}, wantErr: true},
+		{name: "cluster and topics", req: dto.LeaderElectionRequest{All: true, Topics: []string{"orders"}}, wantErr: true},
+		{name: "unknown topic", req: dto.LeaderElectionRequest{Topics: []string{"missing"}}, wantErr: true},
+		{name: "unknown partition", req: dto.LeaderElectionRequest{Partitions: []dto.TopicPartition{{Topic: "orders", Partition: 5}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, notNeeded, err := electionTargets(partitions, &tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("electionTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidElection) {
+					t.Fatalf("electionTargets() error = %v, want ErrInvalidElection", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("electionTargets() = %v, want %v", got, tt.want)
+			}
+			if len(notNeeded) != tt.wantNotNeeded {
+				t.Fatalf("not needed = %+v, want %d partitions", notNeeded, tt.wantNotNeeded)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/IBM/sarama"
 	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
@@ -10,14 +11,19 @@ import (
 )
 
 type BrokerService struct {
-	clusterRepo  *repository.ClusterRepository
-	kafkaManager *util.KafkaClientManager
+	clusterRepo   *repository.ClusterRepository
+	kafkaManager  *util.KafkaClientManager
+	reassignments *ReassignmentService
+	drains        map[uint]*drainRun // by cluster, one drain at a time
+	mu            sync.Mutex
 }
 
-func NewBrokerService(clusterRepo *repository.ClusterRepository, kafkaManager *util.KafkaClientManager) *BrokerService {
+func NewBrokerService(clusterRepo *repository.ClusterRepository, kafkaManager *util.KafkaClientManager, reassignments *ReassignmentService) *BrokerService {
 	return &BrokerService{
-		clusterRepo:  clusterRepo,
-		kafkaManager: kafkaManager,
+		clusterRepo:   clusterRepo,
+		kafkaManager:  kafkaManager,
+		reassignments: reassignments,
+		drains:        make(map[uint]*drainRun),
 	}
 }
 
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+// severityRank orders health severities from healthy to critical.
+var severityRank = map[string]int{dto.HealthOK: 0, dto.HealthWarning: 1, dto.HealthCritical: 2}
+
+func worseSeverity(a, b string) string {
+	if severityRank[b] > severityRank[a] {
+		return b
+	}
+	return a
+}
+
+// partitionHealth checks one partition, returning nil when it has no issue. Offline and
+// under-min-ISR partitions are critical since clients fail; the others are warnings.
+func partitionHealth(p clusterPartition, registered map[int32]string, minISR int) *dto.PartitionHealth {
+	health := &dto.PartitionHealth{
+		Topic:     p.topic,
+		Partition: p.id,
+		Severity:  dto.HealthOK,
+		Issues:    []string{},
+		Leader:    p.leader,
+		Replicas:  p.replicas,
+		Isr:       p.isr,
+		Offline:   p.offline,
+	}
+	issue := func(name, severity string) {
+		health.Issues = append(health.Issues, name)
+		health.Severity = worseSeverity(health.Severity, severity)
+	}
+
+	if p.leader < 0 {
+		issue(dto.HealthOffline, dto.HealthCritical)
+	}
+	if len(p.isr) < minISR {
+		issue(dto.HealthUnderMinISR, dto.HealthCritical)
+	}
+	if len(p.isr) < len(p.replicas) {
+		issue(dto.HealthUnderReplicated, dto.HealthWarning)
+	}
+	for _, broker := range p.replicas {
+		if _, ok := registered[broker]; !ok {
+			health.Unregistered = append(health.Unregistered, broker)
+		}
+	}
+	if len(health.Unregistered) > 0 {
+		issue(dto.HealthUnregisteredReplicas, dto.HealthWarning)
+	}
+
+	if len(health.Issues) == 0 {
+		return nil
+	}
+	return health
+}
+
+// clusterHealth scans every partition and groups the unhealthy ones by topic and by the
+// brokers whose replicas are out of sync. minISR holds min.insync.replicas per topic.
+func clusterHealth(partitions []clusterPartition, registered map[int32]string, minISR map[string]int) *dto.ClusterHealth {
+	health := &dto.ClusterHealth{Severity: dto.HealthOK, Partitions: len(partitions), Topics: []dto.TopicHealth{}}
+
+	topics := make(map[string]*dto.TopicHealth)
+	brokers := make(map[int32]*dto.BrokerHealth, len(registered))
+	broker := func(id int32) *dto.BrokerHealth {
+		if brokers[id] == nil {
+			_, ok := registered[id]
+			brokers[id] = &dto.BrokerHealth{Broker: id, Registered: ok, Severity: dto.HealthOK, Partitions: []dto.PartitionHealth{}}
+		}
+		return brokers[id]
+	}
+	for id := range registered {
+		broker(id)
+	}
+
+	for _, p := range partitions {
+		partition := partitionHealth(p, registered, minISR[p.topic])
+		if partition == nil {
+			continue
+		}
+		for _, issue := range partition.Issues {
+			switch issue {
+			case dto.HealthOffline:
+				health.Offline++
+			case dto.HealthUnderMinISR:
+				health.UnderMinISR++
+			case dto.HealthUnderReplicated:
+				health.UnderReplicated++
+			case dto.HealthUnregisteredReplicas:
+				health.UnregisteredReplicas++
+			}
+		}
+		health.Severity = worseSeverity(health.Severity, partition.Severity)
+
+		topic := topics[p.topic]
+		if topic == nil {
+			topic = &dto.TopicHealth{Topic: p.topic, Severity: dto.HealthOK, MinInSyncReplicas: minISR[p.topic]}
+			topics[p.topic] = topic
+		}
+		topic.Severity = worseSeverity(topic.Severity, partition.Severity)
+		topic.Partitions = append(topic.Partitions, *partition)
+
+		// an offline partition involves all its replicas, otherwise only the lagging ones
+		for _, id := range p.replicas {
+			if p.leader >= 0 && containsBroker(p.isr, id) && !containsBroker(partition.Unregistered, id) {
+				continue
+			}
+			b := broker(id)
+			b.Severity = worseSeverity(b.Severity, partition.Severity)
+			b.Partitions = append(b.Partitions, *partition)
+		}
+	}
+
+	for _, topic := range topics {
+		health.Topics = append(health.Topics, *topic)
+	}
+	sort.Slice(health.Topics, func(i, j int) bool {
+		a, b := health.Topics[i], health.Topics[j]
+		if a.Severity != b.Severity {
+			return severityRank[a.Severity] > severityRank[b.Severity]
+		}
+		return a.Topic < b.Topic
+	})
+	health.Brokers = make([]dto.BrokerHealth, 0, len(brokers))
+	for _, b := range brokers {
+		health.Brokers = append(health.Brokers, *b)
+	}
+	sort.Slice(health.Brokers, func(i, j int) bool { return health.Brokers[i].Broker < health.Brokers[j].Broker })
+	return health
+}
+
+// GetClusterHealth scans the metadata of every topic for offline, under-min-ISR and
+// under-replicated partitions and for replicas on brokers that are not registered.
+func (s *ClusterService) GetClusterHealth(id uint) (*dto.ClusterHealth, error) {
+	cluster, err := s.clusterRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := s.kafkaManager.GetAdminClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	registered, err := brokerRacks(admin)
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := describeClusterPartitions(admin)
+	if err != nil {
+		return nil, err
+	}
+	minISR := make(map[string]int)
+	for _, p := range partitions {
+		if _, ok := minISR[p.topic]; ok {
+			continue
+		}
+		if minISR[p.topic], err = topicMinInSyncReplicas(admin, p.topic); err != nil {
+			return nil, err
+		}
+	}
+
+	health := clusterHealth(partitions, registered, minISR)
+	health.ClusterID = id
+	health.CheckedAt = time.Now()
+	return health, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+func TestClusterHealth(t *testing.T) {
+	partitions := []clusterPartition{
+		{topic: "orders", id: 0, leader: 1, replicas: []int32{1, 2, 3}, isr: []int32{1, 2, 3}},
+		{topic: "orders", id: 1, leader: 1, replicas: []int32{1, 2, 3}, isr: []int32{1, 2}},
+		{topic: "orders", id: 2, leader: 1, replicas: []int32{1, 2, 3}, isr: []int32{1}},
+		{topic: "payments", id: 0, leader: -1, replicas: []int32{4}, offline: []int32{4}},
+	}
+	registered := map[int32]string{1: "", 2: "", 3: ""}
+
+	health := clusterHealth(partitions, registered, map[string]int{"orders": 2, "payments": 1})
+	if health.Severity != dto.HealthCritical || health.Partitions != 4 {
+		t.Fatalf("health = %s over %d partitions, want critical over 4", health.Severity, health.Partitions)
+	}
+	if health.Offline != 1 || health.UnderMinISR != 2 || health.UnderReplicated != 3 || health.UnregisteredReplicas != 1 {
+		t.Fatalf("counts = %d offline, %d under min ISR, %d under-replicated, %d unregistered, want 1, 2, 3, 1",
+			health.Offline, health.UnderMinISR, health.UnderReplicated, health.UnregisteredReplicas)
+	}
+
+	if len(health.Topics) != 2 || health.Topics[0].Topic != "orders" || health.Topics[1].Topic != "payments" {
+		t.Fatalf("topics = %+v, want orders and payments", health.Topics)
+	}
+	orders := health.Topics[0]
+	if orders.Severity != dto.HealthCritical || len(orders.Partitions) != 2 {
+		t.Fatalf("orders = %s with %d partitions, want critical with 2", orders.Severity, len(orders.Partitions))
+	}
+	if got := orders.Partitions[0]; got.Severity != dto.HealthWarning || !reflect.DeepEqual(got.Issues, []string{dto.HealthUnderReplicated}) {
+		t.Fatalf("orders-1 = %s %v, want a warning for under-replicated", got.Severity, got.Issues)
+	}
+	if got := orders.Partitions[1]; !reflect.DeepEqual(got.Issues, []string{dto.HealthUnderMinISR, dto.HealthUnderReplicated}) {
+		t.Fatalf("orders-2 issues = %v, want under-min-isr and under-replicated", got.Issues)
+	}
+	payments := health.Topics[1].Partitions[0]
+	want := []string{dto.HealthOffline, dto.HealthUnderMinISR, dto.HealthUnderReplicated, dto.HealthUnregisteredReplicas}
+	if !reflect.DeepEqual(payments.Issues, want) || !reflect.DeepEqual(payments.Unregistered, []int32{4}) {
+		t.Fatalf("payments-0 = %v on unregistered %v, want %v on [4]", payments.Issues, payments.Unregistered, want)
+	}
+
+	// brokers list the partitions where their replica lags, broker 4 is not registered
+	wantBrokers := map[int32]struct {
+		registered bool
+		severity   string
+		partitions int
+	}{
+		1: {true, dto.HealthOK, 0},
+		2: {true, dto.HealthCritical, 1},
+		3: {true, dto.HealthCritical, 2},
+		4: {false, dto.HealthCritical, 1},
+	}
+	if len(health.Brokers) != len(wantBrokers) {
+		t.Fatalf("brokers = %+v, want %d brokers", health.Brokers, len(wantBrokers))
+	}
+	for _, broker := range health.Brokers {
+		w := wantBrokers[broker.Broker]
+		if broker.Registered != w.registered || broker.Severity != w.severity || len(broker.Partitions) != w.partitions {
+			t.Fatalf("broker %d = registered %v, %s with %d partitions, want %v, %s with %d",
+				broker.Broker, broker.Registered, broker.Severity, len(broker.Partitions), w.registered, w.severity, w.partitions)
+		}
+	}
+}
+
+func TestClusterHealthHealthy(t *testing.T) {
+	partitions := []clusterPartition{
+		{topic: "orders", id: 0, leader: 1, replicas: []int32{1, 2}, isr: []int32{2, 1}},
+	}
+
+	health := clusterHealth(partitions, map[int32]string{1: "", 2: ""}, map[string]int{"orders": 1})
+	if health.Severity != dto.HealthOK || len(health.Topics) != 0 || len(health.Brokers) != 2 {
+		t.Fatalf("health = %+v, want ok without topics and with 2 brokers", health)
+	}
+}
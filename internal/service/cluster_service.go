@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"strings"
 	"time"
 
@@ -95,14 +96,15 @@ func (s *ClusterService) GetClusterInfo(id uint) (*dto.ClusterInfo, error) {
 	}
 
 	info := &dto.ClusterInfo{
-		ID:               cluster.ID,
-		Name:             cluster.Name,
-		Servers:          cluster.Servers,
-		SecurityProtocol: cluster.SecurityProtocol,
-		SaslMechanism:    cluster.SaslMechanism,
-		SaslUsername:     cluster.SaslUsername,
-		CreatedAt:        cluster.CreatedAt,
-		UpdatedAt:        cluster.UpdatedAt,
+		ID:                cluster.ID,
+		Name:              cluster.Name,
+		Servers:           cluster.Servers,
+		SecurityProtocol:  cluster.SecurityProtocol,
+		SaslMechanism:     cluster.SaslMechanism,
+		SaslUsername:      cluster.SaslUsername,
+		SchemaRegistryURL: cluster.SchemaRegistryURL,
+		CreatedAt:         cluster.CreatedAt,
+		UpdatedAt:         cluster.UpdatedAt,
 	}
 
 	admin, err := s.kafkaManager.GetAdminClient(cluster)
@@ -205,6 +207,7 @@ func normalizeCluster(cluster *model.Cluster) error {
 	cluster.SecurityProtocol = strings.ToUpper(strings.TrimSpace(cluster.SecurityProtocol))
 	cluster.SaslMechanism = strings.ToUpper(strings.TrimSpace(cluster.SaslMechanism))
 	cluster.SaslUsername = strings.TrimSpace(cluster.SaslUsername)
+	cluster.SchemaRegistryURL = strings.TrimRight(strings.TrimSpace(cluster.SchemaRegistryURL), "/")
 
 	if cluster.SecurityProtocol == "" {
 		cluster.SecurityProtocol = "PLAINTEXT"
@@ -234,6 +237,13 @@ func normalizeCluster(cluster *model.Cluster) error {
 		}
 	}
 
+	if cluster.SchemaRegistryURL != "" {
+		registryURL, err := url.Parse(cluster.SchemaRegistryURL)
+		if err != nil || (registryURL.Scheme != "http" && registryURL.Scheme != "https") || registryURL.Host == "" {
+			return fmt.Errorf("schemaRegistryUrl must be an http(s) URL")
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSONPath condition operators
+const (
+	JSONPathEqual        = "=="
+	JSONPathNotEqual     = "!="
+	JSONPathLess         = "<"
+	JSONPathLessEqual    = "<="
+	JSONPathGreater      = ">"
+	JSONPathGreaterEqual = ">="
+	JSONPathIn           = "in"
+	JSONPathExists       = "exists"
+	JSONPathRegex        = "regex"
+)
+
+// JSONPathCondition is a typed predicate on the JSON value of a record, such as
+//
+//	$.payload.user.id == 12
+//	$.status in ["NEW", "PAID"]
+//	$.items[*].sku regex "^AB-"
+//	$.payload.coupon exists
+//
+// The operand is read as JSON, so 12 is a number, true a boolean and "12" a string;
+// unquoted text that is not valid JSON is taken as a string. Comparisons are typed:
+// the number 12 equals neither 123 nor the string "12". Paths support .name, ['name'],
+// [index] and the [*] wildcard; a condition holds if any selected node satisfies it.
+type JSONPathCondition struct {
+	Path    string
+	Op      string
+	Operand interface{}
+	steps   []jsonPathStep
+	pattern *regexp.Regexp
+}
+
+// JSONPathFilter combines conditions with AND, or with OR when Any is set.
+type JSONPathFilter struct {
+	Conditions []*JSONPathCondition
+	Any        bool
+}
+
+type jsonPathStep struct {
+	name     string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// ParseJSONPathCondition parses a condition of the form "<path> <op> [operand]".
+func ParseJSONPathCondition(source string) (*JSONPathCondition, error) {
+	source = strings.TrimSpace(source)
+	if !strings.HasPrefix(source, "$") {
+		return nil, fmt.Errorf("json path %q must start with $", source)
+	}
+
+	pathEnd := scanJSONPath(source)
+	condition := &JSONPathCondition{Path: source[:pathEnd]}
+	steps, err := parseJSONPath(condition.Path)
+	if err != nil {
+		return nil, err
+	}
+	condition.steps = steps
+
+	rest := strings.TrimSpace(source[pathEnd:])
+	for _, op := range []string{JSONPathEqual, JSONPathNotEqual, JSONPathLessEqual, JSONPathGreaterEqual, JSONPathLess, JSONPathGreater, "=~", JSONPathIn, JSONPathExists, JSONPathRegex} {
+		if !strings.HasPrefix(rest, op) {
+			continue
+		}
+		// word operators must not run into the operand
+		if isIdentStart(op[0]) && len(rest) > len(op) && rest[len(op)] != ' ' && rest[len(op)] != '[' && rest[len(op)] != '"' {
+			continue
+		}
+		condition.Op = op
+		rest = strings.TrimSpace(rest[len(op):])
+		break
+	}
+	if condition.Op == "=~" {
+		condition.Op = JSONPathRegex
+	}
+
+	switch condition.Op {
+	case "":
+		return nil, fmt.Errorf("missing operator after %s, expected ==, !=, <, <=, >, >=, in, exists or regex", condition.Path)
+	case JSONPathExists:
+		if rest != "" {
+			return nil, fmt.Errorf("exists takes no operand, found %q", rest)
+		}
+		return condition, nil
+	}
+
+	if rest == "" {
+		return nil, fmt.Errorf("missing operand after %s %s", condition.Path, condition.Op)
+	}
+	condition.Operand = parseJSONOperand(rest)
+
+	switch condition.Op {
+	case JSONPathIn:
+		if _, ok := condition.Operand.([]interface{}); !ok {
+			return nil, fmt.Errorf("in expects a JSON array such as [1, 2], found %q", rest)
+		}
+	case JSONPathRegex:
+		expr, ok := condition.Operand.(string)
+		if !ok {
+			return nil, fmt.Errorf("regex expects a string pattern, found %q", rest)
+		}
+		if condition.pattern, err = regexp.Compile(expr); err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", expr, err)
+		}
+	case JSONPathLess, JSONPathLessEqual, JSONPathGreater, JSONPathGreaterEqual:
+		switch condition.Operand.(type) {
+		case float64, string:
+		default:
+			return nil, fmt.Errorf("%s expects a number or string, found %q", condition.Op, rest)
+		}
+	}
+	return condition, nil
+}
+
+// parseJSONOperand reads an operand as JSON, falling back to the raw text.
+func parseJSONOperand(text string) interface{} {
+	var operand interface{}
+	if err := json.Unmarshal([]byte(text), &operand); err == nil {
+		return operand
+	}
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return text[1 : len(text)-1]
+	}
+	return text
+}
+
+// scanJSONPath returns the length of the path at the start of source. The path ends
+// at the first space or operator character outside of brackets.
+func scanJSONPath(source string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(source); i++ {
+		ch := source[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '[':
+			depth++
+		case ch == ']':
+			depth--
+		case depth == 0 && strings.IndexByte(" \t=!<>", ch) >= 0:
+			return i
+		}
+	}
+	return len(source)
+}
+
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	var steps []jsonPathStep
+	i := 1 // skip $
+	for i < len(path) {
+		switch path[i] {
+		case '.':
+			i++
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				i++
+			}
+			name := path[start:i]
+			if name == "" {
+				return nil, fmt.Errorf("empty field name in json path %q", path)
+			}
+			if name == "*" {
+				steps = append(steps, jsonPathStep{wildcard: true})
+			} else {
+				steps = append(steps, jsonPathStep{name: name})
+			}
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ in json path %q", path)
+			}
+			selector := strings.TrimSpace(path[i+1 : i+end])
+			i += end + 1
+			switch {
+			case selector == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				steps = append(steps, jsonPathStep{name: selector[1 : len(selector)-1]})
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid selector [%s] in json path %q", selector, path)
+				}
+				steps = append(steps, jsonPathStep{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in json path %q", path[i], path)
+		}
+	}
+	return steps, nil
+}
+
+// selectJSONPath returns every node the steps select in a document.
+func selectJSONPath(document interface{}, steps []jsonPathStep) []interface{} {
+	nodes := []interface{}{document}
+	for _, step := range steps {
+		var next []interface{}
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					for _, child := range n {
+						next = append(next, child)
+					}
+				} else if child, ok := n[step.name]; ok && !step.isIndex {
+					next = append(next, child)
+				}
+			case []interface{}:
+				if step.wildcard {
+					next = append(next, n...)
+				} else if step.isIndex && step.index < len(n) {
+					next = append(next, n[step.index])
+				}
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// Match reports whether any node selected by the path satisfies the condition.
+func (c *JSONPathCondition) Match(document interface{}) bool {
+	nodes := selectJSONPath(document, c.steps)
+	if c.Op == JSONPathExists {
+		return len(nodes) > 0
+	}
+	if c.Op == JSONPathNotEqual {
+		// != holds when the path is missing or no selected node equals the operand
+		for _, node := range nodes {
+			if reflect.DeepEqual(node, c.Operand) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, node := range nodes {
+		if c.matchNode(node) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *JSONPathCondition) matchNode(node interface{}) bool {
+	switch c.Op {
+	case JSONPathEqual:
+		return reflect.DeepEqual(node, c.Operand)
+	case JSONPathIn:
+		for _, candidate := range c.Operand.([]interface{}) {
+			if reflect.DeepEqual(node, candidate) {
+				return true
+			}
+		}
+		return false
+	case JSONPathRegex:
+		text, ok := node.(string)
+		return ok && c.pattern.MatchString(text)
+	}
+
+	var cmp int
+	switch operand := c.Operand.(type) {
+	case float64:
+		number, ok := node.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case number < operand:
+			cmp = -1
+		case number > operand:
+			cmp = 1
+		}
+	case string:
+		text, ok := node.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(text, operand)
+	default:
+		return false
+	}
+
+	switch c.Op {
+	case JSONPathLess:
+		return cmp < 0
+	case JSONPathLessEqual:
+		return cmp <= 0
+	case JSONPathGreater:
+		return cmp > 0
+	case JSONPathGreaterEqual:
+		return cmp >= 0
+	}
+	return false
+}
+
+// ParseJSONPathFilter parses conditions combined with "and" (default) or "or".
+func ParseJSONPathFilter(conditions []string, mode string) (*JSONPathFilter, error) {
+	filter := &JSONPathFilter{}
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "and":
+	case "or":
+		filter.Any = true
+	default:
+		return nil, errors.New("json path mode must be and or or")
+	}
+
+	for _, source := range conditions {
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+		condition, err := ParseJSONPathCondition(source)
+		if err != nil {
+			return nil, err
+		}
+		filter.Conditions = append(filter.Conditions, condition)
+	}
+	if len(filter.Conditions) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// Match evaluates the conditions against a parsed JSON document.
+func (f *JSONPathFilter) Match(document interface{}) bool {
+	for _, condition := range f.Conditions {
+		if condition.Match(document) == f.Any {
+			return f.Any
+		}
+	}
+	return !f.Any
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testJSONPathDocument = `{
+	"payload": {
+		"user": {"id": 12, "name": "ada", "admin": false},
+		"status": "PAID",
+		"items": [{"sku": "AB-1", "qty": 2}, {"sku": "CD-2", "qty": 5}],
+		"coupon": null
+	}
+}`
+
+func TestJSONPathConditionMatch(t *testing.T) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(testJSONPathDocument), &document); err != nil {
+		t.Fatalf("unmarshal document: %v", err)
+	}
+
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		{condition: `$.payload.user.id == 12`, want: true},
+		{condition: `$.payload.user.id == 1`, want: false},
+		{condition: `$.payload.user.id == 123`, want: false},
+		{condition: `$.payload.user.id == "12"`, want: false},
+		{condition: `$.payload.user.id != 13`, want: true},
+		{condition: `$.payload.missing != 13`, want: true},
+		{condition: `$.payload.user.id>10`, want: true},
+		{condition: `$.payload.user.id < 12`, want: false},
+		{condition: `$.payload.user.id <= 12`, want: true},
+		{condition: `$.payload.user.admin == false`, want: true},
+		{condition: `$.payload.user.admin == "false"`, want: false},
+		{condition: `$.payload.status == PAID`, want: true},
+		{condition: `$.payload.status in ["NEW", "PAID"]`, want: true},
+		{condition: `$.payload.user.id in [1, 2]`, want: false},
+		{condition: `$.payload.coupon exists`, want: true},
+		{condition: `$.payload.discount exists`, want: false},
+		{condition: `$.payload.coupon == null`, want: true},
+		{condition: `$.payload.user.name regex "^a.a$"`, want: true},
+		{condition: `$.payload.user.name =~ '^b'`, want: false},
+		{condition: `$.payload.items[1].sku == "CD-2"`, want: true},
+		{condition: `$.payload.items[*].qty > 4`, want: true},
+		{condition: `$.payload.items[*].sku regex "^ZZ"`, want: false},
+		{condition: `$['payload']["user"].name == "ada"`, want: true},
+		{condition: `$.payload.*.id == 12`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			condition, err := ParseJSONPathCondition(tt.condition)
+			if err != nil {
+				t.Fatalf("ParseJSONPathCondition() error = %v", err)
+			}
+			if got := condition.Match(document); got != tt.want {
+				t.Fatalf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathConditionErrors(t *testing.T) {
+	tests := []string{
+		`payload.user.id == 12`,
+		`$.payload.user.id`,
+		`$.payload.user.id ==`,
+		`$.payload.user.id in 12`,
+		`$.payload.user.name regex "("`,
+		`$.payload.user.id exists 1`,
+		`$.payload..id == 1`,
+		`$.payload[x] == 1`,
+		`$.payload.user.id > true`,
+	}
+
+	for _, source := range tests {
+		t.Run(source, func(t *testing.T) {
+			if _, err := ParseJSONPathCondition(source); err == nil {
+				t.Fatal("ParseJSONPathCondition() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestJSONPathFilterCombinesConditions(t *testing.T) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(testJSONPathDocument), &document); err != nil {
+		t.Fatalf("unmarshal document: %v", err)
+	}
+	conditions := []string{`$.payload.user.id == 12`, `$.payload.status == "NEW"`}
+
+	and, err := ParseJSONPathFilter(conditions, "")
+	if err != nil {
+		t.Fatalf("ParseJSONPathFilter() error = %v", err)
+	}
+	if and.Match(document) {
+		t.Fatal("AND filter matched, want one failing condition to reject the record")
+	}
+
+	or, err := ParseJSONPathFilter(conditions, "OR")
+	if err != nil {
+		t.Fatalf("ParseJSONPathFilter() error = %v", err)
+	}
+	if !or.Match(document) {
+		t.Fatal("OR filter did not match, want one passing condition to accept the record")
+	}
+
+	if _, err := ParseJSONPathFilter(conditions, "xor"); err == nil {
+		t.Fatal("ParseJSONPathFilter() error = nil, want invalid mode error")
+	}
+	if filter, err := ParseJSONPathFilter(nil, ""); err != nil || filter != nil {
+		t.Fatalf("ParseJSONPathFilter(nil) = %v, %v, want no filter", filter, err)
+	}
+}
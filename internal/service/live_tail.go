@@ -0,0 +1,488 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+// liveTailBuffer bounds the records decoded ahead of the reader.
+const liveTailBuffer = 256
+
+// LiveRecord is a record delivered by a live tail. It carries the follower that read it,
+// so records read before a seek or a partition change can be told apart with Current.
+type LiveRecord struct {
+	dto.MessageRecord
+	follower uint64
+}
+
+// LiveTail follows new records on one or more partitions of a topic through a single
+// consumer that lives as long as the tail. Records are decoded and filtered as they
+// arrive and delivered on Records in per-partition offset order. The filter, the
+// followed partitions and their positions can be changed while the tail runs.
+type LiveTail struct {
+	topic    string
+	client   sarama.Client
+	consumer sarama.Consumer
+	decoder  *messageDecoder
+	records  chan LiveRecord
+	ended    chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	opMu sync.Mutex // serializes changes to the followed partitions
+
+	mu         sync.Mutex
+	matcher    *recordMatcher
+	from, to   int64
+	paused     bool
+	active     int // followers that have not reached the end of the time range
+	partitions []*tailPartition
+	followers  uint64 // followers started so far, numbering the next one
+
+	endOnce   sync.Once
+	closeOnce sync.Once
+}
+
+// tailPartition is the follower of one partition and its position.
+type tailPartition struct {
+	id        uint64
+	partition int32
+	consumer  sarama.PartitionConsumer
+	next      int64
+	stop      int64 // exclusive upper bound from the time range, -1 when unbounded
+	finished  bool  // reached stop
+	quit      chan struct{}
+	exited    chan struct{}
+}
+
+// StartLiveTail starts following a topic. Without partitions it follows query.Partition,
+// or every partition when that is dto.AllPartitions. Each partition starts at its entry in
+// query.Offsets, at query.Offset, at the first record from query.From, or else at the end
+// of the log. The tail stops when ctx is done or Close is called.
+func (s *TopicService) StartLiveTail(ctx context.Context, clusterID uint, topicName string, partitions []int32, query dto.MessageQuery) (*LiveTail, error) {
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := s.topicDecoder(cluster, topicName, query.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := newRecordMatcher(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// the consumer skips aborted and open transactions with read_committed
+	client, err := s.kafkaManager.CreateClient(cluster, func(config *sarama.Config) {
+		config.Consumer.IsolationLevel = isolationLevel(query.ReadCommitted)
+	})
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create consumer: %w", err)
+	}
+
+	return newLiveTail(ctx, topicName, client, consumer, decoder, matcher, partitions, query)
+}
+
+// newLiveTail starts following partitions with a consumer it takes ownership of.
+func newLiveTail(ctx context.Context, topicName string, client sarama.Client, consumer sarama.Consumer, decoder *messageDecoder, matcher *recordMatcher, partitions []int32, query dto.MessageQuery) (*LiveTail, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	tail := &LiveTail{
+		topic:    topicName,
+		client:   client,
+		consumer: consumer,
+		decoder:  decoder,
+		records:  make(chan LiveRecord, liveTailBuffer),
+		ended:    make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		matcher:  matcher,
+		from:     query.From,
+		to:       query.To,
+	}
+
+	if len(partitions) == 0 && query.Partition != dto.AllPartitions {
+		partitions = []int32{query.Partition}
+	}
+	partitions, err := tail.checkPartitions(partitions)
+	if err != nil {
+		tail.Close()
+		return nil, err
+	}
+
+	if query.Offset < 0 && query.Offset != sarama.OffsetOldest {
+		query.Offset = sarama.OffsetNewest
+	}
+	tail.hold()
+	for _, partition := range partitions {
+		if err := tail.follow(partition, query); err != nil {
+			tail.Close()
+			return nil, err
+		}
+	}
+	tail.release()
+
+	go func() {
+		<-ctx.Done()
+		tail.Close()
+	}()
+	return tail, nil
+}
+
+// checkPartitions verifies that partitions exist, returning all partitions when none are given.
+func (t *LiveTail) checkPartitions(partitions []int32) ([]int32, error) {
+	known, err := t.client.Partitions(t.topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions: %w", err)
+	}
+	if len(partitions) == 0 {
+		return known, nil
+	}
+	for _, partition := range partitions {
+		if !containsPartition(known, partition) {
+			return nil, fmt.Errorf("partition %d does not exist in topic %s", partition, t.topic)
+		}
+	}
+	return partitions, nil
+}
+
+func containsPartition(partitions []int32, partition int32) bool {
+	for _, p := range partitions {
+		if p == partition {
+			return true
+		}
+	}
+	return false
+}
+
+// follow starts following a partition from the position the query resolves to.
+func (t *LiveTail) follow(partition int32, query dto.MessageQuery) error {
+	t.mu.Lock()
+	query.From, query.To = t.from, t.to
+	t.mu.Unlock()
+
+	rng, err := resolvePartitionRange(t.client, t.topic, partition, query, false, false)
+	if err != nil {
+		return err
+	}
+	partitionConsumer, err := t.consumer.ConsumePartition(t.topic, partition, rng.start)
+	if err != nil {
+		return fmt.Errorf("failed to consume partition %d: %w", partition, err)
+	}
+
+	tp := &tailPartition{
+		partition: partition,
+		consumer:  partitionConsumer,
+		next:      rng.start,
+		stop:      rng.stop,
+		quit:      make(chan struct{}),
+		exited:    make(chan struct{}),
+	}
+	t.mu.Lock()
+	if t.paused {
+		partitionConsumer.Pause()
+	}
+	t.followers++
+	tp.id = t.followers
+	t.partitions = append(t.partitions, tp)
+	t.active++
+	t.mu.Unlock()
+
+	go t.run(tp)
+	return nil
+}
+
+// run decodes and filters the records of a partition until the follower is stopped, or
+// until the end of the time range when the tail has an upper time bound.
+func (t *LiveTail) run(tp *tailPartition) {
+	defer close(tp.exited)
+
+	if tp.stop >= 0 && tp.next >= tp.stop {
+		t.finish(tp)
+		return
+	}
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-tp.quit:
+			return
+		case msg, ok := <-tp.consumer.Messages():
+			if !ok || tp.stop >= 0 && msg.Offset >= tp.stop {
+				t.finish(tp)
+				return
+			}
+
+			t.mu.Lock()
+			tp.next = msg.Offset + 1
+			matcher, from, to := t.matcher, t.from, t.to
+			t.mu.Unlock()
+
+			if !inTimeRange(msg.Timestamp.UnixMilli(), from, to) {
+				continue
+			}
+			record := t.decoder.record(msg)
+			if !matcher.match(msg, &record) {
+				continue
+			}
+
+			select {
+			case t.records <- LiveRecord{MessageRecord: record, follower: tp.id}:
+			case <-tp.quit:
+				return
+			case <-t.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// finish records that a follower reached the end of its range. The tail ends once
+// every follower has.
+func (t *LiveTail) finish(tp *tailPartition) {
+	t.mu.Lock()
+	tp.finished = true
+	t.mu.Unlock()
+	t.release()
+}
+
+// hold keeps the tail from ending while its followers are replaced.
+func (t *LiveTail) hold() {
+	t.mu.Lock()
+	t.active++
+	t.mu.Unlock()
+}
+
+func (t *LiveTail) release() {
+	t.mu.Lock()
+	t.active--
+	ended := t.active == 0
+	t.mu.Unlock()
+
+	if ended {
+		t.endOnce.Do(func() { close(t.ended) })
+	}
+}
+
+// unfollow stops the follower of a partition and forgets it.
+func (t *LiveTail) unfollow(tp *tailPartition) {
+	close(tp.quit)
+	tp.consumer.AsyncClose()
+	<-tp.exited
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !tp.finished {
+		t.active--
+	}
+	for i, candidate := range t.partitions {
+		if candidate == tp {
+			t.partitions = append(t.partitions[:i], t.partitions[i+1:]...)
+			break
+		}
+	}
+}
+
+// Records delivers matching records. Records read before a Seek or SetPartitions may
+// still be waiting on it; Current tells them apart.
+func (t *LiveTail) Records() <-chan LiveRecord {
+	return t.records
+}
+
+// Current reports whether a record was read by a follower the tail still runs, that is
+// neither from before a seek of its partition nor from a partition no longer followed.
+func (t *LiveTail) Current(record LiveRecord) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tp := range t.partitions {
+		if tp.id == record.follower {
+			return true
+		}
+	}
+	return false
+}
+
+// Done is closed when the tail stops, or when every partition reached the end of the time range.
+func (t *LiveTail) Done() <-chan struct{} {
+	return t.ended
+}
+
+// Positions reports the next offset the tail reads and the end offset of each partition.
+func (t *LiveTail) Positions() []dto.LivePartition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	positions := make([]dto.LivePartition, 0, len(t.partitions))
+	for _, tp := range t.partitions {
+		end := tp.consumer.HighWaterMarkOffset()
+		if end < tp.next {
+			// the high water mark is only known after the first fetch
+			end = tp.next
+		}
+		positions = append(positions, dto.LivePartition{Partition: tp.partition, Offset: tp.next, EndOffset: end})
+	}
+	return positions
+}
+
+// Paused reports whether fetching is paused.
+func (t *LiveTail) Paused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
+
+// Pause stops fetching on every partition, keeping the positions.
+func (t *LiveTail) Pause() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paused = true
+	for _, tp := range t.partitions {
+		tp.consumer.Pause()
+	}
+}
+
+// Resume continues fetching after Pause.
+func (t *LiveTail) Resume() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paused = false
+	for _, tp := range t.partitions {
+		tp.consumer.Resume()
+	}
+}
+
+// SetFilter replaces the key, value, JSON and expression filters of the tail. The time
+// range and positions are kept.
+func (t *LiveTail) SetFilter(query dto.MessageQuery) error {
+	matcher, err := newRecordMatcher(query)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.matcher = matcher
+	t.mu.Unlock()
+	return nil
+}
+
+// Seek moves the followed partitions to a new position: the entries of query.Offsets
+// for the partitions listed there, otherwise query.Offset or the first record from
+// query.From on every partition. Seeking by time replaces the lower time bound. Offsets
+// of partitions the tail does not follow are rejected.
+func (t *LiveTail) Seek(query dto.MessageQuery) error {
+	t.opMu.Lock()
+	defer t.opMu.Unlock()
+	if t.ctx.Err() != nil {
+		return errors.New("live tail is closed")
+	}
+	t.hold()
+	defer t.release()
+
+	if len(query.Offsets) == 0 && query.From == 0 && query.Offset == -1 {
+		query.Offset = sarama.OffsetNewest
+	}
+	t.mu.Lock()
+	if len(query.Offsets) == 0 {
+		t.from = query.From
+	}
+	selected := make([]*tailPartition, 0, len(t.partitions))
+	followed := make(map[int32]bool, len(t.partitions))
+	for _, tp := range t.partitions {
+		followed[tp.partition] = true
+		if _, ok := query.Offsets[tp.partition]; ok || len(query.Offsets) == 0 {
+			selected = append(selected, tp)
+		}
+	}
+	t.mu.Unlock()
+
+	var unfollowed []int32
+	for partition := range query.Offsets {
+		if !followed[partition] {
+			unfollowed = append(unfollowed, partition)
+		}
+	}
+	if len(unfollowed) > 0 {
+		sort.Slice(unfollowed, func(i, j int) bool { return unfollowed[i] < unfollowed[j] })
+		return fmt.Errorf("partitions %v are not followed by the tail", unfollowed)
+	}
+
+	for _, tp := range selected {
+		t.unfollow(tp)
+		if err := t.follow(tp.partition, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetPartitions switches the followed partitions. Partitions that stay keep their
+// position and added ones start at the end of the log.
+func (t *LiveTail) SetPartitions(partitions []int32) error {
+	t.opMu.Lock()
+	defer t.opMu.Unlock()
+	if t.ctx.Err() != nil {
+		return errors.New("live tail is closed")
+	}
+
+	partitions, err := t.checkPartitions(partitions)
+	if err != nil {
+		return err
+	}
+	t.hold()
+	defer t.release()
+
+	t.mu.Lock()
+	var removed []*tailPartition
+	followed := make(map[int32]bool, len(t.partitions))
+	for _, tp := range t.partitions {
+		followed[tp.partition] = true
+		if !containsPartition(partitions, tp.partition) {
+			removed = append(removed, tp)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, partition := range partitions {
+		if !followed[partition] {
+			if err := t.follow(partition, dto.MessageQuery{Offset: sarama.OffsetNewest}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, tp := range removed {
+		t.unfollow(tp)
+	}
+	return nil
+}
+
+// Close stops the tail and releases its consumer and client.
+func (t *LiveTail) Close() {
+	t.closeOnce.Do(func() {
+		t.cancel()
+		t.opMu.Lock()
+		defer t.opMu.Unlock()
+
+		t.mu.Lock()
+		partitions := append([]*tailPartition(nil), t.partitions...)
+		t.mu.Unlock()
+		for _, tp := range partitions {
+			tp.consumer.AsyncClose()
+			<-tp.exited
+		}
+
+		t.endOnce.Do(func() { close(t.ended) })
+		t.consumer.Close()
+		t.client.Close()
+	})
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+)
+
+// reconsumer hands out a fresh mock partition consumer on every call, so a partition
+// can be consumed again after a seek.
+type reconsumer struct {
+	sarama.Consumer
+	t       *testing.T
+	mu      sync.Mutex
+	started map[int32][]*mocks.PartitionConsumer
+	offsets map[int32][]int64
+}
+
+func (c *reconsumer) ConsumePartition(topic string, partition int32, offset int64) (sarama.PartitionConsumer, error) {
+	consumer := mocks.NewConsumer(c.t, nil)
+	expectation := consumer.ExpectConsumePartition(topic, partition, offset)
+	pc, err := consumer.ConsumePartition(topic, partition, offset)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.started[partition] = append(c.started[partition], expectation)
+	c.offsets[partition] = append(c.offsets[partition], offset)
+	c.mu.Unlock()
+	return pc, nil
+}
+
+func (c *reconsumer) Close() error {
+	return nil
+}
+
+func (c *reconsumer) latest(partition int32) *mocks.PartitionConsumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	started := c.started[partition]
+	return started[len(started)-1]
+}
+
+func newTestTailClient(t *testing.T) sarama.Client {
+	t.Helper()
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()).
+			SetLeader("orders", 1, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 0, sarama.OffsetOldest, 0).
+			SetOffset("orders", 0, sarama.OffsetNewest, 5).
+			SetOffset("orders", 1, sarama.OffsetOldest, 0).
+			SetOffset("orders", 1, sarama.OffsetNewest, 9),
+	})
+
+	config := sarama.NewConfig()
+	config.ApiVersionsRequest = false
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+func receiveRecord(t *testing.T, tail *LiveTail) LiveRecord {
+	t.Helper()
+	select {
+	case record := <-tail.Records():
+		return record
+	case <-time.After(5 * time.Second):
+		t.Fatal("no record received")
+		return LiveRecord{}
+	}
+}
+
+func TestLiveTailFollowsCommands(t *testing.T) {
+	consumer := &reconsumer{t: t, started: map[int32][]*mocks.PartitionConsumer{}, offsets: map[int32][]int64{}}
+	matcher, err := newRecordMatcher(dto.MessageQuery{ValueFilter: "paid"})
+	if err != nil {
+		t.Fatalf("newRecordMatcher() error = %v", err)
+	}
+	decoder := newMessageDecoder(&model.Cluster{}, nil, nil, dto.EncodingAuto)
+
+	tail, err := newLiveTail(context.Background(), "orders", newTestTailClient(t), consumer, decoder, matcher, []int32{0}, dto.MessageQuery{Offset: -1})
+	if err != nil {
+		t.Fatalf("newLiveTail() error = %v", err)
+	}
+	defer tail.Close()
+
+	// a new tail starts at the end of the log
+	consumer.latest(0).YieldMessage(&sarama.ConsumerMessage{Value: []byte("new")})
+	consumer.latest(0).YieldMessage(&sarama.ConsumerMessage{Value: []byte("paid")})
+	if record := receiveRecord(t, tail); record.Offset != 6 || record.Value != "paid" {
+		t.Fatalf("record = %d %q, want paid at offset 6", record.Offset, record.Value)
+	}
+
+	if err := tail.SetFilter(dto.MessageQuery{ValueFilter: "new"}); err != nil {
+		t.Fatalf("SetFilter() error = %v", err)
+	}
+	consumer.latest(0).YieldMessage(&sarama.ConsumerMessage{Value: []byte("paid")})
+	consumer.latest(0).YieldMessage(&sarama.ConsumerMessage{Value: []byte("new")})
+	beforeSeek := receiveRecord(t, tail)
+	if beforeSeek.Offset != 8 || beforeSeek.Value != "new" {
+		t.Fatalf("record = %d %q, want new at offset 8 after the filter change", beforeSeek.Offset, beforeSeek.Value)
+	}
+	if !tail.Current(beforeSeek) {
+		t.Fatal("Current() = false for a record of the running follower")
+	}
+
+	tail.Pause()
+	if !tail.Paused() || !consumer.latest(0).IsPaused() {
+		t.Fatal("tail is not paused")
+	}
+	tail.Resume()
+	if tail.Paused() || consumer.latest(0).IsPaused() {
+		t.Fatal("tail is still paused")
+	}
+
+	if err := tail.Seek(dto.MessageQuery{Offset: -1, Offsets: map[int32]int64{0: 2}}); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	if offsets := consumer.offsets[0]; len(offsets) != 2 || offsets[1] != 2 {
+		t.Fatalf("partition 0 consumed from %v, want a restart at offset 2", offsets)
+	}
+	if tail.Current(beforeSeek) {
+		t.Fatal("Current() = true for a record read before the seek")
+	}
+	consumer.latest(0).YieldMessage(&sarama.ConsumerMessage{Value: []byte("new")})
+	afterSeek := receiveRecord(t, tail)
+	if afterSeek.Offset != 2 || !tail.Current(afterSeek) {
+		t.Fatalf("record offset = %d, want a current record at 2 after the seek", afterSeek.Offset)
+	}
+
+	if err := tail.SetPartitions([]int32{1}); err != nil {
+		t.Fatalf("SetPartitions() error = %v", err)
+	}
+	positions := tail.Positions()
+	if len(positions) != 1 || positions[0].Partition != 1 || positions[0].Offset != 9 {
+		t.Fatalf("Positions() = %v, want partition 1 at its end offset 9", positions)
+	}
+	if tail.Current(afterSeek) {
+		t.Fatal("Current() = true for a record of a partition no longer followed")
+	}
+	if err := tail.Seek(dto.MessageQuery{Offset: -1, Offsets: map[int32]int64{0: 2}}); err == nil {
+		t.Fatal("Seek() error = nil, want error for a partition not followed")
+	}
+	if err := tail.SetPartitions([]int32{7}); err == nil {
+		t.Fatal("SetPartitions() error = nil, want error for an unknown partition")
+	}
+
+	tail.Close()
+	select {
+	case <-tail.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done() not closed after Close")
+	}
+}
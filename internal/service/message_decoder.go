@@ -0,0 +1,142 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"github.com/bingfengfeifei/kafka-map-go/internal/util"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// messageDecoder renders consumed records. Keys and values bound to a protobuf message
+// type are decoded with it, Confluent framed Avro payloads are decoded through the
+// cluster's schema registry when one is configured. Everything else is rendered with
+// the requested byte encoding.
+type messageDecoder struct {
+	cluster  *model.Cluster
+	registry *util.SchemaRegistryClient
+	proto    *topicProto
+	encoding string
+}
+
+func newMessageDecoder(cluster *model.Cluster, registry *util.SchemaRegistryClient, proto *topicProto, encoding string) *messageDecoder {
+	if encoding == "" {
+		encoding = dto.EncodingAuto
+	}
+	return &messageDecoder{cluster: cluster, registry: registry, proto: proto, encoding: encoding}
+}
+
+// record converts a consumed message into its display form. Payloads that fail to
+// decode are kept in their raw rendering and the failure is reported on the record.
+func (d *messageDecoder) record(msg *sarama.ConsumerMessage) dto.MessageRecord {
+	headers := make([]dto.MessageHeader, 0, len(msg.Headers))
+	for _, header := range msg.Headers {
+		if header == nil {
+			continue
+		}
+		value, encoding := encodeBytes(header.Value, d.encoding)
+		headers = append(headers, dto.MessageHeader{Key: string(header.Key), Value: value, Encoding: encoding})
+	}
+
+	record := dto.MessageRecord{
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp.UnixMilli(),
+		Headers:   headers,
+	}
+
+	var keyType, valueType protoreflect.MessageDescriptor
+	if d.proto != nil {
+		keyType, valueType = d.proto.key, d.proto.value
+	}
+	key, keyErr := d.decode(msg.Key, keyType, true)
+	value, valueErr := d.decode(msg.Value, valueType, false)
+	record.Key, record.KeyEncoding, record.KeySchemaID = key.text, key.encoding, key.schemaID
+	record.Value, record.ValueEncoding, record.ValueSchemaID = value.text, value.encoding, value.schemaID
+	if keyErr != nil {
+		record.DecodeError = "key: " + keyErr.Error()
+	} else if valueErr != nil {
+		record.DecodeError = "value: " + valueErr.Error()
+	}
+	return record
+}
+
+// decodedPayload is the display form of a key or value.
+type decodedPayload struct {
+	text     string
+	encoding string
+	schemaID *int32 // registry schema the payload was decoded with
+}
+
+// decode renders a key or value, decoding it with its protobuf type or registry schema when
+// possible. Only a payload the registry has a schema for counts as Confluent framed: when
+// the registry does not know the ID, and for keys whatever the failure, the payload is
+// rendered raw without an error, as plain binary keys often start with a zero byte too.
+func (d *messageDecoder) decode(data []byte, protoType protoreflect.MessageDescriptor, key bool) (decodedPayload, error) {
+	if protoType != nil && data != nil {
+		text, err := d.proto.files.DecodeProtobuf(protoType, data)
+		if err != nil {
+			return d.raw(data), err
+		}
+		return decodedPayload{text: text, encoding: dto.EncodingProtobuf}, nil
+	}
+
+	if d.registry == nil || d.cluster.SchemaRegistryURL == "" {
+		return d.raw(data), nil
+	}
+
+	id, payload, ok := util.ParseSchemaID(data)
+	if !ok {
+		return d.raw(data), nil
+	}
+
+	text, err := d.registry.DecodeAvro(d.cluster, id, payload)
+	if err != nil {
+		if key || errors.Is(err, util.ErrSchemaNotFound) {
+			return d.raw(data), nil
+		}
+		return d.raw(data), err
+	}
+	return decodedPayload{text: text, encoding: dto.EncodingAvro, schemaID: &id}, nil
+}
+
+func (d *messageDecoder) raw(data []byte) decodedPayload {
+	text, encoding := encodeBytes(data, d.encoding)
+	return decodedPayload{text: text, encoding: encoding}
+}
+
+// encodeBytes renders raw bytes with the requested encoding. Auto keeps printable
+// UTF-8 as text and falls back to hex for anything else.
+func encodeBytes(data []byte, encoding string) (string, string) {
+	switch encoding {
+	case dto.EncodingHex:
+		return hex.EncodeToString(data), dto.EncodingHex
+	case dto.EncodingBase64:
+		return base64.StdEncoding.EncodeToString(data), dto.EncodingBase64
+	case dto.EncodingUTF8:
+		return string(data), dto.EncodingUTF8
+	default:
+		if isPrintableUTF8(data) {
+			return string(data), dto.EncodingUTF8
+		}
+		return hex.EncodeToString(data), dto.EncodingHex
+	}
+}
+
+func isPrintableUTF8(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"github.com/bingfengfeifei/kafka-map-go/internal/util"
+	"github.com/linkedin/goavro/v2"
+)
+
+const testOrderSchema = `{"type":"record","name":"Order","fields":[{"name":"id","type":"long"},{"name":"note","type":["null","string"],"default":null}]}`
+
+func TestMessageDecoderDecodesRegistryAvro(t *testing.T) {
+	requests := 0
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if user, pass, ok := r.BasicAuth(); !ok || user != "sr-user" || pass != "sr-pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/schemas/ids/42" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"schema": testOrderSchema})
+	}))
+	defer registry.Close()
+
+	codec, err := goavro.NewCodec(testOrderSchema)
+	if err != nil {
+		t.Fatalf("goavro.NewCodec() error = %v", err)
+	}
+	payload, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"id":   int64(7),
+		"note": goavro.Union("string", "rush"),
+	})
+	if err != nil {
+		t.Fatalf("BinaryFromNative() error = %v", err)
+	}
+	framed := append([]byte{0, 0, 0, 0, 0}, payload...)
+	binary.BigEndian.PutUint32(framed[1:5], 42)
+
+	cluster := &model.Cluster{
+		SchemaRegistryURL:      registry.URL,
+		SchemaRegistryUsername: "sr-user",
+		SchemaRegistryPassword: "sr-pass",
+	}
+	decoder := newMessageDecoder(cluster, util.NewSchemaRegistryClient(), nil, dto.EncodingAuto)
+
+	for i := 0; i < 2; i++ {
+		record := decoder.record(&sarama.ConsumerMessage{
+			Key:       []byte("order-7"),
+			Value:     framed,
+			Timestamp: time.UnixMilli(1000),
+		})
+		if record.DecodeError != "" {
+			t.Fatalf("DecodeError = %q", record.DecodeError)
+		}
+		if record.Key != "order-7" || record.KeySchemaID != nil {
+			t.Fatalf("Key = %q (schema %v), want plain key", record.Key, record.KeySchemaID)
+		}
+		if record.ValueSchemaID == nil || *record.ValueSchemaID != 42 {
+			t.Fatalf("ValueSchemaID = %v, want 42", record.ValueSchemaID)
+		}
+
+		var value map[string]interface{}
+		if err := json.Unmarshal([]byte(record.Value), &value); err != nil {
+			t.Fatalf("Value %q is not JSON: %v", record.Value, err)
+		}
+		if value["id"] != float64(7) || value["note"] != "rush" {
+			t.Fatalf("Value = %v, want id 7 and note rush", value)
+		}
+	}
+
+	if requests != 1 {
+		t.Fatalf("registry requests = %d, want schema to be cached after the first fetch", requests)
+	}
+}
+
+func TestMessageDecoderKeepsRawPayloadOnRegistryFailure(t *testing.T) {
+	framed := []byte{0, 0, 0, 0, 9, 'x'}
+	tests := []struct {
+		name      string
+		status    int
+		message   *sarama.ConsumerMessage
+		wantError bool
+	}{
+		{name: "registry error on value", status: http.StatusInternalServerError, message: &sarama.ConsumerMessage{Value: framed}, wantError: true},
+		{name: "unknown schema on value", status: http.StatusNotFound, message: &sarama.ConsumerMessage{Value: framed}},
+		{name: "registry error on key", status: http.StatusInternalServerError, message: &sarama.ConsumerMessage{Key: framed}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(tt.status)
+			}))
+			defer registry.Close()
+
+			decoder := newMessageDecoder(&model.Cluster{SchemaRegistryURL: registry.URL}, util.NewSchemaRegistryClient(), nil, dto.EncodingAuto)
+			for i := 0; i < 3; i++ {
+				record := decoder.record(tt.message)
+				if (record.DecodeError != "") != tt.wantError {
+					t.Fatalf("DecodeError = %q, wantError %v", record.DecodeError, tt.wantError)
+				}
+				raw, encoding, schemaID := record.Value, record.ValueEncoding, record.ValueSchemaID
+				if tt.message.Key != nil {
+					raw, encoding, schemaID = record.Key, record.KeyEncoding, record.KeySchemaID
+				}
+				if raw != "000000000978" || encoding != dto.EncodingHex || schemaID != nil {
+					t.Fatalf("payload = %q (%s), want raw payload as hex", raw, encoding)
+				}
+			}
+			if requests != 1 {
+				t.Fatalf("registry requests = %d, want the failure to be cached after the first fetch", requests)
+			}
+		})
+	}
+}
+
+func TestParseSchemaID(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		wantID int32
+		wantOK bool
+	}{
+		{name: "framed", data: []byte{0, 0, 0, 1, 2, 'x'}, wantID: 258, wantOK: true},
+		{name: "too short", data: []byte{0, 0, 0, 1}},
+		{name: "no magic byte", data: []byte{1, 0, 0, 0, 1}},
+		{name: "zero id", data: []byte{0, 0, 0, 0, 0, 'x'}},
+		{name: "negative id", data: []byte{0, 0xff, 0, 0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, _, ok := util.ParseSchemaID(tt.data)
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Fatalf("ParseSchemaID() = %d, %v, want %d, %v", id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEncodeBytes(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         []byte
+		encoding     string
+		want         string
+		wantEncoding string
+	}{
+		{name: "auto text", data: []byte("hello\tworld\n"), encoding: dto.EncodingAuto, want: "hello\tworld\n", wantEncoding: dto.EncodingUTF8},
+		{name: "auto invalid utf8", data: []byte{0xff, 0x00, 0x10}, encoding: dto.EncodingAuto, want: "ff0010", wantEncoding: dto.EncodingHex},
+		{name: "auto control bytes", data: []byte("a\x00b"), encoding: dto.EncodingAuto, want: "610062", wantEncoding: dto.EncodingHex},
+		{name: "hex", data: []byte("hi"), encoding: dto.EncodingHex, want: "6869", wantEncoding: dto.EncodingHex},
+		{name: "base64", data: []byte{0xff, 0xfe}, encoding: dto.EncodingBase64, want: "//4=", wantEncoding: dto.EncodingBase64},
+		{name: "utf8", data: []byte("héllo"), encoding: dto.EncodingUTF8, want: "héllo", wantEncoding: dto.EncodingUTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoding := encodeBytes(tt.data, tt.encoding)
+			if got != tt.want || encoding != tt.wantEncoding {
+				t.Fatalf("encodeBytes() = %q, %q, want %q, %q", got, encoding, tt.want, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestMessageDecoderKeepsHeaderOrderAndDuplicates(t *testing.T) {
+	decoder := newMessageDecoder(&model.Cluster{}, nil, nil, dto.EncodingAuto)
+	record := decoder.record(&sarama.ConsumerMessage{
+		Key:   []byte{0xca, 0xfe},
+		Value: []byte("payload"),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("hop"), Value: []byte("a")},
+			{Key: []byte("trace"), Value: []byte{0x01, 0x02}},
+			{Key: []byte("hop"), Value: []byte("b")},
+		},
+	})
+
+	want := []dto.MessageHeader{
+		{Key: "hop", Value: "a", Encoding: dto.EncodingUTF8},
+		{Key: "trace", Value: "0102", Encoding: dto.EncodingHex},
+		{Key: "hop", Value: "b", Encoding: dto.EncodingUTF8},
+	}
+	if len(record.Headers) != len(want) {
+		t.Fatalf("Headers = %v, want %v", record.Headers, want)
+	}
+	for i := range want {
+		if record.Headers[i] != want[i] {
+			t.Fatalf("Headers[%d] = %v, want %v", i, record.Headers[i], want[i])
+		}
+	}
+	if record.Key != "cafe" || record.KeyEncoding != dto.EncodingHex {
+		t.Fatalf("Key = %q (%s), want hex cafe", record.Key, record.KeyEncoding)
+	}
+	if record.Value != "payload" || record.ValueEncoding != dto.EncodingUTF8 {
+		t.Fatalf("Value = %q (%s), want utf8 payload", record.Value, record.ValueEncoding)
+	}
+}
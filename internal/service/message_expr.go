@@ -0,0 +1,696 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+// FilterExpression is a compiled message filter such as
+//
+//	value.order.amount > 100 && headers["source"] == "billing" && key matches "^user-"
+//
+// The record fields key, value, headers, partition, offset and timestamp can be
+// referenced. key and value evaluate to their text, while member access such as
+// value.order.amount or value["order-id"] navigates into the parsed JSON. Supported
+// operators are ==, !=, <, <=, >, >=, matches (regular expression), contains, in
+// (list membership), &&/and, ||/or and !/not, with parentheses for grouping.
+type FilterExpression struct {
+	source string
+	root   exprNode
+}
+
+// FilterSyntaxError reports an invalid filter expression.
+type FilterSyntaxError struct {
+	Pos int // byte offset into the expression
+	Msg string
+}
+
+func (e *FilterSyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// CompileFilterExpression parses a filter expression.
+func CompileFilterExpression(source string) (*FilterExpression, error) {
+	tokens, err := lexFilterExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, &FilterSyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %s", tok)}
+	}
+	return &FilterExpression{source: source, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (f *FilterExpression) String() string {
+	return f.source
+}
+
+// Match reports whether a record satisfies the expression.
+func (f *FilterExpression) Match(record *dto.MessageRecord) bool {
+	return truthy(f.root.eval(&exprContext{record: record}))
+}
+
+// exprContext lazily parses the JSON forms of a record while it is evaluated.
+type exprContext struct {
+	record    *dto.MessageRecord
+	key       interface{}
+	keyOK     bool
+	keyParsed bool
+	val       interface{}
+	valOK     bool
+	valParsed bool
+	headers   map[string]interface{}
+}
+
+func (c *exprContext) keyJSON() (interface{}, bool) {
+	if !c.keyParsed {
+		c.keyParsed = true
+		c.keyOK = json.Unmarshal([]byte(c.record.Key), &c.key) == nil
+	}
+	return c.key, c.keyOK
+}
+
+func (c *exprContext) valueJSON() (interface{}, bool) {
+	if !c.valParsed {
+		c.valParsed = true
+		c.valOK = json.Unmarshal([]byte(c.record.Value), &c.val) == nil
+	}
+	return c.val, c.valOK
+}
+
+// headerMap exposes the first value of every header key.
+func (c *exprContext) headerMap() map[string]interface{} {
+	if c.headers == nil {
+		c.headers = make(map[string]interface{}, len(c.record.Headers))
+		for _, header := range c.record.Headers {
+			if _, exists := c.headers[header.Key]; !exists {
+				c.headers[header.Key] = header.Value
+			}
+		}
+	}
+	return c.headers
+}
+
+type exprNode interface {
+	eval(ctx *exprContext) interface{}
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(*exprContext) interface{} {
+	return n.value
+}
+
+type listNode struct {
+	items []exprNode
+}
+
+func (n listNode) eval(ctx *exprContext) interface{} {
+	values := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		values[i] = item.eval(ctx)
+	}
+	return values
+}
+
+// fieldNode is a record field, optionally followed by a member access path.
+type fieldNode struct {
+	field string
+	path  []exprNode
+}
+
+func (n fieldNode) eval(ctx *exprContext) interface{} {
+	var current interface{}
+	switch n.field {
+	case "partition":
+		current = float64(ctx.record.Partition)
+	case "offset":
+		current = float64(ctx.record.Offset)
+	case "timestamp":
+		current = float64(ctx.record.Timestamp)
+	case "headers":
+		current = ctx.headerMap()
+	case "key":
+		if len(n.path) == 0 {
+			return ctx.record.Key
+		}
+		parsed, ok := ctx.keyJSON()
+		if !ok {
+			return nil
+		}
+		current = parsed
+	case "value":
+		if len(n.path) == 0 {
+			return ctx.record.Value
+		}
+		parsed, ok := ctx.valueJSON()
+		if !ok {
+			return nil
+		}
+		current = parsed
+	}
+
+	for _, step := range n.path {
+		current = member(current, step.eval(ctx))
+		if current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+// member returns an object field or array element, or nil when it does not exist.
+func member(container, selector interface{}) interface{} {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if name, ok := selector.(string); ok {
+			return c[name]
+		}
+	case []interface{}:
+		if index, ok := selector.(float64); ok && index == float64(int(index)) && int(index) >= 0 && int(index) < len(c) {
+			return c[int(index)]
+		}
+	}
+	return nil
+}
+
+type notNode struct {
+	operand exprNode
+}
+
+func (n notNode) eval(ctx *exprContext) interface{} {
+	return !truthy(n.operand.eval(ctx))
+}
+
+type logicalNode struct {
+	and         bool
+	left, right exprNode
+}
+
+func (n logicalNode) eval(ctx *exprContext) interface{} {
+	left := truthy(n.left.eval(ctx))
+	if n.and {
+		return left && truthy(n.right.eval(ctx))
+	}
+	return left || truthy(n.right.eval(ctx))
+}
+
+type compareNode struct {
+	op          string
+	left, right exprNode
+	pattern     *regexp.Regexp // precompiled for matches with a literal pattern
+}
+
+func (n compareNode) eval(ctx *exprContext) interface{} {
+	left := n.left.eval(ctx)
+	right := n.right.eval(ctx)
+
+	switch n.op {
+	case "==":
+		return valuesEqual(left, right)
+	case "!=":
+		return !valuesEqual(left, right)
+	case "<", "<=", ">", ">=":
+		cmp, ok := compareValues(left, right)
+		if !ok {
+			return false
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		default:
+			return cmp >= 0
+		}
+	case "matches":
+		text, ok := scalarText(left)
+		if !ok {
+			return false
+		}
+		pattern := n.pattern
+		if pattern == nil {
+			source, ok := right.(string)
+			if !ok {
+				return false
+			}
+			var err error
+			if pattern, err = regexp.Compile(source); err != nil {
+				return false
+			}
+		}
+		return pattern.MatchString(text)
+	case "contains":
+		return containsValue(left, right)
+	case "in":
+		return containsValue(right, left)
+	}
+	return false
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	default:
+		return true
+	}
+}
+
+// numeric returns a number, accepting strings that hold one so that text keys
+// and header values can be compared with numeric literals.
+func numeric(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return parsed, err == nil
+	}
+	return 0, false
+}
+
+func valuesEqual(left, right interface{}) bool {
+	_, leftNumber := left.(float64)
+	_, rightNumber := right.(float64)
+	if leftNumber || rightNumber {
+		l, lok := numeric(left)
+		r, rok := numeric(right)
+		return lok && rok && l == r
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func compareValues(left, right interface{}) (int, bool) {
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), true
+		}
+	}
+
+	l, lok := numeric(left)
+	r, rok := numeric(right)
+	if !lok || !rok {
+		return 0, false
+	}
+	switch {
+	case l < r:
+		return -1, true
+	case l > r:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+func scalarText(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// containsValue checks substrings of strings, elements of arrays and keys of objects.
+func containsValue(container, item interface{}) bool {
+	switch c := container.(type) {
+	case string:
+		text, ok := scalarText(item)
+		return ok && strings.Contains(c, text)
+	case []interface{}:
+		for _, element := range c {
+			if valuesEqual(element, item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		if name, ok := item.(string); ok {
+			_, exists := c[name]
+			return exists
+		}
+	}
+	return false
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type exprToken struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+func (t exprToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.value.(string))
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ".", ",", "-"}
+
+func lexFilterExpression(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	i := 0
+	for i < len(source) {
+		ch := source[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '"' || ch == '\'':
+			text, end, err := lexString(source, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, text: source[i:end], value: text, pos: i})
+			i = end
+		case ch >= '0' && ch <= '9':
+			end := i
+			for end < len(source) && (isNumberChar(source[end]) || ((source[end] == '+' || source[end] == '-') && (source[end-1] == 'e' || source[end-1] == 'E'))) {
+				end++
+			}
+			number, err := strconv.ParseFloat(source[i:end], 64)
+			if err != nil {
+				return nil, &FilterSyntaxError{Pos: i, Msg: fmt.Sprintf("invalid number %q", source[i:end])}
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: source[i:end], value: number, pos: i})
+			i = end
+		case isIdentStart(ch):
+			end := i
+			for end < len(source) && (isIdentStart(source[end]) || (source[end] >= '0' && source[end] <= '9')) {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: source[i:end], pos: i})
+			i = end
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, exprToken{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &FilterSyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", ch)}
+			}
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEOF, pos: len(source)}), nil
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isNumberChar(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || ch == '.' || ch == 'e' || ch == 'E'
+}
+
+// lexString reads a quoted string starting at source[start] and returns its value
+// together with the offset right after the closing quote.
+func lexString(source string, start int) (string, int, error) {
+	quote := source[start]
+	var b strings.Builder
+	for i := start + 1; i < len(source); i++ {
+		ch := source[i]
+		switch {
+		case ch == quote:
+			return b.String(), i + 1, nil
+		case ch == '\\':
+			if i+1 >= len(source) {
+				break
+			}
+			i++
+			switch source[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				// keeps \\, \", \' and regular expression escapes such as \d
+				if source[i] != quote && source[i] != '\\' {
+					b.WriteByte('\\')
+				}
+				b.WriteByte(source[i])
+			}
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return "", 0, &FilterSyntaxError{Pos: start, Msg: "unterminated string"}
+}
+
+// Parser
+
+var filterFields = map[string]bool{
+	"key":       true,
+	"value":     true,
+	"headers":   true,
+	"partition": true,
+	"offset":    true,
+	"timestamp": true,
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the given operators or keywords.
+func (p *exprParser) accept(texts ...string) (exprToken, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator && tok.kind != tokenIdent {
+		return tok, false
+	}
+	for _, text := range texts {
+		if tok.text == text {
+			return p.next(), true
+		}
+	}
+	return tok, false
+}
+
+func (p *exprParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		tok := p.peek()
+		return &FilterSyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("expected %q but found %s", text, tok)}
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{and: true, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "matches", "contains", "in")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	node := compareNode{op: op.text, left: left, right: right}
+	if op.text == "matches" {
+		if literal, isLiteral := right.(literalNode); isLiteral {
+			source, isString := literal.value.(string)
+			if !isString {
+				return nil, &FilterSyntaxError{Pos: op.pos, Msg: "matches expects a string pattern"}
+			}
+			if node.pattern, err = regexp.Compile(source); err != nil {
+				return nil, &FilterSyntaxError{Pos: op.pos, Msg: fmt.Sprintf("invalid regular expression: %v", err)}
+			}
+		}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseOperand() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber, tokenString:
+		return literalNode{value: tok.value}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		}
+		if !filterFields[tok.text] {
+			return nil, &FilterSyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unknown field %q, expected key, value, headers, partition, offset or timestamp", tok.text)}
+		}
+		return p.parsePath(fieldNode{field: tok.text})
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			return p.parseList()
+		case "-":
+			number := p.next()
+			if number.kind != tokenNumber {
+				return nil, &FilterSyntaxError{Pos: number.pos, Msg: fmt.Sprintf("expected a number after \"-\" but found %s", number)}
+			}
+			return literalNode{value: -number.value.(float64)}, nil
+		}
+	}
+	return nil, &FilterSyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("expected a value but found %s", tok)}
+}
+
+func (p *exprParser) parsePath(field fieldNode) (exprNode, error) {
+	for {
+		if _, ok := p.accept("."); ok {
+			name := p.next()
+			if name.kind != tokenIdent {
+				return nil, &FilterSyntaxError{Pos: name.pos, Msg: fmt.Sprintf("expected a field name but found %s", name)}
+			}
+			field.path = append(field.path, literalNode{value: name.text})
+			continue
+		}
+		if _, ok := p.accept("["); ok {
+			selector, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			field.path = append(field.path, selector)
+			continue
+		}
+		return field, nil
+	}
+}
+
+func (p *exprParser) parseList() (exprNode, error) {
+	var list listNode
+	if _, ok := p.accept("]"); ok {
+		return list, nil
+	}
+	for {
+		item, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		list.items = append(list.items, item)
+		if _, ok := p.accept(","); ok {
+			continue
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+}
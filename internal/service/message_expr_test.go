@@ -0,0 +1,92 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+func TestFilterExpressionMatch(t *testing.T) {
+	record := &dto.MessageRecord{
+		Partition: 3,
+		Offset:    1200,
+		Timestamp: 1700000000000,
+		Key:       "user-42",
+		Value:     `{"order":{"id":"A-1","amount":150.5,"tags":["rush","gift"],"paid":true,"note":null}}`,
+		Headers: []dto.MessageHeader{
+			{Key: "source", Value: "billing"},
+			{Key: "retry", Value: "2"},
+			{Key: "source", Value: "ignored"},
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: `value.order.amount > 100 && headers["source"] == "billing" && key matches "^user-"`, want: true},
+		{expr: `value.order.amount > 200`, want: false},
+		{expr: `value.order.amount >= 150.5 and value.order.amount <= 150.5`, want: true},
+		{expr: `value.order.id == "A-1"`, want: true},
+		{expr: `value["order"]["id"] != "A-2"`, want: true},
+		{expr: `value.order.tags[0] == "rush"`, want: true},
+		{expr: `value.order.tags contains "gift"`, want: true},
+		{expr: `value.order.tags[5] == null`, want: true},
+		{expr: `value.order.paid == true`, want: true},
+		{expr: `value.order.note == null`, want: true},
+		{expr: `value.order.missing.deeper == null`, want: true},
+		{expr: `value contains "A-1"`, want: true},
+		{expr: `key in ["user-1", "user-42"]`, want: true},
+		{expr: `partition in [0, 1, 2]`, want: false},
+		{expr: `offset >= 1000 && timestamp < 1800000000000`, want: true},
+		{expr: `headers["retry"] > 1`, want: true},
+		{expr: `headers["missing"]`, want: false},
+		{expr: `!headers["missing"] && headers["source"]`, want: true},
+		{expr: `not (partition == 3 or offset == 0)`, want: false},
+		{expr: `key matches 'user-\d+$'`, want: true},
+		{expr: `key.id == 1`, want: false},
+		{expr: `value.order.amount > -1`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := CompileFilterExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("CompileFilterExpression() error = %v", err)
+			}
+			if got := expr.Match(record); got != tt.want {
+				t.Fatalf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileFilterExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantPos int
+		wantMsg string
+	}{
+		{expr: `value.amount >`, wantPos: 15, wantMsg: "expected a value"},
+		{expr: `amount > 100`, wantPos: 1, wantMsg: `unknown field "amount"`},
+		{expr: `key matches "("`, wantPos: 5, wantMsg: "invalid regular expression"},
+		{expr: `key == "open`, wantPos: 8, wantMsg: "unterminated string"},
+		{expr: `(key == "a"`, wantPos: 12, wantMsg: `expected ")"`},
+		{expr: `key == "a" key`, wantPos: 12, wantMsg: `unexpected "key"`},
+		{expr: `key # 1`, wantPos: 5, wantMsg: "unexpected character"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := CompileFilterExpression(tt.expr)
+			syntaxErr, ok := err.(*FilterSyntaxError)
+			if !ok {
+				t.Fatalf("CompileFilterExpression() error = %v, want *FilterSyntaxError", err)
+			}
+			if syntaxErr.Pos+1 != tt.wantPos || !strings.Contains(syntaxErr.Msg, tt.wantMsg) {
+				t.Fatalf("error = %v, want %q at position %d", err, tt.wantMsg, tt.wantPos)
+			}
+		})
+	}
+}
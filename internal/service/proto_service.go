@@ -0,0 +1,227 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"github.com/bingfengfeifei/kafka-map-go/internal/repository"
+	"github.com/bingfengfeifei/kafka-map-go/internal/util"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gorm.io/gorm"
+)
+
+// ErrDescriptorSetNotFound is returned for descriptor sets that do not exist in a cluster.
+var ErrDescriptorSetNotFound = errors.New("descriptor set not found")
+
+// ProtoService manages uploaded protobuf descriptor sets and their topic bindings.
+type ProtoService struct {
+	clusterRepo *repository.ClusterRepository
+	protoRepo   *repository.ProtoRepository
+	compiled    map[uint]*util.ProtoFiles // descriptor sets are immutable, keyed by ID
+	mu          sync.RWMutex
+}
+
+func NewProtoService(clusterRepo *repository.ClusterRepository, protoRepo *repository.ProtoRepository) *ProtoService {
+	return &ProtoService{
+		clusterRepo: clusterRepo,
+		protoRepo:   protoRepo,
+		compiled:    make(map[uint]*util.ProtoFiles),
+	}
+}
+
+// UploadDescriptorSet validates and stores a serialized FileDescriptorSet for a cluster.
+func (s *ProtoService) UploadDescriptorSet(clusterID uint, name string, data []byte) (*dto.ProtoDescriptorSetInfo, error) {
+	if _, err := s.clusterRepo.FindByID(clusterID); err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	files, err := util.ParseDescriptorSet(data)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &model.ProtoDescriptorSet{ClusterID: clusterID, Name: name, Data: data}
+	if err := s.protoRepo.CreateDescriptorSet(set); err != nil {
+		return nil, fmt.Errorf("failed to save descriptor set: %w", err)
+	}
+
+	s.mu.Lock()
+	s.compiled[set.ID] = files
+	s.mu.Unlock()
+
+	return descriptorSetInfo(set, files), nil
+}
+
+// GetDescriptorSets lists the descriptor sets uploaded for a cluster.
+func (s *ProtoService) GetDescriptorSets(clusterID uint) ([]dto.ProtoDescriptorSetInfo, error) {
+	sets, err := s.protoRepo.FindDescriptorSetsByCluster(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.ProtoDescriptorSetInfo, 0, len(sets))
+	for i := range sets {
+		files, err := s.compile(&sets[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *descriptorSetInfo(&sets[i], files))
+	}
+	return result, nil
+}
+
+// DeleteDescriptorSet removes a descriptor set that is no longer bound to any topic.
+func (s *ProtoService) DeleteDescriptorSet(clusterID, id uint) error {
+	if _, err := s.protoRepo.FindDescriptorSet(clusterID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDescriptorSetNotFound
+		}
+		return err
+	}
+	count, err := s.protoRepo.CountBindingsByDescriptorSet(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("descriptor set is still bound to %d topic(s)", count)
+	}
+
+	if err := s.protoRepo.DeleteDescriptorSet(clusterID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDescriptorSetNotFound
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.compiled, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// GetTopicBinding returns the protobuf binding of a topic, or nil when it has none.
+func (s *ProtoService) GetTopicBinding(clusterID uint, topic string) (*model.TopicProtoBinding, error) {
+	binding, err := s.protoRepo.FindBinding(clusterID, topic)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return binding, err
+}
+
+// BindTopic decodes the keys and/or values of a topic with message types from a descriptor set.
+func (s *ProtoService) BindTopic(clusterID uint, topic string, req *dto.TopicProtoBindingRequest) (*model.TopicProtoBinding, error) {
+	keyType := strings.TrimSpace(req.KeyMessageType)
+	valueType := strings.TrimSpace(req.ValueMessageType)
+	if keyType == "" && valueType == "" {
+		return nil, errors.New("keyMessageType or valueMessageType is required")
+	}
+
+	set, err := s.protoRepo.FindDescriptorSet(clusterID, req.DescriptorSetID)
+	if err != nil {
+		return nil, fmt.Errorf("descriptor set %d not found: %w", req.DescriptorSetID, err)
+	}
+	files, err := s.compile(set)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{keyType, valueType} {
+		if name == "" {
+			continue
+		}
+		if _, err := files.FindMessage(name); err != nil {
+			return nil, err
+		}
+	}
+
+	binding := &model.TopicProtoBinding{
+		ClusterID:        clusterID,
+		Topic:            topic,
+		DescriptorSetID:  set.ID,
+		KeyMessageType:   keyType,
+		ValueMessageType: valueType,
+	}
+	if err := s.protoRepo.UpsertBinding(binding); err != nil {
+		return nil, fmt.Errorf("failed to save binding: %w", err)
+	}
+	return binding, nil
+}
+
+// UnbindTopic removes the protobuf binding of a topic.
+func (s *ProtoService) UnbindTopic(clusterID uint, topic string) error {
+	return s.protoRepo.DeleteBinding(clusterID, topic)
+}
+
+// topicProto holds the resolved message types used to decode one topic.
+type topicProto struct {
+	files *util.ProtoFiles
+	key   protoreflect.MessageDescriptor
+	value protoreflect.MessageDescriptor
+}
+
+// topicMessageTypes resolves the binding of a topic, returning nil when the topic has none.
+func (s *ProtoService) topicMessageTypes(clusterID uint, topic string) (*topicProto, error) {
+	binding, err := s.GetTopicBinding(clusterID, topic)
+	if err != nil || binding == nil {
+		return nil, err
+	}
+
+	set, err := s.protoRepo.FindDescriptorSet(clusterID, binding.DescriptorSetID)
+	if err != nil {
+		return nil, fmt.Errorf("descriptor set %d bound to topic %s not found: %w", binding.DescriptorSetID, topic, err)
+	}
+	files, err := s.compile(set)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &topicProto{files: files}
+	if binding.KeyMessageType != "" {
+		if result.key, err = files.FindMessage(binding.KeyMessageType); err != nil {
+			return nil, err
+		}
+	}
+	if binding.ValueMessageType != "" {
+		if result.value, err = files.FindMessage(binding.ValueMessageType); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *ProtoService) compile(set *model.ProtoDescriptorSet) (*util.ProtoFiles, error) {
+	s.mu.RLock()
+	files, exists := s.compiled[set.ID]
+	s.mu.RUnlock()
+
+	if exists {
+		return files, nil
+	}
+
+	files, err := util.ParseDescriptorSet(set.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.compiled[set.ID] = files
+	s.mu.Unlock()
+	return files, nil
+}
+
+func descriptorSetInfo(set *model.ProtoDescriptorSet, files *util.ProtoFiles) *dto.ProtoDescriptorSetInfo {
+	return &dto.ProtoDescriptorSetInfo{
+		ID:           set.ID,
+		ClusterID:    set.ClusterID,
+		Name:         set.Name,
+		MessageTypes: files.MessageTypes(),
+		CreatedAt:    set.CreatedAt,
+	}
+}
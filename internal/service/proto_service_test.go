@@ -0,0 +1,161 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"github.com/bingfengfeifei/kafka-map-go/internal/repository"
+	"github.com/bingfengfeifei/kafka-map-go/pkg/database"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func testDescriptorSet(t *testing.T) []byte {
+	t.Helper()
+
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("shop/order.proto"),
+		Package: proto.String("shop"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Order"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("id"), JsonName: proto.String("id"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				{Name: proto.String("customer"), JsonName: proto.String("customer"), Number: proto.Int32(2), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".shop.Order.Customer")},
+			},
+			NestedType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Customer"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				},
+			}},
+		}},
+	}}}
+
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatalf("marshal descriptor set: %v", err)
+	}
+	return data
+}
+
+func newTestProtoService(t *testing.T) (*ProtoService, *model.Cluster) {
+	t.Helper()
+
+	db, err := database.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init test database: %v", err)
+	}
+	clusterRepo := repository.NewClusterRepository(db)
+	cluster := &model.Cluster{Name: "local", Servers: "kafka:9092"}
+	if err := clusterRepo.Create(cluster); err != nil {
+		t.Fatalf("create cluster: %v", err)
+	}
+	return NewProtoService(clusterRepo, repository.NewProtoRepository(db)), cluster
+}
+
+func TestProtoServiceDecodesBoundTopic(t *testing.T) {
+	protoService, cluster := newTestProtoService(t)
+
+	set, err := protoService.UploadDescriptorSet(cluster.ID, "orders", testDescriptorSet(t))
+	if err != nil {
+		t.Fatalf("UploadDescriptorSet() error = %v", err)
+	}
+	if len(set.MessageTypes) != 2 || set.MessageTypes[0] != "shop.Order" || set.MessageTypes[1] != "shop.Order.Customer" {
+		t.Fatalf("MessageTypes = %v, want shop.Order and shop.Order.Customer", set.MessageTypes)
+	}
+
+	if _, err := protoService.BindTopic(cluster.ID, "orders", &dto.TopicProtoBindingRequest{
+		DescriptorSetID:  set.ID,
+		ValueMessageType: "shop.Order",
+	}); err != nil {
+		t.Fatalf("BindTopic() error = %v", err)
+	}
+
+	types, err := protoService.topicMessageTypes(cluster.ID, "orders")
+	if err != nil || types == nil || types.value == nil || types.key != nil {
+		t.Fatalf("topicMessageTypes() = %+v, %v, want value type only", types, err)
+	}
+
+	order := dynamicpb.NewMessage(types.value)
+	order.Set(types.value.Fields().ByName("id"), protoreflect.ValueOfString("A-12"))
+	customer := dynamicpb.NewMessage(types.value.Fields().ByName("customer").Message())
+	customer.Set(customer.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString("ada"))
+	order.Set(types.value.Fields().ByName("customer"), protoreflect.ValueOfMessage(customer))
+	payload, err := proto.Marshal(order)
+	if err != nil {
+		t.Fatalf("marshal order: %v", err)
+	}
+
+	decoder := newMessageDecoder(cluster, nil, types, dto.EncodingAuto)
+	record := decoder.record(&sarama.ConsumerMessage{Key: []byte("A-12"), Value: payload})
+	if record.DecodeError != "" {
+		t.Fatalf("DecodeError = %q", record.DecodeError)
+	}
+	if record.Key != "A-12" {
+		t.Fatalf("Key = %q, want raw key", record.Key)
+	}
+	if record.Value != `{"id":"A-12","customer":{"name":"ada"}}` {
+		t.Fatalf("Value = %s", record.Value)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(record.Value), &value); err != nil {
+		t.Fatalf("Value is not JSON: %v", err)
+	}
+	if !findInJSON(value, "name", "ada") {
+		t.Fatal("findInJSON() = false, want nested match on decoded value")
+	}
+
+	if err := protoService.DeleteDescriptorSet(cluster.ID, set.ID); err == nil {
+		t.Fatal("DeleteDescriptorSet() error = nil, want bound set to be kept")
+	}
+	if err := protoService.UnbindTopic(cluster.ID, "orders"); err != nil {
+		t.Fatalf("UnbindTopic() error = %v", err)
+	}
+	if err := protoService.DeleteDescriptorSet(cluster.ID+1, set.ID); !errors.Is(err, ErrDescriptorSetNotFound) {
+		t.Fatalf("DeleteDescriptorSet() error = %v, want ErrDescriptorSetNotFound for another cluster", err)
+	}
+	if err := protoService.DeleteDescriptorSet(cluster.ID, set.ID); err != nil {
+		t.Fatalf("DeleteDescriptorSet() error = %v", err)
+	}
+	if err := protoService.DeleteDescriptorSet(cluster.ID, set.ID); !errors.Is(err, ErrDescriptorSetNotFound) {
+		t.Fatalf("DeleteDescriptorSet() error = %v, want ErrDescriptorSetNotFound for a deleted set", err)
+	}
+}
+
+func TestProtoServiceRejectsInvalidBindings(t *testing.T) {
+	protoService, cluster := newTestProtoService(t)
+
+	if _, err := protoService.UploadDescriptorSet(cluster.ID, "garbage", []byte("not a descriptor set")); err == nil {
+		t.Fatal("UploadDescriptorSet() error = nil, want parse failure")
+	}
+
+	set, err := protoService.UploadDescriptorSet(cluster.ID, "orders", testDescriptorSet(t))
+	if err != nil {
+		t.Fatalf("UploadDescriptorSet() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  dto.TopicProtoBindingRequest
+	}{
+		{name: "no message type", req: dto.TopicProtoBindingRequest{DescriptorSetID: set.ID}},
+		{name: "unknown message type", req: dto.TopicProtoBindingRequest{DescriptorSetID: set.ID, ValueMessageType: "shop.Invoice"}},
+		{name: "unknown descriptor set", req: dto.TopicProtoBindingRequest{DescriptorSetID: set.ID + 1, ValueMessageType: "shop.Order"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := protoService.BindTopic(cluster.ID, "orders", &tt.req); err == nil {
+				t.Fatal("BindTopic() error = nil, want rejection")
+			}
+		})
+	}
+}
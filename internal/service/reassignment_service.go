@@ -0,0 +1,1079 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"github.com/bingfengfeifei/kafka-map-go/internal/repository"
+	"github.com/bingfengfeifei/kafka-map-go/internal/util"
+	"gorm.io/gorm"
+)
+
+// reassignmentPollInterval is how often the progress of a running reassignment is checked.
+const reassignmentPollInterval = 5 * time.Second
+
+// Broker and topic configs throttling the replication traffic of a reassignment
+const (
+	leaderThrottleRate        = "leader.replication.throttled.rate"
+	followerThrottleRate      = "follower.replication.throttled.rate"
+	leaderThrottledReplicas   = "leader.replication.throttled.replicas"
+	followerThrottledReplicas = "follower.replication.throttled.replicas"
+)
+
+var (
+	// ErrInvalidReassignment is wrapped by errors about plans and reassignments that do not fit the cluster.
+	ErrInvalidReassignment = errors.New("invalid reassignment")
+	// ErrReassignmentRunning is returned when a reassignment is started while another one runs.
+	ErrReassignmentRunning = errors.New("a reassignment is already running on this cluster")
+	// ErrNoReassignment is returned for the status of a cluster without a reassignment.
+	ErrNoReassignment = errors.New("no reassignment was started on this cluster")
+	// ErrReassignmentBusy is returned while another request starts or cancels a reassignment on the cluster.
+	ErrReassignmentBusy = errors.New("a reassignment is being started or cancelled on this cluster")
+)
+
+// ReassignmentService plans and executes partition reassignments. The reassignment itself
+// is carried out by the Kafka controller; the service applies the replication throttles,
+// polls the progress and removes the throttles once every partition arrived or the
+// reassignment was cancelled. The last reassignment of each cluster is kept in memory;
+// the configs changed by its throttles are saved so they can be restored after a restart.
+type ReassignmentService struct {
+	clusterRepo  *repository.ClusterRepository
+	throttleRepo *repository.ReplicationThrottleRepository
+	kafkaManager *util.KafkaClientManager
+	runs         map[uint]*reassignmentRun
+	busy         map[uint]bool // clusters a reassignment is being started or cancelled on
+	mu           sync.Mutex
+}
+
+func NewReassignmentService(clusterRepo *repository.ClusterRepository, throttleRepo *repository.ReplicationThrottleRepository, kafkaManager *util.KafkaClientManager) *ReassignmentService {
+	return &ReassignmentService{
+		clusterRepo:  clusterRepo,
+		throttleRepo: throttleRepo,
+		kafkaManager: kafkaManager,
+		runs:         make(map[uint]*reassignmentRun),
+		busy:         make(map[uint]bool),
+	}
+}
+
+// reserve claims a cluster for starting or cancelling a reassignment and returns its last
+// run. The claim keeps other requests off the cluster while the Kafka calls are made
+// without holding s.mu, so a slow cluster does not hold up the others.
+func (s *ReassignmentService) reserve(clusterID uint) (*reassignmentRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.busy[clusterID] {
+		return nil, ErrReassignmentBusy
+	}
+	s.busy[clusterID] = true
+	return s.runs[clusterID], nil
+}
+
+func (s *ReassignmentService) unreserve(clusterID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.busy, clusterID)
+}
+
+// RestoreInterruptedThrottles puts back the configs changed by the throttles of
+// reassignments that were running when the server stopped, since no run is left to remove
+// them. Throttles of removed clusters are dropped.
+func (s *ReassignmentService) RestoreInterruptedThrottles() error {
+	saved, err := s.throttleRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	byCluster := make(map[uint][]model.ReplicationThrottle)
+	for _, config := range saved {
+		byCluster[config.ClusterID] = append(byCluster[config.ClusterID], config)
+	}
+	for clusterID, configs := range byCluster {
+		admin, err := s.adminClient(clusterID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := s.throttleRepo.DeleteByCluster(clusterID); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("[Reassignment] Failed to restore the replication throttles of cluster %d: %v", clusterID, err)
+			continue
+		}
+		restoreThrottles(admin, s.throttleRepo, configs)
+	}
+	return nil
+}
+
+// reassignmentRun is the state of a reassignment started by the service.
+type reassignmentRun struct {
+	stop      context.CancelFunc
+	throttles *replicationThrottles
+	status    dto.ReassignmentStatus
+	done      chan struct{} // closed once the run is no longer watched
+	closeDone sync.Once
+	mu        sync.Mutex
+}
+
+func (r *reassignmentRun) finish() {
+	r.closeDone.Do(func() { close(r.done) })
+}
+
+func (r *reassignmentRun) snapshot() dto.ReassignmentStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := r.status
+	status.Partitions = append([]dto.ReassignmentProgress(nil), r.status.Partitions...)
+	return status
+}
+
+func (r *reassignmentRun) running() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status.Status == dto.ReassignmentRunning
+}
+
+// Reassignment is a handle on a reassignment started by the service.
+type Reassignment struct {
+	run *reassignmentRun
+}
+
+// Status returns the progress of the reassignment.
+func (r *Reassignment) Status() *dto.ReassignmentStatus {
+	status := r.run.snapshot()
+	return &status
+}
+
+// Wait blocks until the reassignment ended or ctx is done, and returns its final status.
+func (r *Reassignment) Wait(ctx context.Context) (*dto.ReassignmentStatus, error) {
+	select {
+	case <-r.run.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return r.Status(), nil
+}
+
+// partitionReplicas is a partition to place, with the number of replicas it should end up with.
+type partitionReplicas struct {
+	topic             string
+	partition         int32
+	replicas          []int32
+	replicationFactor int
+}
+
+// PlanReassignment proposes target replicas spreading the partitions of topics evenly over
+// brokers, moving as few replicas as possible.
+func (s *ReassignmentService) PlanReassignment(clusterID uint, req *dto.ReassignmentPlanRequest) (*dto.ReassignmentPlan, error) {
+	topics := uniqueTopics(req.Topics)
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("%w: no topics selected", ErrInvalidReassignment)
+	}
+	if len(req.Brokers) == 0 {
+		return nil, fmt.Errorf("%w: no brokers selected", ErrInvalidReassignment)
+	}
+
+	admin, err := s.adminClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	racks, err := brokerRacks(admin)
+	if err != nil {
+		return nil, err
+	}
+	for _, broker := range req.Brokers {
+		if _, ok := racks[broker]; !ok {
+			return nil, fmt.Errorf("%w: broker %d does not exist", ErrInvalidReassignment, broker)
+		}
+	}
+	current, err := describeReplicas(admin, topics)
+	if err != nil {
+		return nil, err
+	}
+
+	var partitions []partitionReplicas
+	for topic, replicas := range current {
+		for partition, assigned := range replicas {
+			partitions = append(partitions, partitionReplicas{topic: topic, partition: partition, replicas: assigned, replicationFactor: len(assigned)})
+		}
+	}
+	plan, err := planReassignment(partitions, req.Brokers, racks)
+	if err != nil {
+		return nil, err
+	}
+	plan.Topics = topics
+	return plan, nil
+}
+
+// planReassignment places the replicas of partitions on brokers. Replicas already on one of
+// the brokers stay there as long as the broker does not exceed its even share; the missing
+// replicas go to the least loaded brokers, preferring racks the partition does not use yet.
+// Preferred leaders are then balanced the same way, keeping the current leader when possible.
+func planReassignment(partitions []partitionReplicas, brokers []int32, racks map[int32]string) (*dto.ReassignmentPlan, error) {
+	brokers = uniqueBrokers(brokers)
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].topic != partitions[j].topic {
+			return partitions[i].topic < partitions[j].topic
+		}
+		return partitions[i].partition < partitions[j].partition
+	})
+
+	eligible := make(map[int32]bool, len(brokers))
+	for _, broker := range brokers {
+		eligible[broker] = true
+	}
+	total := 0
+	for _, p := range partitions {
+		if p.replicationFactor < 1 || p.replicationFactor > len(brokers) {
+			return nil, fmt.Errorf("%w: %s-%d needs %d replicas but %d brokers are selected",
+				ErrInvalidReassignment, p.topic, p.partition, p.replicationFactor, len(brokers))
+		}
+		total += p.replicationFactor
+	}
+	replicaCap := (total + len(brokers) - 1) / len(brokers)
+	leaderCap := (len(partitions) + len(brokers) - 1) / len(brokers)
+
+	// replicas are kept position by position across all partitions, so the share of a
+	// broker is not used up by the first partitions alone
+	load := make(map[int32]int, len(brokers))
+	targets := make([][]int32, len(partitions))
+	for position := 0; ; position++ {
+		more := false
+		for i, p := range partitions {
+			if position >= len(p.replicas) {
+				continue
+			}
+			more = true
+			broker := p.replicas[position]
+			if len(targets[i]) < p.replicationFactor && eligible[broker] && load[broker] < replicaCap {
+				targets[i] = append(targets[i], broker)
+				load[broker]++
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	for i, p := range partitions {
+		for len(targets[i]) < p.replicationFactor {
+			usedRacks := make(map[string]bool)
+			for _, broker := range targets[i] {
+				if racks[broker] != "" {
+					usedRacks[racks[broker]] = true
+				}
+			}
+			best := int32(-1)
+			for _, broker := range brokers {
+				if containsBroker(targets[i], broker) {
+					continue
+				}
+				if best < 0 || betterPlacement(broker, best, load, usedRacks, racks) {
+					best = broker
+				}
+			}
+			targets[i] = append(targets[i], best)
+			load[best]++
+		}
+	}
+
+	leaders := make(map[int32]int, len(brokers))
+	for i, p := range partitions {
+		// keep the current leader first when it is still a replica and has room
+		lead := -1
+		if len(p.replicas) > 0 && leaders[p.replicas[0]] < leaderCap {
+			for j, broker := range targets[i] {
+				if broker == p.replicas[0] {
+					lead = j
+				}
+			}
+		}
+		if lead < 0 {
+			lead = 0
+			for j, broker := range targets[i] {
+				if leaders[broker] < leaders[targets[i][lead]] {
+					lead = j
+				}
+			}
+		}
+		leader := targets[i][lead]
+		targets[i] = append([]int32{leader}, append(append([]int32(nil), targets[i][:lead]...), targets[i][lead+1:]...)...)
+		leaders[leader]++
+	}
+
+	plan := &dto.ReassignmentPlan{Brokers: brokers, Partitions: []dto.PartitionReassignment{}}
+	loads := make(map[int32]*dto.ReassignmentBrokerLoad)
+	brokerLoad := func(broker int32) *dto.ReassignmentBrokerLoad {
+		if loads[broker] == nil {
+			loads[broker] = &dto.ReassignmentBrokerLoad{Broker: broker}
+		}
+		return loads[broker]
+	}
+	for _, broker := range brokers {
+		brokerLoad(broker)
+	}
+	for i, p := range partitions {
+		for j, broker := range p.replicas {
+			brokerLoad(broker).ReplicasBefore++
+			if j == 0 {
+				brokerLoad(broker).LeadersBefore++
+			}
+		}
+		for j, broker := range targets[i] {
+			brokerLoad(broker).ReplicasAfter++
+			if j == 0 {
+				brokerLoad(broker).LeadersAfter++
+			}
+		}
+		if sameReplicas(p.replicas, targets[i]) {
+			continue
+		}
+		move := newPartitionReassignment(p.topic, p.partition, p.replicas, targets[i])
+		plan.Moves += len(move.Adding)
+		plan.Partitions = append(plan.Partitions, move)
+	}
+	for _, l := range loads {
+		plan.Load = append(plan.Load, *l)
+	}
+	sort.Slice(plan.Load, func(i, j int) bool { return plan.Load[i].Broker < plan.Load[j].Broker })
+	return plan, nil
+}
+
+// betterPlacement reports whether a new replica is better placed on broker than on best:
+// on a rack the partition does not use yet, then on the broker with fewer replicas.
+func betterPlacement(broker, best int32, load map[int32]int, usedRacks map[string]bool, racks map[int32]string) bool {
+	brokerSpreads := racks[broker] == "" || !usedRacks[racks[broker]]
+	bestSpreads := racks[best] == "" || !usedRacks[racks[best]]
+	if brokerSpreads != bestSpreads {
+		return brokerSpreads
+	}
+	if load[broker] != load[best] {
+		return load[broker] < load[best]
+	}
+	return broker < best
+}
+
+func newPartitionReassignment(topic string, partition int32, current, target []int32) dto.PartitionReassignment {
+	move := dto.PartitionReassignment{Topic: topic, Partition: partition, Current: current, Target: target}
+	for _, broker := range target {
+		if !containsBroker(current, broker) {
+			move.Adding = append(move.Adding, broker)
+		}
+	}
+	for _, broker := range current {
+		if !containsBroker(target, broker) {
+			move.Removing = append(move.Removing, broker)
+		}
+	}
+	return move
+}
+
+// ValidateReassignment checks a reassignment request on its own, before it is matched
+// against the cluster.
+func ValidateReassignment(req *dto.ReassignmentRequest) error {
+	if len(req.Partitions) == 0 {
+		return errors.New("no partitions to reassign")
+	}
+	if req.Throttle < 0 {
+		return errors.New("throttle must not be negative")
+	}
+	seen := make(map[string]bool, len(req.Partitions))
+	for i := range req.Partitions {
+		p := &req.Partitions[i]
+		p.Topic = strings.TrimSpace(p.Topic)
+		if p.Topic == "" {
+			return errors.New("partition without topic")
+		}
+		key := fmt.Sprintf("%s-%d", p.Topic, p.Partition)
+		if seen[key] {
+			return fmt.Errorf("partition %s is listed twice", key)
+		}
+		seen[key] = true
+		if p.Partition < 0 {
+			return fmt.Errorf("invalid partition %s", key)
+		}
+		if len(p.Target) == 0 {
+			return fmt.Errorf("partition %s has no target replicas", key)
+		}
+		if len(uniqueBrokers(p.Target)) != len(p.Target) {
+			return fmt.Errorf("partition %s lists a broker twice", key)
+		}
+	}
+	return nil
+}
+
+// StartReassignment moves partitions to their target replicas, throttling the replication
+// traffic when req.Throttle is set, and tracks the progress until the move completes.
+// The returned handle follows this reassignment even once another one was started.
+func (s *ReassignmentService) StartReassignment(clusterID uint, req *dto.ReassignmentRequest) (*Reassignment, error) {
+	admin, err := s.adminClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := s.reserve(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.unreserve(clusterID)
+	if last != nil && last.running() {
+		return nil, ErrReassignmentRunning
+	}
+
+	racks, err := brokerRacks(admin)
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string]map[int32][]int32)
+	var topics []string
+	for _, p := range req.Partitions {
+		for _, broker := range p.Target {
+			if _, ok := racks[broker]; !ok {
+				return nil, fmt.Errorf("%w: broker %d does not exist", ErrInvalidReassignment, broker)
+			}
+		}
+		if targets[p.Topic] == nil {
+			targets[p.Topic] = make(map[int32][]int32)
+			topics = append(topics, p.Topic)
+		}
+		targets[p.Topic][p.Partition] = p.Target
+	}
+
+	current, err := describeReplicas(admin, topics)
+	if err != nil {
+		return nil, err
+	}
+	ongoing := make(map[string]map[int32]*sarama.PartitionReplicaReassignmentsStatus, len(topics))
+	for _, topic := range topics {
+		if ongoing[topic], err = listReassignments(admin, topic, partitionIDs(current[topic])); err != nil {
+			return nil, err
+		}
+		for partition := range targets[topic] {
+			if _, ok := current[topic][partition]; !ok {
+				return nil, fmt.Errorf("%w: partition %s-%d does not exist", ErrInvalidReassignment, topic, partition)
+			}
+			if _, ok := ongoing[topic][partition]; ok {
+				return nil, fmt.Errorf("%w: partition %s-%d is already being reassigned", ErrInvalidReassignment, topic, partition)
+			}
+		}
+	}
+
+	var moves []dto.PartitionReassignment
+	for _, p := range req.Partitions {
+		moves = append(moves, newPartitionReassignment(p.Topic, p.Partition, current[p.Topic][p.Partition], p.Target))
+	}
+	var throttles *replicationThrottles
+	if req.Throttle > 0 {
+		throttles = newReplicationThrottles(moves, req.Throttle)
+		if err := throttles.apply(admin, s.throttleRepo, clusterID); err != nil {
+			throttles.remove(admin, s.throttleRepo)
+			return nil, err
+		}
+	}
+
+	for _, topic := range topics {
+		if err := alterReassignments(admin, topic, current[topic], ongoing[topic], targets[topic]); err != nil {
+			if throttles != nil {
+				throttles.remove(admin, s.throttleRepo)
+			}
+			return nil, fmt.Errorf("failed to reassign partitions of %s: %w", topic, err)
+		}
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	run := &reassignmentRun{
+		stop:      stop,
+		throttles: throttles,
+		done:      make(chan struct{}),
+		status: dto.ReassignmentStatus{
+			ClusterID: clusterID,
+			Status:    dto.ReassignmentRunning,
+			Throttle:  req.Throttle,
+			Total:     len(moves),
+			StartedAt: time.Now(),
+		},
+	}
+	for _, move := range moves {
+		run.status.Partitions = append(run.status.Partitions, dto.ReassignmentProgress{
+			Topic:     move.Topic,
+			Partition: move.Partition,
+			Target:    move.Target,
+			Replicas:  move.Current,
+		})
+	}
+	s.mu.Lock()
+	s.runs[clusterID] = run
+	s.mu.Unlock()
+	go s.watch(ctx, clusterID, run)
+	return &Reassignment{run: run}, nil
+}
+
+// watch polls a reassignment until it ends, then removes its throttles. The admin client
+// is looked up on every poll as it is replaced when the cluster settings change.
+func (s *ReassignmentService) watch(ctx context.Context, clusterID uint, run *reassignmentRun) {
+	ticker := time.NewTicker(reassignmentPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		admin, err := s.adminClient(clusterID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// the cluster was removed, nothing is left to watch
+			run.mu.Lock()
+			now := time.Now()
+			run.status.Status = dto.ReassignmentFailed
+			run.status.Error = "cluster was removed"
+			run.status.FinishedAt = &now
+			run.mu.Unlock()
+			run.finish()
+			return
+		}
+		if err == nil {
+			err = pollReassignment(admin, run)
+		}
+		if err != nil {
+			log.Printf("[Reassignment] Failed to poll reassignment of cluster %d: %v", clusterID, err)
+			continue
+		}
+		if !run.running() {
+			if run.throttles != nil {
+				run.throttles.remove(admin, s.throttleRepo)
+			}
+			run.finish()
+			return
+		}
+	}
+}
+
+// pollReassignment refreshes the progress of every partition of a run. The run ends once
+// the controller lists none of its partitions as moving anymore.
+func pollReassignment(admin sarama.ClusterAdmin, run *reassignmentRun) error {
+	run.mu.Lock()
+	partitions := append([]dto.ReassignmentProgress(nil), run.status.Partitions...)
+	run.mu.Unlock()
+
+	byTopic := make(map[string][]int32)
+	var topics []string
+	for _, p := range partitions {
+		if byTopic[p.Topic] == nil {
+			topics = append(topics, p.Topic)
+		}
+		byTopic[p.Topic] = append(byTopic[p.Topic], p.Partition)
+	}
+	current, err := describeReplicas(admin, topics)
+	if err != nil {
+		return err
+	}
+	ongoing := make(map[string]map[int32]*sarama.PartitionReplicaReassignmentsStatus, len(topics))
+	for _, topic := range topics {
+		if ongoing[topic], err = listReassignments(admin, topic, byTopic[topic]); err != nil {
+			return err
+		}
+	}
+
+	moving, completed := 0, 0
+	for i := range partitions {
+		p := &partitions[i]
+		if state, ok := ongoing[p.Topic][p.Partition]; ok {
+			p.Replicas, p.Adding, p.Removing, p.Done = state.Replicas, state.AddingReplicas, state.RemovingReplicas, false
+			moving++
+			continue
+		}
+		p.Replicas, p.Adding, p.Removing = current[p.Topic][p.Partition], nil, nil
+		p.Done = sameBrokerSet(p.Replicas, p.Target)
+		if p.Done {
+			completed++
+		}
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.status.Partitions = partitions
+	run.status.Completed = completed
+	run.status.CheckedAt = time.Now()
+	if moving == 0 && run.status.Status == dto.ReassignmentRunning {
+		now := time.Now()
+		run.status.FinishedAt = &now
+		run.status.Status = dto.ReassignmentCompleted
+		if completed < len(partitions) {
+			run.status.Status = dto.ReassignmentFailed
+			run.status.Error = fmt.Sprintf("%d partitions stopped moving without reaching their target replicas", len(partitions)-completed)
+		}
+	}
+	return nil
+}
+
+// GetReassignment returns the progress of the last reassignment started on a cluster.
+func (s *ReassignmentService) GetReassignment(clusterID uint) (*dto.ReassignmentStatus, error) {
+	s.mu.Lock()
+	run, ok := s.runs[clusterID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrNoReassignment
+	}
+	status := run.snapshot()
+	return &status, nil
+}
+
+// CancelReassignment stops the partitions of the running reassignment that are still moving,
+// returning them to their original replicas, and removes the throttles.
+func (s *ReassignmentService) CancelReassignment(clusterID uint) (*dto.ReassignmentStatus, error) {
+	run, err := s.reserve(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.unreserve(clusterID)
+	if run == nil || !run.running() {
+		return nil, ErrNoReassignment
+	}
+	admin, err := s.adminClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := run.snapshot()
+	byTopic := make(map[string][]int32)
+	for _, p := range status.Partitions {
+		byTopic[p.Topic] = append(byTopic[p.Topic], p.Partition)
+	}
+	topics := make([]string, 0, len(byTopic))
+	for topic := range byTopic {
+		topics = append(topics, topic)
+	}
+	current, err := describeReplicas(admin, topics)
+	if err != nil {
+		return nil, err
+	}
+	for _, topic := range topics {
+		ongoing, err := listReassignments(admin, topic, partitionIDs(current[topic]))
+		if err != nil {
+			return nil, err
+		}
+		cancel := make(map[int32][]int32)
+		for _, partition := range byTopic[topic] {
+			if _, ok := ongoing[partition]; ok {
+				cancel[partition] = nil
+			}
+		}
+		if len(cancel) == 0 {
+			continue
+		}
+		if err := alterReassignments(admin, topic, current[topic], ongoing, cancel); err != nil {
+			// a partition may have completed in the meantime, which fails its cancellation
+			left, listErr := listReassignments(admin, topic, byTopic[topic])
+			if listErr != nil || len(left) > 0 {
+				return nil, fmt.Errorf("failed to cancel reassignment of %s: %w", topic, err)
+			}
+		}
+	}
+
+	run.stop()
+	if err := pollReassignment(admin, run); err != nil {
+		log.Printf("[Reassignment] Failed to poll cancelled reassignment of cluster %d: %v", clusterID, err)
+	}
+	run.mu.Lock()
+	now := time.Now()
+	run.status.Status = dto.ReassignmentCancelled
+	run.status.Error = ""
+	run.status.FinishedAt = &now
+	run.mu.Unlock()
+	if run.throttles != nil {
+		run.throttles.remove(admin, s.throttleRepo)
+	}
+	run.finish()
+
+	status = run.snapshot()
+	return &status, nil
+}
+
+func (s *ReassignmentService) adminClient(clusterID uint) (sarama.ClusterAdmin, error) {
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return s.kafkaManager.GetAdminClient(cluster)
+}
+
+// alterReassignments submits new target replicas for some partitions of a topic; a nil
+// target cancels the reassignment of the partition. The request covers every partition up
+// to the highest one given, so the others are resubmitted unchanged: the target of their
+// ongoing reassignment, or else their current replicas.
+func alterReassignments(admin sarama.ClusterAdmin, topic string, current map[int32][]int32, ongoing map[int32]*sarama.PartitionReplicaReassignmentsStatus, targets map[int32][]int32) error {
+	assignment, err := reassignmentAssignment(current, ongoing, targets)
+	if err != nil {
+		return err
+	}
+	return admin.AlterPartitionReassignments(topic, assignment)
+}
+
+func reassignmentAssignment(current map[int32][]int32, ongoing map[int32]*sarama.PartitionReplicaReassignmentsStatus, targets map[int32][]int32) ([][]int32, error) {
+	highest := int32(-1)
+	for partition := range targets {
+		if partition > highest {
+			highest = partition
+		}
+	}
+	assignment := make([][]int32, highest+1)
+	for partition := int32(0); partition <= highest; partition++ {
+		if target, ok := targets[partition]; ok {
+			assignment[partition] = target
+			continue
+		}
+		if state, ok := ongoing[partition]; ok {
+			assignment[partition] = ongoingTarget(state)
+			continue
+		}
+		replicas, ok := current[partition]
+		if !ok || len(replicas) == 0 {
+			return nil, fmt.Errorf("replicas of partition %d are unknown", partition)
+		}
+		assignment[partition] = replicas
+	}
+	return assignment, nil
+}
+
+// ongoingTarget is the replica set a reassignment in progress moves towards.
+func ongoingTarget(state *sarama.PartitionReplicaReassignmentsStatus) []int32 {
+	var target []int32
+	for _, broker := range state.Replicas {
+		if !containsBroker(state.RemovingReplicas, broker) {
+			target = append(target, broker)
+		}
+	}
+	return target
+}
+
+// replicationThrottles are the configs limiting the replication traffic of a reassignment:
+// a rate on every broker taking part and, per topic, the replicas the rate applies to.
+// Existing replicas are throttled as leaders and the replicas being added as followers.
+type replicationThrottles struct {
+	rate      int64
+	brokers   []int32
+	leaders   map[string][]string // topic to partition:broker entries
+	followers map[string][]string
+	saved     []model.ReplicationThrottle // configs changed by apply, with their previous values
+}
+
+// throttledResource is a broker or topic with the throttle configs to set on it.
+type throttledResource struct {
+	resourceType sarama.ConfigResourceType
+	name         string
+	values       map[string]string
+}
+
+func newReplicationThrottles(moves []dto.PartitionReassignment, rate int64) *replicationThrottles {
+	throttles := &replicationThrottles{rate: rate, leaders: make(map[string][]string), followers: make(map[string][]string)}
+	brokers := make(map[int32]bool)
+	for _, move := range moves {
+		if len(move.Adding) == 0 {
+			continue
+		}
+		for _, broker := range move.Current {
+			brokers[broker] = true
+			throttles.leaders[move.Topic] = append(throttles.leaders[move.Topic], fmt.Sprintf("%d:%d", move.Partition, broker))
+		}
+		for _, broker := range move.Adding {
+			brokers[broker] = true
+			throttles.followers[move.Topic] = append(throttles.followers[move.Topic], fmt.Sprintf("%d:%d", move.Partition, broker))
+		}
+	}
+	for broker := range brokers {
+		throttles.brokers = append(throttles.brokers, broker)
+	}
+	sort.Slice(throttles.brokers, func(i, j int) bool { return throttles.brokers[i] < throttles.brokers[j] })
+	return throttles
+}
+
+// resources lists the brokers and topics to throttle with the configs to set on each.
+func (t *replicationThrottles) resources() []throttledResource {
+	rate := strconv.FormatInt(t.rate, 10)
+	resources := make([]throttledResource, 0, len(t.brokers)+len(t.leaders))
+	for _, broker := range t.brokers {
+		resources = append(resources, throttledResource{
+			resourceType: sarama.BrokerResource,
+			name:         strconv.Itoa(int(broker)),
+			values:       map[string]string{leaderThrottleRate: rate, followerThrottleRate: rate},
+		})
+	}
+	topics := make([]string, 0, len(t.leaders))
+	for topic := range t.leaders {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	for _, topic := range topics {
+		resources = append(resources, throttledResource{
+			resourceType: sarama.TopicResource,
+			name:         topic,
+			values: map[string]string{
+				leaderThrottledReplicas:   strings.Join(t.leaders[topic], ","),
+				followerThrottledReplicas: strings.Join(t.followers[topic], ","),
+			},
+		})
+	}
+	return resources
+}
+
+// apply sets the throttle configs. The values they had are read and saved first, so that
+// remove, or RestoreInterruptedThrottles after a restart, can put them back. Replicas that
+// were throttled already stay throttled while the reassignment runs.
+func (t *replicationThrottles) apply(admin sarama.ClusterAdmin, repo *repository.ReplicationThrottleRepository, clusterID uint) error {
+	resources := t.resources()
+	var saved []model.ReplicationThrottle
+	for _, resource := range resources {
+		names := make([]string, 0, len(resource.values))
+		for name := range resource.values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		previous, err := ownConfigs(admin, resource.resourceType, resource.name, names)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			config := model.ReplicationThrottle{
+				ClusterID:    clusterID,
+				ResourceType: int8(resource.resourceType),
+				ResourceName: resource.name,
+				Name:         name,
+			}
+			if value, ok := previous[name]; ok {
+				config.Previous = &value
+				if name == leaderThrottledReplicas || name == followerThrottledReplicas {
+					resource.values[name] = mergeThrottledReplicas(value, resource.values[name])
+				}
+			}
+			saved = append(saved, config)
+		}
+	}
+	if err := repo.Create(saved); err != nil {
+		return fmt.Errorf("failed to save the replication throttles: %w", err)
+	}
+	t.saved = saved
+
+	for _, resource := range resources {
+		entries := make(map[string]sarama.IncrementalAlterConfigsEntry, len(resource.values))
+		for name := range resource.values {
+			value := resource.values[name]
+			entries[name] = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &value}
+		}
+		if err := admin.IncrementalAlterConfig(resource.resourceType, resource.name, entries, false); err != nil {
+			return fmt.Errorf("failed to throttle %s: %w", throttledResourceName(resource.resourceType, resource.name), err)
+		}
+	}
+	return nil
+}
+
+// remove restores the configs changed by apply. Failures are logged, since the throttles
+// are removed after the reassignment itself succeeded or was cancelled; the saved values
+// of what failed are kept for RestoreInterruptedThrottles.
+func (t *replicationThrottles) remove(admin sarama.ClusterAdmin, repo *repository.ReplicationThrottleRepository) {
+	restoreThrottles(admin, repo, t.saved)
+}
+
+// restoreThrottles sets saved throttle configs back to their previous values, deleting the
+// ones that were not set, and drops the saved rows of every broker and topic restored.
+func restoreThrottles(admin sarama.ClusterAdmin, repo *repository.ReplicationThrottleRepository, configs []model.ReplicationThrottle) {
+	type resource struct {
+		resourceType sarama.ConfigResourceType
+		name         string
+	}
+	var resources []resource
+	byResource := make(map[resource][]model.ReplicationThrottle)
+	for _, config := range configs {
+		key := resource{sarama.ConfigResourceType(config.ResourceType), config.ResourceName}
+		if _, ok := byResource[key]; !ok {
+			resources = append(resources, key)
+		}
+		byResource[key] = append(byResource[key], config)
+	}
+
+	for _, key := range resources {
+		entries := make(map[string]sarama.IncrementalAlterConfigsEntry)
+		ids := make([]uint, 0, len(byResource[key]))
+		for _, config := range byResource[key] {
+			entry := sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationDelete}
+			if config.Previous != nil {
+				entry = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationSet, Value: config.Previous}
+			}
+			entries[config.Name] = entry
+			ids = append(ids, config.ID)
+		}
+		name := throttledResourceName(key.resourceType, key.name)
+		if err := admin.IncrementalAlterConfig(key.resourceType, key.name, entries, false); err != nil {
+			log.Printf("[Reassignment] Failed to remove the replication throttle of %s: %v", name, err)
+			continue
+		}
+		if err := repo.Delete(ids); err != nil {
+			log.Printf("[Reassignment] Failed to delete the saved replication throttle of %s: %v", name, err)
+		}
+	}
+}
+
+// ownConfigs returns the values of the named configs set on a broker or topic itself,
+// leaving out defaults and the values a topic inherits from its broker.
+func ownConfigs(admin sarama.ClusterAdmin, resourceType sarama.ConfigResourceType, name string, names []string) (map[string]string, error) {
+	entries, err := admin.DescribeConfig(sarama.ConfigResource{Type: resourceType, Name: name, ConfigNames: names})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the configs of %s: %w", throttledResourceName(resourceType, name), err)
+	}
+	values := make(map[string]string)
+	for _, entry := range entries {
+		own := entry.Source == sarama.SourceTopic || entry.Source == sarama.SourceDynamicBroker ||
+			(entry.Source == sarama.SourceUnknown && !entry.Default)
+		if own {
+			values[entry.Name] = entry.Value
+		}
+	}
+	return values, nil
+}
+
+// mergeThrottledReplicas adds the partition:broker entries of a reassignment to the
+// throttled replicas a topic had already; "*" throttles every replica of the topic.
+func mergeThrottledReplicas(previous, entries string) string {
+	if strings.TrimSpace(previous) == "*" {
+		return "*"
+	}
+	seen := make(map[string]bool)
+	var merged []string
+	for _, entry := range append(strings.Split(previous, ","), strings.Split(entries, ",")...) {
+		entry = strings.TrimSpace(entry)
+		if entry != "" && !seen[entry] {
+			seen[entry] = true
+			merged = append(merged, entry)
+		}
+	}
+	return strings.Join(merged, ",")
+}
+
+func throttledResourceName(resourceType sarama.ConfigResourceType, name string) string {
+	if resourceType == sarama.BrokerResource {
+		return "broker " + name
+	}
+	return "topic " + name
+}
+
+// brokerRacks returns the rack of every broker of the cluster, empty when it has none.
+func brokerRacks(admin sarama.ClusterAdmin) (map[int32]string, error) {
+	brokers, _, err := admin.DescribeCluster()
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe cluster: %w", err)
+	}
+	racks := make(map[int32]string, len(brokers))
+	for _, broker := range brokers {
+		racks[broker.ID()] = broker.Rack()
+	}
+	return racks, nil
+}
+
+// describeReplicas returns the replicas of every partition of topics.
+func describeReplicas(admin sarama.ClusterAdmin, topics []string) (map[string]map[int32][]int32, error) {
+	metadata, err := admin.DescribeTopics(topics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topics: %w", err)
+	}
+	replicas := make(map[string]map[int32][]int32, len(metadata))
+	for _, topic := range metadata {
+		if topic == nil {
+			continue
+		}
+		if topic.Err != sarama.ErrNoError {
+			return nil, fmt.Errorf("%w: topic %s: %v", ErrInvalidReassignment, topic.Name, topic.Err)
+		}
+		replicas[topic.Name] = make(map[int32][]int32, len(topic.Partitions))
+		for _, partition := range topic.Partitions {
+			if partition != nil {
+				replicas[topic.Name][partition.ID] = partition.Replicas
+			}
+		}
+	}
+	return replicas, nil
+}
+
+// listReassignments returns the reassignments in progress on partitions of a topic.
+func listReassignments(admin sarama.ClusterAdmin, topic string, partitions []int32) (map[int32]*sarama.PartitionReplicaReassignmentsStatus, error) {
+	status, err := admin.ListPartitionReassignments(topic, partitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reassignments of %s: %w", topic, err)
+	}
+	return status[topic], nil
+}
+
+func partitionIDs(replicas map[int32][]int32) []int32 {
+	ids := make([]int32, 0, len(replicas))
+	for id := range replicas {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func uniqueTopics(topics []string) []string {
+	seen := make(map[string]bool, len(topics))
+	unique := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		topic = strings.TrimSpace(topic)
+		if topic != "" && !seen[topic] {
+			seen[topic] = true
+			unique = append(unique, topic)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+func uniqueBrokers(brokers []int32) []int32 {
+	seen := make(map[int32]bool, len(brokers))
+	unique := make([]int32, 0, len(brokers))
+	for _, broker := range brokers {
+		if !seen[broker] {
+			seen[broker] = true
+			unique = append(unique, broker)
+		}
+	}
+	return unique
+}
+
+func containsBroker(brokers []int32, broker int32) bool {
+	for _, b := range brokers {
+		if b == broker {
+			return true
+		}
+	}
+	return false
+}
+
+// sameReplicas compares replica lists including their order, which sets the preferred leader.
+func sameReplicas(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameBrokerSet(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, broker := range a {
+		if !containsBroker(b, broker) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"github.com/bingfengfeifei/kafka-map-go/internal/repository"
+	"github.com/bingfengfeifei/kafka-map-go/internal/util"
+	"github.com/bingfengfeifei/kafka-map-go/pkg/database"
+)
+
+func TestPlanReassignment(t *testing.T) {
+	orders := func(replicas ...[]int32) []partitionReplicas {
+		partitions := make([]partitionReplicas, len(replicas))
+		for i, assigned := range replicas {
+			partitions[i] = partitionReplicas{topic: "orders", partition: int32(i), replicas: assigned, replicationFactor: len(assigned)}
+		}
+		return partitions
+	}
+
+	tests := []struct {
+		name       string
+		partitions []partitionReplicas
+		brokers    []int32
+		racks      map[int32]string
+		want       map[int32][]int32 // target replicas of the partitions that move
+		wantMoves  int
+		wantErr    bool
+	}{
+		{
+			name:       "spread onto a new broker",
+			partitions: orders([]int32{1, 2}, []int32{2, 1}, []int32{1, 2}),
+			brokers:    []int32{1, 2, 3},
+			want:       map[int32][]int32{1: {2, 3}, 2: {3, 1}},
+			wantMoves:  2,
+		},
+		{
+			name:       "move off a removed broker",
+			partitions: orders([]int32{3, 1}, []int32{1, 2}),
+			brokers:    []int32{1, 2},
+			// partition 1 hands its preferred leader to broker 2 to balance leaders
+			want:      map[int32][]int32{0: {1, 2}, 1: {2, 1}},
+			wantMoves: 1,
+		},
+		{
+			name:       "balanced topic stays",
+			partitions: orders([]int32{1, 2}, []int32{2, 1}),
+			brokers:    []int32{1, 2},
+			want:       map[int32][]int32{},
+		},
+		{
+			name:       "new replicas prefer another rack",
+			partitions: []partitionReplicas{{topic: "orders", partition: 0, replicas: []int32{1}, replicationFactor: 2}},
+			brokers:    []int32{1, 2, 3},
+			racks:      map[int32]string{1: "a", 2: "a", 3: "b"},
+			want:       map[int32][]int32{0: {1, 3}},
+			wantMoves:  1,
+		},
+		{
+			name:       "more replicas than brokers",
+			partitions: orders([]int32{1, 2, 3}),
+			brokers:    []int32{1, 2},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planReassignment(tt.partitions, tt.brokers, tt.racks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("planReassignment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidReassignment) {
+					t.Fatalf("planReassignment() error = %v, want ErrInvalidReassignment", err)
+				}
+				return
+			}
+
+			got := make(map[int32][]int32)
+			for _, move := range plan.Partitions {
+				got[move.Partition] = move.Target
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("planned targets = %v, want %v", got, tt.want)
+			}
+			if plan.Moves != tt.wantMoves {
+				t.Fatalf("plan.Moves = %d, want %d", plan.Moves, tt.wantMoves)
+			}
+		})
+	}
+}
+
+func TestPlanReassignmentLoad(t *testing.T) {
+	partitions := []partitionReplicas{
+		{topic: "orders", partition: 0, replicas: []int32{1, 2}, replicationFactor: 2},
+		{topic: "orders", partition: 1, replicas: []int32{2, 1}, replicationFactor: 2},
+		{topic: "orders", partition: 2, replicas: []int32{1, 2}, replicationFactor: 2},
+	}
+	plan, err := planReassignment(partitions, []int32{1, 2, 3}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []dto.ReassignmentBrokerLoad{
+		{Broker: 1, ReplicasBefore: 3, ReplicasAfter: 2, LeadersBefore: 2, LeadersAfter: 1},
+		{Broker: 2, ReplicasBefore: 3, ReplicasAfter: 2, LeadersBefore: 1, LeadersAfter: 1},
+		{Broker: 3, ReplicasBefore: 0, ReplicasAfter: 2, LeadersBefore: 0, LeadersAfter: 1},
+	}
+	if !reflect.DeepEqual(plan.Load, want) {
+		t.Fatalf("plan.Load = %+v, want %+v", plan.Load, want)
+	}
+	move := plan.Partitions[0]
+	if !reflect.DeepEqual(move.Adding, []int32{3}) || !reflect.DeepEqual(move.Removing, []int32{1}) {
+		t.Fatalf("diff of partition 1 = +%v -%v, want +[3] -[1]", move.Adding, move.Removing)
+	}
+}
+
+func TestValidateReassignment(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     dto.ReassignmentRequest
+		wantErr bool
+	}{
+		{name: "valid", req: dto.ReassignmentRequest{Partitions: []dto.PartitionReassignment{{Topic: "orders", Target: []int32{1, 2}}}, Throttle: 1048576}},
+		{name: "no partitions", req: dto.ReassignmentRequest{}, wantErr: true},
+		{name: "no target", req: dto.ReassignmentRequest{Partitions: []dto.PartitionReassignment{{Topic: "orders"}}}, wantErr: true},
+		{name: "broker twice", req: dto.ReassignmentRequest{Partitions: []dto.PartitionReassignment{{Topic: "orders", Target: []int32{1, 1}}}}, wantErr: true},
+		{name: "partition twice", req: dto.ReassignmentRequest{Partitions: []dto.PartitionReassignment{{Topic: "orders", Target: []int32{1}}, {Topic: " orders ", Target: []int32{2}}}}, wantErr: true},
+		{name: "negative throttle", req: dto.ReassignmentRequest{Partitions: []dto.PartitionReassignment{{Topic: "orders", Target: []int32{1}}}, Throttle: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateReassignment(&tt.req); (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateReassignment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReassignmentAssignment(t *testing.T) {
+	current := map[int32][]int32{0: {1, 2}, 1: {2, 3}, 2: {3, 1}, 3: {1, 3}}
+	ongoing := map[int32]*sarama.PartitionReplicaReassignmentsStatus{
+		1: {Replicas: []int32{2, 3, 4}, AddingReplicas: []int32{4}, RemovingReplicas: []int32{3}},
+	}
+
+	got, err := reassignmentAssignment(current, ongoing, map[int32][]int32{2: {4, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// partition 0 is resubmitted unchanged, partition 1 keeps its ongoing target, partition 3 is left out
+	want := [][]int32{{1, 2}, {2, 4}, {4, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("reassignmentAssignment() = %v, want %v", got, want)
+	}
+
+	cancelled, err := reassignmentAssignment(current, ongoing, map[int32][]int32{1: nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cancelled) != 2 || cancelled[1] != nil {
+		t.Fatalf("cancellation = %v, want a nil target for partition 1", cancelled)
+	}
+}
+
+func TestNewReplicationThrottles(t *testing.T) {
+	throttles := newReplicationThrottles([]dto.PartitionReassignment{
+		newPartitionReassignment("orders", 0, []int32{1, 2}, []int32{3, 2}),
+		// a new preferred leader moves no data and needs no throttle
+		newPartitionReassignment("orders", 1, []int32{2, 1}, []int32{1, 2}),
+	}, 1048576)
+
+	if !reflect.DeepEqual(throttles.brokers, []int32{1, 2, 3}) {
+		t.Fatalf("throttled brokers = %v, want [1 2 3]", throttles.brokers)
+	}
+	if !reflect.DeepEqual(throttles.leaders["orders"], []string{"0:1", "0:2"}) {
+		t.Fatalf("leader throttled replicas = %v, want [0:1 0:2]", throttles.leaders["orders"])
+	}
+	if !reflect.DeepEqual(throttles.followers["orders"], []string{"0:3"}) {
+		t.Fatalf("follower throttled replicas = %v, want [0:3]", throttles.followers["orders"])
+	}
+}
+
+func TestMergeThrottledReplicas(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		entries  string
+		want     string
+	}{
+		{name: "nothing throttled", previous: "", entries: "0:1,0:2", want: "0:1,0:2"},
+		{name: "other replicas throttled", previous: "1:1, 0:1", entries: "0:1,0:2", want: "1:1,0:1,0:2"},
+		{name: "every replica throttled", previous: "*", entries: "0:1", want: "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeThrottledReplicas(tt.previous, tt.entries); got != tt.want {
+				t.Fatalf("mergeThrottledReplicas() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplicationThrottlesRestorePreviousConfigs(t *testing.T) {
+	brokers := []*sarama.MockBroker{sarama.NewMockBroker(t, 1), sarama.NewMockBroker(t, 2)}
+	// broker 1 and the topic carry throttles of their own, broker 2 has none
+	describe := &sarama.DescribeConfigsResponse{Version: 2, Resources: []*sarama.ResourceResponse{
+		{Type: sarama.BrokerResource, Name: "1", Configs: []*sarama.ConfigEntry{
+			{Name: leaderThrottleRate, Value: "500", Source: sarama.SourceDynamicBroker},
+		}},
+		{Type: sarama.BrokerResource, Name: "2"},
+		{Type: sarama.TopicResource, Name: "orders", Configs: []*sarama.ConfigEntry{
+			{Name: leaderThrottledReplicas, Value: "1:1", Source: sarama.SourceTopic},
+			{Name: followerThrottledReplicas, Value: "", Source: sarama.SourceDefault},
+		}},
+	}}
+	for _, broker := range brokers {
+		defer broker.Close()
+		metadata := sarama.NewMockMetadataResponse(t).SetController(1)
+		for _, b := range brokers {
+			metadata.SetBroker(b.Addr(), b.BrokerID())
+		}
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest":                metadata,
+			"DescribeConfigsRequest":         sarama.NewMockWrapper(describe),
+			"IncrementalAlterConfigsRequest": sarama.NewMockIncrementalAlterConfigsResponse(t),
+		})
+	}
+
+	config := sarama.NewConfig()
+	config.ApiVersionsRequest = false
+	// the mock IncrementalAlterConfigs response fails to decode for version 1 requests
+	config.Version = sarama.V2_3_0_0
+	admin, err := sarama.NewClusterAdmin([]string{brokers[0].Addr()}, config)
+	if err != nil {
+		t.Fatalf("NewClusterAdmin() error = %v", err)
+	}
+	defer admin.Close()
+	db, err := database.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init test database: %v", err)
+	}
+	repo := repository.NewReplicationThrottleRepository(db)
+
+	// altered returns the config entries sent for every broker and topic since the last call
+	seen := make([]int, len(brokers))
+	altered := func() map[string]map[string]sarama.IncrementalAlterConfigsEntry {
+		got := make(map[string]map[string]sarama.IncrementalAlterConfigsEntry)
+		for i, broker := range brokers {
+			history := broker.History()
+			for _, rr := range history[seen[i]:] {
+				if req, ok := rr.Request.(*sarama.IncrementalAlterConfigsRequest); ok {
+					for _, resource := range req.Resources {
+						got[resource.Name] = resource.ConfigEntries
+					}
+				}
+			}
+			seen[i] = len(history)
+		}
+		return got
+	}
+	value := func(entry sarama.IncrementalAlterConfigsEntry) string {
+		if entry.Operation == sarama.IncrementalAlterConfigsOperationDelete {
+			return "<deleted>"
+		}
+		return *entry.Value
+	}
+
+	throttles := newReplicationThrottles([]dto.PartitionReassignment{
+		newPartitionReassignment("orders", 0, []int32{1}, []int32{1, 2}),
+	}, 1000)
+	if err := throttles.apply(admin, repo, 7); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	saved, err := repo.FindAll()
+	if err != nil || len(saved) != 6 {
+		t.Fatalf("saved throttles = %+v (%v), want 6 configs", saved, err)
+	}
+	got := altered()
+	if value(got["1"][leaderThrottleRate]) != "1000" || value(got["2"][followerThrottleRate]) != "1000" {
+		t.Fatalf("broker throttles = %v, want a rate of 1000", got)
+	}
+	if value(got["orders"][leaderThrottledReplicas]) != "1:1,0:1" || value(got["orders"][followerThrottledReplicas]) != "0:2" {
+		t.Fatalf("topic throttles = %v, want leaders 1:1,0:1 and followers 0:2", got["orders"])
+	}
+
+	throttles.remove(admin, repo)
+	want := map[string]map[string]string{
+		"1":      {leaderThrottleRate: "500", followerThrottleRate: "<deleted>"},
+		"2":      {leaderThrottleRate: "<deleted>", followerThrottleRate: "<deleted>"},
+		"orders": {leaderThrottledReplicas: "1:1", followerThrottledReplicas: "<deleted>"},
+	}
+	got = altered()
+	for resource, configs := range want {
+		for name, v := range configs {
+			if value(got[resource][name]) != v {
+				t.Fatalf("restored %s of %s = %q, want %q", name, resource, value(got[resource][name]), v)
+			}
+		}
+	}
+	if saved, err := repo.FindAll(); err != nil || len(saved) != 0 {
+		t.Fatalf("saved throttles after remove = %+v (%v), want none", saved, err)
+	}
+}
+
+func TestRestoreInterruptedThrottlesOfRemovedCluster(t *testing.T) {
+	db, err := database.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init test database: %v", err)
+	}
+	throttleRepo := repository.NewReplicationThrottleRepository(db)
+	previous := "500"
+	if err := throttleRepo.Create([]model.ReplicationThrottle{
+		{ClusterID: 9, ResourceType: int8(sarama.BrokerResource), ResourceName: "1", Name: leaderThrottleRate, Previous: &previous},
+		{ClusterID: 9, ResourceType: int8(sarama.TopicResource), ResourceName: "orders", Name: leaderThrottledReplicas},
+	}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	s := NewReassignmentService(repository.NewClusterRepository(db), throttleRepo, util.NewKafkaClientManager())
+	if err := s.RestoreInterruptedThrottles(); err != nil {
+		t.Fatalf("RestoreInterruptedThrottles() error = %v", err)
+	}
+	if saved, err := throttleRepo.FindAll(); err != nil || len(saved) != 0 {
+		t.Fatalf("saved throttles = %+v (%v), want none left for a removed cluster", saved, err)
+	}
+}
+
+func TestReassignmentWait(t *testing.T) {
+	started := &reassignmentRun{done: make(chan struct{}), status: dto.ReassignmentStatus{Status: dto.ReassignmentRunning}}
+	reassignment := &Reassignment{run: started}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := reassignment.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() on a running reassignment error = %v, want DeadlineExceeded", err)
+	}
+
+	go func() {
+		started.mu.Lock()
+		started.status.Status = dto.ReassignmentCompleted
+		started.mu.Unlock()
+		started.finish()
+	}()
+	status, err := reassignment.Wait(context.Background())
+	if err != nil || status.Status != dto.ReassignmentCompleted {
+		t.Fatalf("Wait() = %+v, %v, want the completed run", status, err)
+	}
+}
+
+func TestReassignmentReserve(t *testing.T) {
+	s := NewReassignmentService(nil, nil, nil)
+	s.runs[1] = &reassignmentRun{done: make(chan struct{}), status: dto.ReassignmentStatus{ClusterID: 1, Status: dto.ReassignmentCompleted}}
+
+	if _, err := s.reserve(1); err != nil {
+		t.Fatalf("reserve() error = %v", err)
+	}
+	// a reserved cluster keeps other starts and cancellations off it, not readers
+	if _, err := s.CancelReassignment(1); !errors.Is(err, ErrReassignmentBusy) {
+		t.Fatalf("CancelReassignment() error = %v, want ErrReassignmentBusy", err)
+	}
+	if status, err := s.GetReassignment(1); err != nil || status.Status != dto.ReassignmentCompleted {
+		t.Fatalf("GetReassignment() = %+v, %v, want the last run", status, err)
+	}
+	if _, err := s.reserve(2); err != nil {
+		t.Fatalf("reserve() of another cluster error = %v", err)
+	}
+
+	s.unreserve(1)
+	if _, err := s.CancelReassignment(1); !errors.Is(err, ErrNoReassignment) {
+		t.Fatalf("CancelReassignment() error = %v, want ErrNoReassignment once released", err)
+	}
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+const (
+	// fetchMaxBytes is the initial fetch size when reading batches directly. It is
+	// doubled up to fetchMaxBytesLimit when a batch does not fit.
+	fetchMaxBytes      = 1 << 20
+	fetchMaxBytesLimit = 64 << 20
+	fetchMaxWaitMs     = 500
+)
+
+// ErrMessageNotFound is returned when no record exists at the requested position,
+// because it is out of range or was removed by compaction or retention.
+var ErrMessageNotFound = errors.New("message not found")
+
+// batchRecord is a fetched record together with the batch it was written in.
+type batchRecord struct {
+	message       *sarama.ConsumerMessage
+	timestampType string
+	batch         *dto.RecordBatch
+}
+
+// fetchBatches reads a partition from its leader starting at offset. Unlike the
+// consumer it keeps the record batches, so their metadata can be reported.
+func fetchBatches(client sarama.Client, topicName string, partition int32, offset int64, maxBytes int32, isolation sarama.IsolationLevel) (*sarama.FetchResponseBlock, error) {
+	broker, err := client.Leader(topicName, partition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find leader of partition %d: %w", partition, err)
+	}
+
+	request := &sarama.FetchRequest{
+		Version:     11,
+		MaxWaitTime: fetchMaxWaitMs,
+		MinBytes:    1,
+		MaxBytes:    maxBytes,
+		Isolation:   isolation,
+	}
+	request.AddBlock(topicName, partition, offset, maxBytes, -1)
+
+	response, err := broker.Fetch(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch partition %d: %w", partition, err)
+	}
+	block := response.GetBlock(topicName, partition)
+	if block == nil {
+		return nil, fmt.Errorf("fetch response has no data for partition %d", partition)
+	}
+	if block.Err != sarama.ErrNoError {
+		return block, block.Err
+	}
+	return block, nil
+}
+
+// batchRecords flattens fetched batches into records in offset order.
+func batchRecords(topicName string, partition int32, block *sarama.FetchResponseBlock) []batchRecord {
+	var records []batchRecord
+	for _, set := range block.RecordsSet {
+		if set == nil {
+			continue
+		}
+		if batch := set.RecordBatch; batch != nil {
+			info := &dto.RecordBatch{
+				BaseOffset:           batch.FirstOffset,
+				LastOffset:           batch.LastOffset(),
+				RecordCount:          len(batch.Records),
+				Magic:                batch.Version,
+				PartitionLeaderEpoch: batch.PartitionLeaderEpoch,
+				ProducerID:           batch.ProducerID,
+				ProducerEpoch:        batch.ProducerEpoch,
+				BaseSequence:         batch.FirstSequence,
+				Transactional:        batch.IsTransactional,
+				Control:              batch.Control,
+				Compression:          batch.Codec.String(),
+				FirstTimestamp:       batch.FirstTimestamp.UnixMilli(),
+				MaxTimestamp:         batch.MaxTimestamp.UnixMilli(),
+			}
+			timestampType := dto.TimestampCreateTime
+			if batch.LogAppendTime {
+				timestampType = dto.TimestampLogAppendTime
+			}
+			for _, record := range batch.Records {
+				timestamp := batch.FirstTimestamp.Add(record.TimestampDelta)
+				if batch.LogAppendTime {
+					timestamp = batch.MaxTimestamp
+				}
+				records = append(records, batchRecord{
+					message: &sarama.ConsumerMessage{
+						Topic:          topicName,
+						Partition:      partition,
+						Offset:         batch.FirstOffset + record.OffsetDelta,
+						Key:            record.Key,
+						Value:          record.Value,
+						Headers:        record.Headers,
+						Timestamp:      timestamp,
+						BlockTimestamp: batch.MaxTimestamp,
+					},
+					timestampType: timestampType,
+					batch:         info,
+				})
+			}
+		}
+		if set.MsgSet != nil {
+			records = append(records, legacyRecords(topicName, partition, set.MsgSet)...)
+		}
+	}
+	return records
+}
+
+// legacyRecords reads a message set written with magic 0 or 1, where a compressed
+// wrapper message holds the inner messages.
+func legacyRecords(topicName string, partition int32, set *sarama.MessageSet) []batchRecord {
+	var records []batchRecord
+	for _, block := range set.Messages {
+		if block == nil || block.Msg == nil {
+			continue
+		}
+		wrapper := block.Msg
+		inner := []*sarama.MessageBlock{block}
+		if wrapper.Set != nil {
+			inner = wrapper.Set.Messages
+		}
+		if len(inner) == 0 {
+			continue
+		}
+
+		info := &dto.RecordBatch{
+			LastOffset:     block.Offset,
+			RecordCount:    len(inner),
+			Magic:          wrapper.Version,
+			ProducerID:     -1,
+			ProducerEpoch:  -1,
+			BaseSequence:   -1,
+			Compression:    wrapper.Codec.String(),
+			FirstTimestamp: wrapper.Timestamp.UnixMilli(),
+			MaxTimestamp:   wrapper.Timestamp.UnixMilli(),
+		}
+		timestampType := dto.TimestampCreateTime
+		if wrapper.LogAppendTime {
+			timestampType = dto.TimestampLogAppendTime
+		}
+
+		// magic 1 wrappers carry the offset of their last inner message and relative inner offsets
+		base := int64(0)
+		if wrapper.Set != nil && wrapper.Version >= 1 {
+			base = block.Offset - inner[len(inner)-1].Offset
+		}
+		info.BaseOffset = base + inner[0].Offset
+		for _, message := range inner {
+			if message == nil || message.Msg == nil {
+				continue
+			}
+			timestamp := message.Msg.Timestamp
+			if wrapper.LogAppendTime {
+				timestamp = wrapper.Timestamp
+			}
+			records = append(records, batchRecord{
+				message: &sarama.ConsumerMessage{
+					Topic:          topicName,
+					Partition:      partition,
+					Offset:         base + message.Offset,
+					Key:            message.Msg.Key,
+					Value:          message.Msg.Value,
+					Timestamp:      timestamp,
+					BlockTimestamp: wrapper.Timestamp,
+				},
+				timestampType: timestampType,
+				batch:         info,
+			})
+		}
+	}
+	return records
+}
+
+// isolationLevel maps the read_committed toggle of a query to a fetch isolation level.
+func isolationLevel(readCommitted bool) sarama.IsolationLevel {
+	if readCommitted {
+		return sarama.ReadCommitted
+	}
+	return sarama.ReadUncommitted
+}
+
+// endOffset returns the end offset of a partition as seen with an isolation level: the high
+// watermark for read_uncommitted and the last stable offset for read_committed, which stays
+// behind records of open transactions.
+func endOffset(client sarama.Client, topicName string, partition int32, isolation sarama.IsolationLevel) (int64, error) {
+	if isolation == sarama.ReadUncommitted {
+		return client.GetOffset(topicName, partition, sarama.OffsetNewest)
+	}
+
+	broker, err := client.Leader(topicName, partition)
+	if err != nil {
+		return -1, err
+	}
+	request := sarama.NewOffsetRequest(client.Config().Version)
+	if request.Version < 2 {
+		// version 2 added the isolation level
+		request.Version = 2
+	}
+	request.IsolationLevel = isolation
+	request.AddBlock(topicName, partition, sarama.OffsetNewest, 1)
+
+	response, err := broker.GetAvailableOffsets(request)
+	if err != nil {
+		return -1, err
+	}
+	block := response.GetBlock(topicName, partition)
+	if block == nil {
+		return -1, sarama.ErrIncompleteResponse
+	}
+	if block.Err != sarama.ErrNoError {
+		return -1, block.Err
+	}
+	if len(block.Offsets) != 1 {
+		return -1, sarama.ErrOffsetOutOfRange
+	}
+	return block.Offsets[0], nil
+}
+
+// dropAborted removes the records of aborted transactions, as a read_committed consumer
+// does. A producer is aborted from the first offset of its aborted transaction up to its
+// next control batch, the abort marker.
+func dropAborted(records []batchRecord, aborted []*sarama.AbortedTransaction) []batchRecord {
+	if len(aborted) == 0 {
+		return records
+	}
+	pending := make([]*sarama.AbortedTransaction, 0, len(aborted))
+	for _, txn := range aborted {
+		if txn != nil {
+			pending = append(pending, txn)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].FirstOffset < pending[j].FirstOffset })
+
+	abortedProducers := make(map[int64]bool)
+	kept := records[:0]
+	var current *dto.RecordBatch
+	for _, record := range records {
+		batch := record.batch
+		if batch != current {
+			current = batch
+			for len(pending) > 0 && pending[0].FirstOffset <= batch.LastOffset {
+				abortedProducers[pending[0].ProducerID] = true
+				pending = pending[1:]
+			}
+			if batch.Control && abortedProducers[batch.ProducerID] {
+				delete(abortedProducers, batch.ProducerID)
+				// the abort marker itself stays visible
+				kept = append(kept, record)
+				continue
+			}
+		}
+		if batch.Transactional && !batch.Control && abortedProducers[batch.ProducerID] {
+			continue
+		}
+		kept = append(kept, record)
+	}
+	return kept
+}
+
+// fetchRecords reads the records of the first batches at or after offset. A batch larger
+// than the fetch size is retried with a doubled size until it fits.
+func fetchRecords(client sarama.Client, topicName string, partition int32, offset int64, isolation sarama.IsolationLevel) ([]batchRecord, error) {
+	for maxBytes := int32(fetchMaxBytes); ; maxBytes *= 2 {
+		block, err := fetchBatches(client, topicName, partition, offset, maxBytes, isolation)
+		if errors.Is(err, sarama.ErrOffsetOutOfRange) {
+			return nil, ErrMessageNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		records := batchRecords(topicName, partition, block)
+		if isolation == sarama.ReadCommitted {
+			records = dropAborted(records, block.AbortedTransactions)
+		}
+		if len(records) > 0 || !block.Partial || maxBytes >= fetchMaxBytesLimit {
+			return records, nil
+		}
+	}
+}
+
+// fetchRecord reads the record at offset with its batch metadata.
+func fetchRecord(client sarama.Client, topicName string, partition int32, offset int64, isolation sarama.IsolationLevel) (*batchRecord, error) {
+	records, err := fetchRecords(client, topicName, partition, offset, isolation)
+	if err != nil {
+		return nil, err
+	}
+	// the first batch holds the offset if it still exists, a later offset means it was
+	// compacted away or belongs to an aborted transaction
+	for i := range records {
+		if records[i].message.Offset == offset {
+			return &records[i], nil
+		}
+	}
+	return nil, ErrMessageNotFound
+}
+
+// attachBatches sets the timestamp type and batch metadata of records read through the
+// consumer, which drops them. Each fetch covers the batches following the first record
+// still missing its metadata, so a page mostly costs one fetch per partition.
+func attachBatches(client sarama.Client, topicName string, records []dto.MessageRecord, isolation sarama.IsolationLevel) error {
+	byPartition := make(map[int32][]int)
+	for i := range records {
+		byPartition[records[i].Partition] = append(byPartition[records[i].Partition], i)
+	}
+
+	for partition, indexes := range byPartition {
+		sort.Slice(indexes, func(i, j int) bool { return records[indexes[i]].Offset < records[indexes[j]].Offset })
+		for next := 0; next < len(indexes); {
+			fetched, err := fetchRecords(client, topicName, partition, records[indexes[next]].Offset, isolation)
+			if errors.Is(err, ErrMessageNotFound) {
+				// removed by retention since the page was read
+				next++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			found := make(map[int64]*batchRecord, len(fetched))
+			last := records[indexes[next]].Offset
+			for i := range fetched {
+				found[fetched[i].message.Offset] = &fetched[i]
+				if offset := fetched[i].message.Offset; offset > last {
+					last = offset
+				}
+			}
+			for next < len(indexes) && records[indexes[next]].Offset <= last {
+				record := &records[indexes[next]]
+				if match, ok := found[record.Offset]; ok {
+					record.TimestampType = match.timestampType
+					record.Batch = match.batch
+				}
+				next++
+			}
+		}
+	}
+	return nil
+}
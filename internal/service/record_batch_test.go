@@ -0,0 +1,180 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+func TestBatchRecordsKeepBatchMetadata(t *testing.T) {
+	first := time.UnixMilli(1_700_000_000_000)
+	block := &sarama.FetchResponseBlock{RecordsSet: []*sarama.Records{
+		{RecordBatch: &sarama.RecordBatch{
+			FirstOffset:     10,
+			Version:         2,
+			Codec:           sarama.CompressionZSTD,
+			LastOffsetDelta: 3,
+			FirstTimestamp:  first,
+			MaxTimestamp:    first.Add(time.Second),
+			ProducerID:      4001,
+			ProducerEpoch:   2,
+			FirstSequence:   17,
+			IsTransactional: true,
+			Records: []*sarama.Record{
+				{OffsetDelta: 0, Key: []byte("a"), Value: []byte("one")},
+				// offsets 11 and 12 were compacted away
+				{OffsetDelta: 3, TimestampDelta: time.Second, Value: []byte("two"), Headers: []*sarama.RecordHeader{{Key: []byte("trace"), Value: []byte("x")}}},
+			},
+		}},
+		{RecordBatch: &sarama.RecordBatch{
+			FirstOffset:    14,
+			Version:        2,
+			LogAppendTime:  true,
+			FirstTimestamp: first,
+			MaxTimestamp:   first.Add(time.Minute),
+			ProducerID:     -1,
+			ProducerEpoch:  -1,
+			FirstSequence:  -1,
+			Records:        []*sarama.Record{{TimestampDelta: time.Millisecond, Value: []byte("three")}},
+		}},
+	}}
+
+	records := batchRecords("orders", 2, block)
+	if len(records) != 3 {
+		t.Fatalf("len(records) = %d, want 3", len(records))
+	}
+
+	wantOffsets := []int64{10, 13, 14}
+	for i, record := range records {
+		if record.message.Offset != wantOffsets[i] || record.message.Partition != 2 || record.message.Topic != "orders" {
+			t.Fatalf("records[%d] at %s/%d@%d, want orders/2@%d", i, record.message.Topic, record.message.Partition, record.message.Offset, wantOffsets[i])
+		}
+	}
+
+	second := records[1]
+	if !second.message.Timestamp.Equal(first.Add(time.Second)) || second.timestampType != dto.TimestampCreateTime {
+		t.Fatalf("second record timestamp = %v (%s), want create time %v", second.message.Timestamp, second.timestampType, first.Add(time.Second))
+	}
+	if len(second.message.Headers) != 1 || string(second.message.Headers[0].Key) != "trace" {
+		t.Fatalf("second record headers = %v, want trace", second.message.Headers)
+	}
+	wantBatch := dto.RecordBatch{
+		BaseOffset:     10,
+		LastOffset:     13,
+		RecordCount:    2,
+		Magic:          2,
+		ProducerID:     4001,
+		ProducerEpoch:  2,
+		BaseSequence:   17,
+		Transactional:  true,
+		Compression:    "zstd",
+		FirstTimestamp: first.UnixMilli(),
+		MaxTimestamp:   first.Add(time.Second).UnixMilli(),
+	}
+	if *second.batch != wantBatch {
+		t.Fatalf("batch = %+v, want %+v", *second.batch, wantBatch)
+	}
+
+	third := records[2]
+	if !third.message.Timestamp.Equal(first.Add(time.Minute)) || third.timestampType != dto.TimestampLogAppendTime {
+		t.Fatalf("third record timestamp = %v (%s), want log append time %v", third.message.Timestamp, third.timestampType, first.Add(time.Minute))
+	}
+	if third.batch.ProducerID != -1 || third.batch.Compression != "none" {
+		t.Fatalf("third batch = %+v, want no producer and no compression", *third.batch)
+	}
+}
+
+func TestLegacyRecordsResolveRelativeOffsets(t *testing.T) {
+	timestamp := time.UnixMilli(1_600_000_000_000)
+	set := &sarama.MessageSet{Messages: []*sarama.MessageBlock{
+		{Offset: 5, Msg: &sarama.Message{Version: 1, Timestamp: timestamp, Value: []byte("plain")}},
+		{Offset: 8, Msg: &sarama.Message{Version: 1, Codec: sarama.CompressionGZIP, Timestamp: timestamp, Set: &sarama.MessageSet{Messages: []*sarama.MessageBlock{
+			{Offset: 0, Msg: &sarama.Message{Version: 1, Timestamp: timestamp, Value: []byte("a")}},
+			{Offset: 1, Msg: &sarama.Message{Version: 1, Timestamp: timestamp, Value: []byte("b")}},
+		}}}},
+	}}
+
+	records := legacyRecords("orders", 0, set)
+	wantOffsets := []int64{5, 7, 8}
+	if len(records) != len(wantOffsets) {
+		t.Fatalf("len(records) = %d, want %d", len(records), len(wantOffsets))
+	}
+	for i, record := range records {
+		if record.message.Offset != wantOffsets[i] {
+			t.Fatalf("records[%d].Offset = %d, want %d", i, record.message.Offset, wantOffsets[i])
+		}
+	}
+	if batch := records[1].batch; batch.BaseOffset != 7 || batch.LastOffset != 8 || batch.Compression != "gzip" || batch.Magic != 1 {
+		t.Fatalf("wrapper batch = %+v, want offsets 7-8 compressed with gzip", *batch)
+	}
+}
+
+func TestDropAbortedSkipsAbortedTransactions(t *testing.T) {
+	response := &sarama.FetchResponse{Version: 11}
+	response.AddRecordBatch("orders", 0, nil, sarama.StringEncoder("aborted"), 0, 7, true)
+	response.AddRecordBatch("orders", 0, nil, sarama.StringEncoder("other producer"), 1, 8, true)
+	response.AddRecordBatch("orders", 0, nil, sarama.StringEncoder("aborted"), 2, 7, true)
+	response.AddControlRecord("orders", 0, 3, 7, sarama.ControlRecordAbort)
+	response.AddRecordBatch("orders", 0, nil, sarama.StringEncoder("next transaction"), 4, 7, true)
+	block := response.GetBlock("orders", 0)
+	block.AbortedTransactions = []*sarama.AbortedTransaction{{ProducerID: 7, FirstOffset: 0}}
+
+	records := dropAborted(batchRecords("orders", 0, block), block.AbortedTransactions)
+
+	var offsets []int64
+	for _, record := range records {
+		offsets = append(offsets, record.message.Offset)
+	}
+	want := []int64{1, 3, 4}
+	if len(offsets) != len(want) {
+		t.Fatalf("kept offsets %v, want %v", offsets, want)
+	}
+	for i := range want {
+		if offsets[i] != want[i] {
+			t.Fatalf("kept offsets %v, want %v", offsets, want)
+		}
+	}
+	if !records[1].batch.Control {
+		t.Fatalf("record at offset 3 = %+v, want the abort marker", records[1].batch)
+	}
+}
+
+func TestAttachBatches(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()),
+		// every fetch returns at most two records, so the page needs two fetches
+		"FetchRequest": sarama.NewMockFetchResponse(t, 2).
+			SetMessage("orders", 0, 5, sarama.StringEncoder("a")).
+			SetMessage("orders", 0, 6, sarama.StringEncoder("b")).
+			SetMessage("orders", 0, 7, sarama.StringEncoder("c")),
+	})
+
+	config := sarama.NewConfig()
+	config.ApiVersionsRequest = false
+	config.Version = sarama.V3_4_0_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+
+	records := []dto.MessageRecord{{Partition: 0, Offset: 7}, {Partition: 0, Offset: 5}, {Partition: 0, Offset: 6}}
+	if err := attachBatches(client, "orders", records, sarama.ReadUncommitted); err != nil {
+		t.Fatalf("attachBatches() error = %v", err)
+	}
+
+	for _, record := range records {
+		if record.Batch == nil || record.TimestampType == "" {
+			t.Fatalf("record at offset %d has no batch metadata", record.Offset)
+		}
+		if record.Batch.BaseOffset > record.Offset || record.Batch.LastOffset < record.Offset {
+			t.Fatalf("record at offset %d got batch %d-%d", record.Offset, record.Batch.BaseOffset, record.Batch.LastOffset)
+		}
+	}
+}
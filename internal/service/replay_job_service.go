@@ -0,0 +1,600 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"github.com/bingfengfeifei/kafka-map-go/internal/repository"
+	"github.com/bingfengfeifei/kafka-map-go/internal/util"
+)
+
+const (
+	// replayBatchSize bounds the records produced in one call.
+	replayBatchSize = 500
+	// replayIdleTimeout ends a partition copy that stopped receiving records before its end offset.
+	replayIdleTimeout = 10 * time.Second
+	replayFlushPeriod = time.Second
+)
+
+// ReplayJobService copies records between topics, on the same or on different clusters,
+// in the background. Both sides connect with the settings of their registered cluster.
+type ReplayJobService struct {
+	clusterRepo  *repository.ClusterRepository
+	replayRepo   *repository.ReplayJobRepository
+	kafkaManager *util.KafkaClientManager
+	topicService *TopicService
+	running      map[uint]*replayRun
+	mu           sync.Mutex
+}
+
+func NewReplayJobService(clusterRepo *repository.ClusterRepository, replayRepo *repository.ReplayJobRepository, kafkaManager *util.KafkaClientManager, topicService *TopicService) *ReplayJobService {
+	return &ReplayJobService{
+		clusterRepo:  clusterRepo,
+		replayRepo:   replayRepo,
+		kafkaManager: kafkaManager,
+		topicService: topicService,
+		running:      make(map[uint]*replayRun),
+	}
+}
+
+// replayRun is the in-memory state of a running job.
+type replayRun struct {
+	cancel    context.CancelFunc
+	cancelled bool
+	progress  []dto.ReplayPartitionProgress
+	mu        sync.Mutex
+}
+
+// advance moves a partition to the next offset to copy and adds the records copied and skipped on the way.
+func (r *replayRun) advance(index int, next, copied, skipped int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.progress[index].CurrentOffset = next
+	r.progress[index].Copied += copied
+	r.progress[index].Skipped += skipped
+}
+
+func (r *replayRun) snapshot() ([]dto.ReplayPartitionProgress, int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var copied int64
+	for _, partition := range r.progress {
+		copied += partition.Copied
+	}
+	return append([]dto.ReplayPartitionProgress(nil), r.progress...), copied
+}
+
+// replayQuery turns the range and filters of a replay request into a message query.
+func replayQuery(req *dto.ReplayJobRequest) dto.MessageQuery {
+	query := dto.MessageQuery{
+		Offset:      sarama.OffsetOldest,
+		From:        req.From,
+		To:          req.To,
+		KeyFilter:   req.KeyFilter,
+		ValueFilter: req.ValueFilter,
+		JSONKey:     req.JSONKey,
+		JSONValue:   req.JSONValue,
+		Filter:      req.Filter,
+		JSONPath:    req.JSONPath,
+		JSONPathAny: strings.EqualFold(strings.TrimSpace(req.JSONPathMode), "or"),
+		Encoding:    req.Encoding,
+	}
+	if req.StartOffset != nil {
+		query.Offset = *req.StartOffset
+	}
+	if req.EndOffset != nil {
+		query.Until = *req.EndOffset
+	}
+	return query
+}
+
+// ValidateReplayJob checks a replay request before anything is read or written.
+func ValidateReplayJob(req *dto.ReplayJobRequest) error {
+	req.TargetTopic = strings.TrimSpace(req.TargetTopic)
+	if req.TargetTopic == "" {
+		return errors.New("target topic is required")
+	}
+	switch req.PartitionStrategy {
+	case "":
+		req.PartitionStrategy = dto.ReplayPartitionKey
+	case dto.ReplayPartitionPreserve, dto.ReplayPartitionKey, dto.ReplayPartitionRoundRobin:
+	default:
+		return fmt.Errorf("invalid partition strategy %q, expected preserve, key or round-robin", req.PartitionStrategy)
+	}
+	if req.Rate < 0 {
+		return errors.New("rate must not be negative")
+	}
+	if _, err := parseCompression(req.Compression); err != nil {
+		return err
+	}
+	if req.StartOffset != nil && *req.StartOffset < 0 {
+		return errors.New("startOffset must not be negative")
+	}
+	if req.EndOffset != nil {
+		start := int64(0)
+		if req.StartOffset != nil {
+			start = *req.StartOffset
+		}
+		if *req.EndOffset <= start {
+			return errors.New("endOffset must be greater than startOffset")
+		}
+	}
+	if req.From > 0 && req.To > 0 && req.From > req.To {
+		return errors.New("from must not be after to")
+	}
+	if _, err := ParseJSONPathFilter(req.JSONPath, req.JSONPathMode); err != nil {
+		return err
+	}
+	_, err := newRecordMatcher(replayQuery(req))
+	return err
+}
+
+// replayRouter picks the target partition of each copied record.
+type replayRouter struct {
+	strategy   string
+	partitions int32 // partitions of the target topic
+	next       int32
+}
+
+// route returns the producer metadata that places msg on its target partition. Nil leaves
+// the choice to the key hash of the explicit partitioner.
+func (r *replayRouter) route(msg *sarama.ConsumerMessage) interface{} {
+	switch r.strategy {
+	case dto.ReplayPartitionPreserve:
+		return targetPartition(msg.Partition)
+	case dto.ReplayPartitionRoundRobin:
+		partition := r.next
+		r.next = (r.next + 1) % r.partitions
+		return targetPartition(partition)
+	default:
+		return nil
+	}
+}
+
+// replayCopier copies partition ranges of the source topic to the target topic.
+type replayCopier struct {
+	topic           string
+	targetTopic     string
+	producer        sarama.SyncProducer
+	decoder         *messageDecoder
+	matcher         *recordMatcher
+	query           dto.MessageQuery
+	router          *replayRouter
+	resetTimestamps bool
+	rate            int
+	started         time.Time
+	submitted       int64
+	run             *replayRun
+}
+
+// keep reports whether a record passes the time range and filters of the job.
+func (c *replayCopier) keep(msg *sarama.ConsumerMessage) bool {
+	if !inTimeRange(msg.Timestamp.UnixMilli(), c.query.From, c.query.To) {
+		return false
+	}
+	if c.matcher.empty() {
+		return true
+	}
+	record := c.decoder.record(msg)
+	return c.matcher.match(msg, &record)
+}
+
+func (c *replayCopier) message(msg *sarama.ConsumerMessage) *sarama.ProducerMessage {
+	copied := &sarama.ProducerMessage{
+		Topic:    c.targetTopic,
+		Metadata: c.router.route(msg),
+	}
+	// byte encoders keep a null key or value apart from an empty one
+	if msg.Key != nil {
+		copied.Key = sarama.ByteEncoder(msg.Key)
+	}
+	if msg.Value != nil {
+		copied.Value = sarama.ByteEncoder(msg.Value)
+	}
+	if !c.resetTimestamps && !msg.Timestamp.IsZero() {
+		copied.Timestamp = msg.Timestamp
+	}
+	for _, header := range msg.Headers {
+		if header != nil {
+			copied.Headers = append(copied.Headers, sarama.RecordHeader{Key: header.Key, Value: header.Value})
+		}
+	}
+	return copied
+}
+
+// throttle waits until a batch of n records fits in the rate limit of the job.
+func (c *replayCopier) throttle(ctx context.Context, n int) error {
+	if c.rate > 0 {
+		due := c.started.Add(time.Duration(c.submitted) * time.Second / time.Duration(c.rate))
+		if wait := time.Until(due); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	c.submitted += int64(n)
+	return nil
+}
+
+// copyRange copies the kept records of a partition range in batches. Progress moves after
+// every produced batch, so the current offset never passes a record that was not written.
+func (c *replayCopier) copyRange(ctx context.Context, consumer sarama.Consumer, remaining remainingCheck, index int, rng partitionRange) error {
+	batchSize := replayBatchSize
+	if c.rate > 0 && c.rate < batchSize {
+		batchSize = c.rate
+	}
+
+	batch := make([]*sarama.ProducerMessage, 0, batchSize)
+	offsets := make([]int64, 0, batchSize) // source offsets of the batch, for errors
+	next := rng.start
+	var skipped int64
+	flush := func() error {
+		if len(batch) > 0 {
+			if err := c.throttle(ctx, len(batch)); err != nil {
+				return err
+			}
+			result, err := sendMessages(c.producer, batch)
+			if err != nil {
+				return err
+			}
+			for i, record := range result.Records {
+				if record.Error != "" {
+					return fmt.Errorf("record at offset %d of partition %d: %s", offsets[i], rng.partition, record.Error)
+				}
+			}
+		}
+		c.run.advance(index, next, int64(len(batch)), skipped)
+		batch = batch[:0]
+		offsets = offsets[:0]
+		skipped = 0
+		return nil
+	}
+
+	var flushErr error
+	exhausted, err := scanRange(ctx, consumer, remaining, c.topic, rng, replayIdleTimeout, func(msg *sarama.ConsumerMessage) bool {
+		next = msg.Offset + 1
+		if !c.keep(msg) {
+			skipped++
+			return true
+		}
+		batch = append(batch, c.message(msg))
+		offsets = append(offsets, msg.Offset)
+		if len(batch) >= batchSize {
+			flushErr = flush()
+		}
+		return flushErr == nil
+	})
+	if err == nil {
+		err = flushErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	if exhausted {
+		next = rng.stop
+	}
+	return flush()
+}
+
+// FailInterruptedJobs marks jobs that were running when the server stopped as failed.
+func (s *ReplayJobService) FailInterruptedJobs() error {
+	return s.replayRepo.FailRunning(dto.ReplayJobRunning, dto.ReplayJobFailed, "interrupted by server restart")
+}
+
+// StartReplay creates a replay job from a topic and starts copying in the background.
+// The target topic must exist; the source offsets are fixed when the job starts.
+func (s *ReplayJobService) StartReplay(clusterID uint, topicName string, req *dto.ReplayJobRequest) (*dto.ReplayJobInfo, error) {
+	if err := ValidateReplayJob(req); err != nil {
+		return nil, err
+	}
+	targetClusterID := req.TargetClusterID
+	if targetClusterID == 0 {
+		targetClusterID = clusterID
+	}
+	if targetClusterID == clusterID && req.TargetTopic == topicName {
+		return nil, errors.New("target topic must differ from the source topic")
+	}
+
+	query := replayQuery(req)
+	matcher, err := newRecordMatcher(query)
+	if err != nil {
+		return nil, err
+	}
+	codec, _ := parseCompression(req.Compression)
+
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	targetCluster, err := s.clusterRepo.FindByID(targetClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("target cluster %d not found", targetClusterID)
+	}
+	decoder, err := s.topicService.topicDecoder(cluster, topicName, req.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	targetPartitions, err := s.targetPartitions(targetCluster, req.TargetTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.kafkaManager.CreateClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := searchRanges(client, topicName, req.Partitions, query)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	if req.PartitionStrategy == dto.ReplayPartitionPreserve {
+		for _, rng := range ranges {
+			if rng.partition >= targetPartitions {
+				client.Close()
+				return nil, fmt.Errorf("target topic %s has %d partitions, preserving partitions needs partition %d", req.TargetTopic, targetPartitions, rng.partition)
+			}
+		}
+	}
+
+	producer, err := s.topicService.createRecordProducer(targetCluster, codec)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	progress := make([]dto.ReplayPartitionProgress, len(ranges))
+	for i, rng := range ranges {
+		progress[i] = dto.ReplayPartitionProgress{
+			Partition:     rng.partition,
+			StartOffset:   rng.start,
+			EndOffset:     rng.stop,
+			CurrentOffset: rng.start,
+		}
+	}
+
+	request, _ := json.Marshal(req)
+	progressJSON, _ := json.Marshal(progress)
+	job := &model.ReplayJob{
+		ClusterID:       clusterID,
+		Topic:           topicName,
+		TargetClusterID: targetClusterID,
+		TargetTopic:     req.TargetTopic,
+		Status:          dto.ReplayJobRunning,
+		Request:         string(request),
+		Progress:        string(progressJSON),
+	}
+	if err := s.replayRepo.Create(job); err != nil {
+		client.Close()
+		producer.Close()
+		return nil, fmt.Errorf("failed to save replay job: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &replayRun{cancel: cancel, progress: progress}
+	s.mu.Lock()
+	s.running[job.ID] = run
+	s.mu.Unlock()
+
+	copier := &replayCopier{
+		topic:           topicName,
+		targetTopic:     req.TargetTopic,
+		producer:        producer,
+		decoder:         decoder,
+		matcher:         matcher,
+		query:           query,
+		router:          &replayRouter{strategy: req.PartitionStrategy, partitions: targetPartitions},
+		resetTimestamps: req.ResetTimestamps,
+		rate:            req.Rate,
+		started:         time.Now(),
+		run:             run,
+	}
+	info, err := s.jobInfo(job)
+	go s.run(ctx, job, run, client, copier, ranges)
+	return info, err
+}
+
+// targetPartitions returns the partition count of the target topic, which must exist.
+func (s *ReplayJobService) targetPartitions(cluster *model.Cluster, topicName string) (int32, error) {
+	client, err := s.kafkaManager.CreateClient(cluster)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	partitions, err := client.Partitions(topicName)
+	if err == nil && len(partitions) == 0 {
+		err = sarama.ErrUnknownTopicOrPartition
+	}
+	if err != nil {
+		return 0, fmt.Errorf("target topic %s on cluster %s: %w", topicName, cluster.Name, err)
+	}
+	return int32(len(partitions)), nil
+}
+
+func (s *ReplayJobService) run(ctx context.Context, job *model.ReplayJob, run *replayRun, client sarama.Client, copier *replayCopier, ranges []partitionRange) {
+	defer client.Close()
+	defer copier.producer.Close()
+	defer run.cancel()
+
+	saveProgress := func() {
+		progress, copied := run.snapshot()
+		progressJSON, _ := json.Marshal(progress)
+		if err := s.replayRepo.UpdateProgress(job.ID, string(progressJSON), copied); err != nil {
+			log.Printf("[ReplayJob] failed to save progress of job %d: %v", job.ID, err)
+		}
+	}
+	done := make(chan struct{})
+	saved := make(chan struct{})
+	go func() {
+		defer close(saved)
+		ticker := time.NewTicker(replayFlushPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				saveProgress()
+			}
+		}
+	}()
+
+	err := func() error {
+		consumer, err := sarama.NewConsumerFromClient(client)
+		if err != nil {
+			return fmt.Errorf("failed to create consumer: %w", err)
+		}
+		defer consumer.Close()
+
+		for i, rng := range ranges {
+			if err := copier.copyRange(ctx, consumer, remainingIn(client), i, rng); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	close(done)
+	<-saved
+
+	progress, copied := run.snapshot()
+	progressJSON, _ := json.Marshal(progress)
+	finishedAt := time.Now()
+	job.Progress = string(progressJSON)
+	job.Copied = copied
+	job.FinishedAt = &finishedAt
+
+	run.mu.Lock()
+	cancelled := run.cancelled
+	run.mu.Unlock()
+	switch {
+	case cancelled:
+		job.Status = dto.ReplayJobCancelled
+	case err != nil:
+		job.Status = dto.ReplayJobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = dto.ReplayJobCompleted
+	}
+
+	if err := s.replayRepo.Finish(job); err != nil {
+		log.Printf("[ReplayJob] failed to finish job %d: %v", job.ID, err)
+	}
+
+	s.mu.Lock()
+	delete(s.running, job.ID)
+	s.mu.Unlock()
+}
+
+// GetReplayJob returns a job with its current progress.
+func (s *ReplayJobService) GetReplayJob(id uint) (*dto.ReplayJobInfo, error) {
+	job, err := s.replayRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.jobInfo(job)
+}
+
+// GetReplayJobs lists the replay jobs reading from a cluster, optionally from one topic.
+func (s *ReplayJobService) GetReplayJobs(clusterID uint, topicName string) ([]dto.ReplayJobInfo, error) {
+	jobs, err := s.replayRepo.FindByClusterAndTopic(clusterID, topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.ReplayJobInfo, 0, len(jobs))
+	for i := range jobs {
+		info, err := s.jobInfo(&jobs[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *info)
+	}
+	return result, nil
+}
+
+// CancelReplayJob stops a running job. Records copied so far stay in the target topic.
+func (s *ReplayJobService) CancelReplayJob(id uint) error {
+	s.mu.Lock()
+	run, exists := s.running[id]
+	s.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("replay job %d is not running", id)
+	}
+
+	run.mu.Lock()
+	run.cancelled = true
+	run.mu.Unlock()
+	run.cancel()
+	return nil
+}
+
+// DeleteReplayJob removes a finished job.
+func (s *ReplayJobService) DeleteReplayJob(id uint) error {
+	s.mu.Lock()
+	_, running := s.running[id]
+	s.mu.Unlock()
+
+	if running {
+		return fmt.Errorf("replay job %d is still running, cancel it first", id)
+	}
+	return s.replayRepo.Delete(id)
+}
+
+func (s *ReplayJobService) jobInfo(job *model.ReplayJob) (*dto.ReplayJobInfo, error) {
+	info := &dto.ReplayJobInfo{
+		ID:              job.ID,
+		ClusterID:       job.ClusterID,
+		Topic:           job.Topic,
+		TargetClusterID: job.TargetClusterID,
+		TargetTopic:     job.TargetTopic,
+		Status:          job.Status,
+		Error:           job.Error,
+		Copied:          job.Copied,
+		CreatedAt:       job.CreatedAt,
+		FinishedAt:      job.FinishedAt,
+	}
+	if job.Request != "" {
+		if err := json.Unmarshal([]byte(job.Request), &info.Request); err != nil {
+			return nil, fmt.Errorf("failed to read replay job %d: %w", job.ID, err)
+		}
+	}
+	if job.Progress != "" {
+		if err := json.Unmarshal([]byte(job.Progress), &info.Partitions); err != nil {
+			return nil, fmt.Errorf("failed to read replay job %d: %w", job.ID, err)
+		}
+	}
+
+	// running jobs report their live progress rather than the last saved one
+	s.mu.Lock()
+	run, running := s.running[job.ID]
+	s.mu.Unlock()
+	if running && job.Status == dto.ReplayJobRunning {
+		info.Partitions, info.Copied = run.snapshot()
+	}
+
+	for _, partition := range info.Partitions {
+		info.TotalRecords += partition.EndOffset - partition.StartOffset
+		info.ScannedRecords += partition.CurrentOffset - partition.StartOffset
+		info.Skipped += partition.Skipped
+	}
+	return info, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+)
+
+func TestValidateReplayJob(t *testing.T) {
+	offset := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name         string
+		req          dto.ReplayJobRequest
+		wantStrategy string
+		wantErr      bool
+	}{
+		{name: "defaults to key", req: dto.ReplayJobRequest{TargetTopic: " orders-replay "}, wantStrategy: dto.ReplayPartitionKey},
+		{name: "round-robin", req: dto.ReplayJobRequest{TargetTopic: "t", PartitionStrategy: "round-robin"}, wantStrategy: dto.ReplayPartitionRoundRobin},
+		{name: "offset range", req: dto.ReplayJobRequest{TargetTopic: "t", StartOffset: offset(10), EndOffset: offset(20)}, wantStrategy: dto.ReplayPartitionKey},
+		{name: "no target topic", req: dto.ReplayJobRequest{TargetTopic: "  "}, wantErr: true},
+		{name: "unknown strategy", req: dto.ReplayJobRequest{TargetTopic: "t", PartitionStrategy: "random"}, wantErr: true},
+		{name: "negative rate", req: dto.ReplayJobRequest{TargetTopic: "t", Rate: -1}, wantErr: true},
+		{name: "unknown compression", req: dto.ReplayJobRequest{TargetTopic: "t", Compression: "brotli"}, wantErr: true},
+		{name: "end before start", req: dto.ReplayJobRequest{TargetTopic: "t", StartOffset: offset(20), EndOffset: offset(20)}, wantErr: true},
+		{name: "from after to", req: dto.ReplayJobRequest{TargetTopic: "t", From: 2, To: 1}, wantErr: true},
+		{name: "invalid filter", req: dto.ReplayJobRequest{TargetTopic: "t", Filter: "value =="}, wantErr: true},
+		{name: "any json path", req: dto.ReplayJobRequest{TargetTopic: "t", JSONPath: []string{"$.id == 1"}, JSONPathMode: " OR "}, wantStrategy: dto.ReplayPartitionKey},
+		{name: "unknown json path mode", req: dto.ReplayJobRequest{TargetTopic: "t", JSONPath: []string{"$.id == 1"}, JSONPathMode: "xor"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateReplayJob(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateReplayJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (tt.req.PartitionStrategy != tt.wantStrategy || tt.req.TargetTopic == "" || tt.req.TargetTopic[0] == ' ') {
+				t.Fatalf("request = %+v, want strategy %s and a trimmed target topic", tt.req, tt.wantStrategy)
+			}
+		})
+	}
+}
+
+func TestReplayQueryJSONPathMode(t *testing.T) {
+	for mode, want := range map[string]bool{"": false, "and": false, "or": true, " OR ": true} {
+		if got := replayQuery(&dto.ReplayJobRequest{JSONPathMode: mode}).JSONPathAny; got != want {
+			t.Fatalf("replayQuery(%q).JSONPathAny = %v, want %v", mode, got, want)
+		}
+	}
+}
+
+func TestReplayRouter(t *testing.T) {
+	msgs := []*sarama.ConsumerMessage{{Partition: 4}, {Partition: 4}, {Partition: 1}, {Partition: 0}}
+
+	tests := []struct {
+		strategy string
+		want     []interface{}
+	}{
+		{strategy: dto.ReplayPartitionPreserve, want: []interface{}{targetPartition(4), targetPartition(4), targetPartition(1), targetPartition(0)}},
+		{strategy: dto.ReplayPartitionRoundRobin, want: []interface{}{targetPartition(0), targetPartition(1), targetPartition(2), targetPartition(0)}},
+		{strategy: dto.ReplayPartitionKey, want: []interface{}{nil, nil, nil, nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			router := &replayRouter{strategy: tt.strategy, partitions: 3}
+			for i, msg := range msgs {
+				if got := router.route(msg); got != tt.want[i] {
+					t.Fatalf("route(message %d) = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReplayCopyRange(t *testing.T) {
+	timestamp := time.UnixMilli(1_700_000_000_000)
+	consumer := mocks.NewConsumer(t, nil)
+	partitionConsumer := consumer.ExpectConsumePartition("orders", 1, 10)
+	partitionConsumer.YieldMessage(&sarama.ConsumerMessage{Key: []byte("a"), Value: []byte(`{"status":"paid"}`), Timestamp: timestamp,
+		Headers: []*sarama.RecordHeader{{Key: []byte("trace"), Value: []byte{0x01}}}})
+	partitionConsumer.YieldMessage(&sarama.ConsumerMessage{Key: []byte("b"), Value: []byte(`{"status":"open"}`), Timestamp: timestamp})
+	partitionConsumer.YieldMessage(&sarama.ConsumerMessage{Key: []byte("c"), Value: []byte(`{"status":"paid"}`), Timestamp: timestamp.Add(time.Second)})
+
+	query := dto.MessageQuery{ValueFilter: "paid"}
+	matcher, err := newRecordMatcher(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent []*sarama.ProducerMessage
+	producer := &capturingProducer{partialProducer: &partialProducer{partitioner: newExplicitPartitioner("copy"), partitions: 3}, sent: &sent}
+	run := &replayRun{progress: []dto.ReplayPartitionProgress{{Partition: 1, StartOffset: 10, EndOffset: 13, CurrentOffset: 10}}}
+	copier := &replayCopier{
+		topic:       "orders",
+		targetTopic: "copy",
+		producer:    producer,
+		decoder:     newMessageDecoder(&model.Cluster{}, nil, nil, dto.EncodingUTF8),
+		matcher:     matcher,
+		query:       query,
+		router:      &replayRouter{strategy: dto.ReplayPartitionPreserve, partitions: 3},
+		rate:        1000,
+		started:     time.Now(),
+		run:         run,
+	}
+
+	if err := copier.copyRange(context.Background(), consumer, noRemaining, 0, partitionRange{partition: 1, start: 10, stop: 13}); err != nil {
+		t.Fatalf("copyRange() error = %v", err)
+	}
+
+	if len(sent) != 2 {
+		t.Fatalf("sent %d messages, want the 2 paid ones", len(sent))
+	}
+	for _, msg := range sent {
+		if msg.Topic != "copy" || msg.Partition != 1 {
+			t.Fatalf("message went to %s/%d, want copy/1", msg.Topic, msg.Partition)
+		}
+	}
+	if first := sent[0]; !first.Timestamp.Equal(timestamp) || len(first.Headers) != 1 || string(first.Headers[0].Key) != "trace" {
+		t.Fatalf("first message = %+v, want the original timestamp and header", first)
+	}
+
+	progress, copied := run.snapshot()
+	if copied != 2 || progress[0].Skipped != 1 || progress[0].CurrentOffset != 13 {
+		t.Fatalf("progress = %+v, copied %d, want 2 copied, 1 skipped and offset 13", progress[0], copied)
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+// minInSyncReplicas is the topic config below which writes with acks=all are refused.
+const minInSyncReplicas = "min.insync.replicas"
+
+// planReplicationFactor gives every partition of a topic factor replicas. New replicas go
+// to a broker on a rack the partition does not use yet, then to the broker holding the
+// fewest replicas of the cluster. Lowering the factor keeps the leader and drops replicas
+// on unknown brokers or out of sync first, then replicas sharing a rack with another one,
+// then the ones on the busiest brokers. load counts the replicas of every broker.
+func planReplicationFactor(partitions []clusterPartition, factor int, racks map[int32]string, load map[int32]int) ([]dto.PartitionReassignment, error) {
+	if factor < 1 {
+		return nil, fmt.Errorf("%w: replication factor must be at least 1", ErrInvalidReassignment)
+	}
+	if factor > len(racks) {
+		return nil, fmt.Errorf("%w: replication factor %d exceeds the %d brokers of the cluster",
+			ErrInvalidReassignment, factor, len(racks))
+	}
+	brokers := make([]int32, 0, len(racks))
+	for broker := range racks {
+		brokers = append(brokers, broker)
+	}
+	brokers = uniqueBrokers(brokers)
+
+	moves := []dto.PartitionReassignment{}
+	for _, p := range partitions {
+		target := append([]int32(nil), p.replicas...)
+		for len(target) > factor {
+			worst := -1
+			for i, broker := range target {
+				if broker == p.leader {
+					continue
+				}
+				if worst < 0 || worseReplica(p, target, broker, target[worst], racks, load) {
+					worst = i
+				}
+			}
+			load[target[worst]]--
+			target = append(target[:worst:worst], target[worst+1:]...)
+		}
+		for len(target) < factor {
+			usedRacks := make(map[string]bool)
+			for _, broker := range target {
+				if racks[broker] != "" {
+					usedRacks[racks[broker]] = true
+				}
+			}
+			best := int32(-1)
+			for _, broker := range brokers {
+				if containsBroker(target, broker) {
+					continue
+				}
+				if best < 0 || betterPlacement(broker, best, load, usedRacks, racks) {
+					best = broker
+				}
+			}
+			target = append(target, best)
+			load[best]++
+		}
+		if !sameReplicas(p.replicas, target) {
+			moves = append(moves, newPartitionReassignment(p.topic, p.id, p.replicas, target))
+		}
+	}
+	return moves, nil
+}
+
+// worseReplica reports whether the replica of partition p on broker is a better one to
+// drop than the replica on worst, target being the replicas kept so far.
+func worseReplica(p clusterPartition, target []int32, broker, worst int32, racks map[int32]string, load map[int32]int) bool {
+	lost := func(replica int32) bool {
+		_, registered := racks[replica]
+		return !registered || !containsBroker(p.isr, replica) || containsBroker(p.offline, replica)
+	}
+	if lost(broker) != lost(worst) {
+		return lost(broker)
+	}
+	sharesRack := func(replica int32) bool {
+		for _, other := range target {
+			if other != replica && racks[replica] != "" && racks[other] == racks[replica] {
+				return true
+			}
+		}
+		return false
+	}
+	if sharesRack(broker) != sharesRack(worst) {
+		return sharesRack(broker)
+	}
+	if load[broker] != load[worst] {
+		return load[broker] > load[worst]
+	}
+	// the later replica in the list, which is the least preferred leader
+	return true
+}
+
+// ChangeReplicationFactor plans new replicas for every partition of a topic and, unless
+// req.DryRun is set, starts the reassignment adding or removing them. Its progress is
+// reported like any other reassignment.
+func (s *ReassignmentService) ChangeReplicationFactor(clusterID uint, topic string, req *dto.ReplicationFactorRequest) (*dto.ReplicationFactorChange, error) {
+	if req.Throttle < 0 {
+		return nil, fmt.Errorf("%w: throttle must not be negative", ErrInvalidReassignment)
+	}
+	admin, err := s.adminClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	racks, err := brokerRacks(admin)
+	if err != nil {
+		return nil, err
+	}
+	all, err := describeClusterPartitions(admin)
+	if err != nil {
+		return nil, err
+	}
+	load := make(map[int32]int, len(racks))
+	var partitions []clusterPartition
+	for _, p := range all {
+		for _, broker := range p.replicas {
+			load[broker]++
+		}
+		if p.topic == topic {
+			partitions = append(partitions, p)
+		}
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("%w: topic %s does not exist", ErrInvalidReassignment, topic)
+	}
+
+	moves, err := planReplicationFactor(partitions, req.ReplicationFactor, racks, load)
+	if err != nil {
+		return nil, err
+	}
+
+	minISR, err := topicMinInSyncReplicas(admin, topic)
+	if err != nil {
+		return nil, err
+	}
+	if req.ReplicationFactor < minISR {
+		return nil, fmt.Errorf("%w: replication factor %d is below %s=%d, producers with acks=all could not write",
+			ErrInvalidReassignment, req.ReplicationFactor, minInSyncReplicas, minISR)
+	}
+
+	change := &dto.ReplicationFactorChange{
+		Topic:             topic,
+		ReplicationFactor: req.ReplicationFactor,
+		DryRun:            req.DryRun,
+		Partitions:        moves,
+	}
+	if req.DryRun || len(moves) == 0 {
+		return change, nil
+	}
+
+	reassignment, err := s.StartReassignment(clusterID, &dto.ReassignmentRequest{Partitions: moves, Throttle: req.Throttle})
+	if err != nil {
+		return nil, err
+	}
+	change.Reassignment = reassignment.Status()
+	return change, nil
+}
+
+// topicMinInSyncReplicas reads the min.insync.replicas config of a topic, 1 when unset.
+func topicMinInSyncReplicas(admin sarama.ClusterAdmin, topic string) (int, error) {
+	configs, err := admin.DescribeConfig(sarama.ConfigResource{
+		Type:        sarama.TopicResource,
+		Name:        topic,
+		ConfigNames: []string{minInSyncReplicas},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to describe topic config: %w", err)
+	}
+	for _, entry := range configs {
+		if entry.Name == minInSyncReplicas {
+			if value, err := strconv.Atoi(entry.Value); err == nil {
+				return value, nil
+			}
+		}
+	}
+	return 1, nil
+}
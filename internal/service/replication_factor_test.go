@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlanReplicationFactor(t *testing.T) {
+	tests := []struct {
+		name       string
+		partitions []clusterPartition
+		factor     int
+		racks      map[int32]string
+		want       map[int32][]int32 // target replicas of the partitions that change
+		wantErr    bool
+	}{
+		{
+			name: "raise onto another rack",
+			partitions: []clusterPartition{
+				{topic: "orders", id: 0, leader: 1, replicas: []int32{1}, isr: []int32{1}},
+				{topic: "orders", id: 1, leader: 2, replicas: []int32{2}, isr: []int32{2}},
+			},
+			factor: 2,
+			racks:  map[int32]string{1: "a", 2: "a", 3: "b"},
+			want:   map[int32][]int32{0: {1, 3}, 1: {2, 3}},
+		},
+		{
+			name: "raise onto the least loaded brokers",
+			partitions: []clusterPartition{
+				{topic: "orders", id: 0, leader: 1, replicas: []int32{1}, isr: []int32{1}},
+				{topic: "orders", id: 1, leader: 1, replicas: []int32{1}, isr: []int32{1}},
+				{topic: "orders", id: 2, leader: 1, replicas: []int32{1}, isr: []int32{1}},
+			},
+			factor: 2,
+			racks:  map[int32]string{1: "", 2: "", 3: ""},
+			want:   map[int32][]int32{0: {1, 2}, 1: {1, 3}, 2: {1, 2}},
+		},
+		{
+			name: "lower drops out of sync replicas and keeps the leader",
+			partitions: []clusterPartition{
+				{topic: "orders", id: 0, leader: 2, replicas: []int32{1, 2, 3}, isr: []int32{1, 2}},
+				{topic: "orders", id: 1, leader: 3, replicas: []int32{3, 1}, isr: []int32{3}},
+			},
+			factor: 1,
+			racks:  map[int32]string{1: "", 2: "", 3: ""},
+			want:   map[int32][]int32{0: {2}, 1: {3}},
+		},
+		{
+			name: "lower keeps the rack spread",
+			partitions: []clusterPartition{
+				{topic: "orders", id: 0, leader: 1, replicas: []int32{1, 2, 3}, isr: []int32{1, 2, 3}},
+			},
+			factor: 2,
+			racks:  map[int32]string{1: "a", 2: "a", 3: "b"},
+			want:   map[int32][]int32{0: {1, 3}},
+		},
+		{
+			name: "same factor changes nothing",
+			partitions: []clusterPartition{
+				{topic: "orders", id: 0, leader: 1, replicas: []int32{1, 2}, isr: []int32{1, 2}},
+			},
+			factor: 2,
+			racks:  map[int32]string{1: "", 2: ""},
+			want:   map[int32][]int32{},
+		},
+		{
+			name:       "more replicas than brokers",
+			partitions: []clusterPartition{{topic: "orders", id: 0, leader: 1, replicas: []int32{1}}},
+			factor:     3,
+			racks:      map[int32]string{1: "", 2: ""},
+			wantErr:    true,
+		},
+		{
+			name:       "no replicas",
+			partitions: []clusterPartition{{topic: "orders", id: 0, leader: 1, replicas: []int32{1}}},
+			factor:     0,
+			racks:      map[int32]string{1: ""},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			load := make(map[int32]int)
+			for _, p := range tt.partitions {
+				for _, broker := range p.replicas {
+					load[broker]++
+				}
+			}
+
+			moves, err := planReplicationFactor(tt.partitions, tt.factor, tt.racks, load)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("planReplicationFactor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidReassignment) {
+					t.Fatalf("planReplicationFactor() error = %v, want ErrInvalidReassignment", err)
+				}
+				return
+			}
+
+			got := make(map[int32][]int32)
+			for _, move := range moves {
+				got[move.Partition] = move.Target
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("planned targets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
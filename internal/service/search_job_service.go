@@ -0,0 +1,444 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"github.com/bingfengfeifei/kafka-map-go/internal/repository"
+	"github.com/bingfengfeifei/kafka-map-go/internal/util"
+)
+
+const (
+	defaultSearchMatches = 1000
+	maxSearchMatches     = 10000
+	// searchIdleTimeout ends a partition scan that stopped receiving records before its
+	// end offset, which happens when the remaining offsets are transaction markers.
+	searchIdleTimeout  = 10 * time.Second
+	searchFlushPeriod  = time.Second
+	searchMatchBatches = 200
+)
+
+// SearchJobService scans whole topics for matching records in the background. Jobs
+// and their matches are persisted, so results outlive the request that started them.
+type SearchJobService struct {
+	clusterRepo  *repository.ClusterRepository
+	searchRepo   *repository.SearchJobRepository
+	kafkaManager *util.KafkaClientManager
+	topicService *TopicService
+	running      map[uint]*searchRun
+	mu           sync.Mutex
+}
+
+func NewSearchJobService(clusterRepo *repository.ClusterRepository, searchRepo *repository.SearchJobRepository, kafkaManager *util.KafkaClientManager, topicService *TopicService) *SearchJobService {
+	return &SearchJobService{
+		clusterRepo:  clusterRepo,
+		searchRepo:   searchRepo,
+		kafkaManager: kafkaManager,
+		topicService: topicService,
+		running:      make(map[uint]*searchRun),
+	}
+}
+
+// searchRun is the in-memory state of a running job.
+type searchRun struct {
+	cancel     context.CancelFunc
+	cancelled  bool // cancelled by the user rather than by reaching the match limit
+	maxMatches int64
+	truncated  bool // a match beyond maxMatches was found
+	progress   []dto.SearchPartitionProgress
+	matchCount int64
+	mu         sync.Mutex
+}
+
+func (r *searchRun) advance(index int, next int64) {
+	r.mu.Lock()
+	r.progress[index].CurrentOffset = next
+	r.mu.Unlock()
+}
+
+// addMatch counts a match and reports whether it is within the match limit.
+func (r *searchRun) addMatch(index int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.matchCount >= r.maxMatches {
+		r.truncated = true
+		return false
+	}
+	r.matchCount++
+	r.progress[index].Matches++
+	return true
+}
+
+func (r *searchRun) snapshot() ([]dto.SearchPartitionProgress, int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]dto.SearchPartitionProgress(nil), r.progress...), r.matchCount
+}
+
+// FailInterruptedJobs marks jobs that were running when the server stopped as failed.
+func (s *SearchJobService) FailInterruptedJobs() error {
+	return s.searchRepo.FailRunning(dto.SearchJobRunning, dto.SearchJobFailed, "interrupted by server restart")
+}
+
+// StartSearch creates a search job and starts scanning in the background.
+func (s *SearchJobService) StartSearch(clusterID uint, topicName string, req *dto.SearchJobRequest) (*dto.SearchJobInfo, error) {
+	if req.MaxMatches <= 0 {
+		req.MaxMatches = defaultSearchMatches
+	}
+	if req.MaxMatches > maxSearchMatches {
+		req.MaxMatches = maxSearchMatches
+	}
+	query := dto.MessageQuery{
+		Offset:      sarama.OffsetOldest,
+		From:        req.From,
+		To:          req.To,
+		KeyFilter:   req.KeyFilter,
+		ValueFilter: req.ValueFilter,
+		JSONKey:     req.JSONKey,
+		JSONValue:   req.JSONValue,
+		Filter:      req.Filter,
+		JSONPath:    req.JSONPath,
+		JSONPathAny: strings.EqualFold(strings.TrimSpace(req.JSONPathMode), "or"),
+		Encoding:    req.Encoding,
+	}
+	matcher, err := newRecordMatcher(query)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := s.topicService.topicDecoder(cluster, topicName, req.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.kafkaManager.CreateClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges, err := searchRanges(client, topicName, req.Partitions, query)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	progress := make([]dto.SearchPartitionProgress, len(ranges))
+	for i, rng := range ranges {
+		progress[i] = dto.SearchPartitionProgress{
+			Partition:     rng.partition,
+			StartOffset:   rng.start,
+			EndOffset:     rng.stop,
+			CurrentOffset: rng.start,
+		}
+	}
+
+	request, _ := json.Marshal(req)
+	progressJSON, _ := json.Marshal(progress)
+	job := &model.SearchJob{
+		ClusterID: clusterID,
+		Topic:     topicName,
+		Status:    dto.SearchJobRunning,
+		Request:   string(request),
+		Progress:  string(progressJSON),
+	}
+	if err := s.searchRepo.Create(job); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to save search job: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &searchRun{cancel: cancel, maxMatches: int64(req.MaxMatches), progress: progress}
+	s.mu.Lock()
+	s.running[job.ID] = run
+	s.mu.Unlock()
+
+	go s.run(ctx, job, run, client, ranges, decoder, matcher, query)
+
+	return s.jobInfo(job)
+}
+
+// searchRanges resolves the offsets to scan on the selected partitions, from the
+// beginning of the log or the start of the time range up to the current end offset.
+func searchRanges(client sarama.Client, topicName string, selected []int32, query dto.MessageQuery) ([]partitionRange, error) {
+	partitions, err := client.Partitions(topicName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions: %w", err)
+	}
+	if len(selected) > 0 {
+		known := make(map[int32]bool, len(partitions))
+		for _, partition := range partitions {
+			known[partition] = true
+		}
+		for _, partition := range selected {
+			if !known[partition] {
+				return nil, fmt.Errorf("partition %d does not exist in topic %s", partition, topicName)
+			}
+		}
+		partitions = selected
+	}
+
+	ranges := make([]partitionRange, 0, len(partitions))
+	for _, partition := range partitions {
+		rng, err := resolvePartitionRange(client, topicName, partition, query, true, false)
+		if err != nil {
+			return nil, err
+		}
+		if rng.stop < rng.start {
+			rng.stop = rng.start
+		}
+		ranges = append(ranges, rng)
+	}
+	return ranges, nil
+}
+
+func (s *SearchJobService) run(ctx context.Context, job *model.SearchJob, run *searchRun, client sarama.Client, ranges []partitionRange, decoder *messageDecoder, matcher *recordMatcher, query dto.MessageQuery) {
+	defer client.Close()
+	defer run.cancel()
+
+	matches := make(chan model.SearchMatch, searchMatchBatches)
+	errs := make(chan error, len(ranges))
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		errs <- fmt.Errorf("failed to create consumer: %w", err)
+		close(matches)
+	} else {
+		defer consumer.Close()
+
+		var wg sync.WaitGroup
+		for i, rng := range ranges {
+			wg.Add(1)
+			go func(i int, rng partitionRange) {
+				defer wg.Done()
+				if err := scanPartition(ctx, consumer, remainingIn(client), decoder, matcher, job, run, i, rng, query, matches); err != nil {
+					errs <- err
+					run.cancel()
+				}
+			}(i, rng)
+		}
+		go func() {
+			wg.Wait()
+			close(matches)
+		}()
+	}
+
+	ticker := time.NewTicker(searchFlushPeriod)
+	defer ticker.Stop()
+
+	var batch []model.SearchMatch
+	flush := func() {
+		if len(batch) > 0 {
+			if err := s.searchRepo.CreateMatches(batch); err != nil {
+				log.Printf("[SearchJob] failed to save matches of job %d: %v", job.ID, err)
+			}
+			batch = batch[:0]
+		}
+		progress, matchCount := run.snapshot()
+		progressJSON, _ := json.Marshal(progress)
+		if err := s.searchRepo.UpdateProgress(job.ID, string(progressJSON), matchCount); err != nil {
+			log.Printf("[SearchJob] failed to save progress of job %d: %v", job.ID, err)
+		}
+	}
+
+	for done := false; !done; {
+		select {
+		case match, ok := <-matches:
+			if !ok {
+				done = true
+				break
+			}
+			batch = append(batch, match)
+			if len(batch) >= searchMatchBatches {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+	flush()
+
+	progress, matchCount := run.snapshot()
+	progressJSON, _ := json.Marshal(progress)
+	finishedAt := time.Now()
+	job.Progress = string(progressJSON)
+	job.MatchCount = matchCount
+	job.FinishedAt = &finishedAt
+
+	run.mu.Lock()
+	cancelled := run.cancelled
+	job.Truncated = run.truncated
+	run.mu.Unlock()
+	select {
+	case err := <-errs:
+		job.Status = dto.SearchJobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = dto.SearchJobCompleted
+		if cancelled {
+			job.Status = dto.SearchJobCancelled
+		}
+	}
+
+	if err := s.searchRepo.Finish(job); err != nil {
+		log.Printf("[SearchJob] failed to finish job %d: %v", job.ID, err)
+	}
+
+	s.mu.Lock()
+	delete(s.running, job.ID)
+	s.mu.Unlock()
+}
+
+// scanPartition scans a partition range and sends every matching record, stopping at the
+// end of the range, on cancellation or when the job reached its match limit.
+func scanPartition(ctx context.Context, consumer sarama.Consumer, remaining remainingCheck, decoder *messageDecoder, matcher *recordMatcher, job *model.SearchJob, run *searchRun, index int, rng partitionRange, query dto.MessageQuery, matches chan<- model.SearchMatch) error {
+	exhausted, err := scanRange(ctx, consumer, remaining, job.Topic, rng, searchIdleTimeout, func(msg *sarama.ConsumerMessage) bool {
+		run.advance(index, msg.Offset+1)
+		if !inTimeRange(msg.Timestamp.UnixMilli(), query.From, query.To) {
+			return true
+		}
+		record := decoder.record(msg)
+		if !matcher.match(msg, &record) {
+			return true
+		}
+		if !run.addMatch(index) {
+			// the match limit was reached, stop the whole job
+			run.cancel()
+			return false
+		}
+		data, _ := json.Marshal(record)
+		select {
+		case matches <- model.SearchMatch{JobID: job.ID, Partition: msg.Partition, Offset: msg.Offset, Record: string(data)}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	if exhausted {
+		run.advance(index, rng.stop)
+	}
+	return err
+}
+
+// GetSearchJob returns a job with its current progress.
+func (s *SearchJobService) GetSearchJob(id uint) (*dto.SearchJobInfo, error) {
+	job, err := s.searchRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.jobInfo(job)
+}
+
+// GetSearchJobs lists the search jobs of a cluster, optionally limited to one topic.
+func (s *SearchJobService) GetSearchJobs(clusterID uint, topicName string) ([]dto.SearchJobInfo, error) {
+	jobs, err := s.searchRepo.FindByClusterAndTopic(clusterID, topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.SearchJobInfo, 0, len(jobs))
+	for i := range jobs {
+		info, err := s.jobInfo(&jobs[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *info)
+	}
+	return result, nil
+}
+
+// GetSearchMatches returns up to limit matches found after the match with ID afterID.
+func (s *SearchJobService) GetSearchMatches(id, afterID uint, limit int) ([]dto.SearchMatch, error) {
+	matches, err := s.searchRepo.FindMatches(id, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.SearchMatch, 0, len(matches))
+	for _, match := range matches {
+		var record dto.MessageRecord
+		if err := json.Unmarshal([]byte(match.Record), &record); err != nil {
+			return nil, fmt.Errorf("failed to read match %d: %w", match.ID, err)
+		}
+		result = append(result, dto.SearchMatch{ID: match.ID, Record: record})
+	}
+	return result, nil
+}
+
+// CancelSearchJob stops a running job. Matches found so far are kept.
+func (s *SearchJobService) CancelSearchJob(id uint) error {
+	s.mu.Lock()
+	run, exists := s.running[id]
+	s.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("search job %d is not running", id)
+	}
+
+	run.mu.Lock()
+	run.cancelled = true
+	run.mu.Unlock()
+	run.cancel()
+	return nil
+}
+
+// DeleteSearchJob removes a finished job together with its matches.
+func (s *SearchJobService) DeleteSearchJob(id uint) error {
+	s.mu.Lock()
+	_, running := s.running[id]
+	s.mu.Unlock()
+
+	if running {
+		return fmt.Errorf("search job %d is still running, cancel it first", id)
+	}
+	return s.searchRepo.Delete(id)
+}
+
+func (s *SearchJobService) jobInfo(job *model.SearchJob) (*dto.SearchJobInfo, error) {
+	info := &dto.SearchJobInfo{
+		ID:         job.ID,
+		ClusterID:  job.ClusterID,
+		Topic:      job.Topic,
+		Status:     job.Status,
+		Error:      job.Error,
+		MatchCount: job.MatchCount,
+		Truncated:  job.Truncated,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if job.Request != "" {
+		if err := json.Unmarshal([]byte(job.Request), &info.Request); err != nil {
+			return nil, fmt.Errorf("failed to read search job %d: %w", job.ID, err)
+		}
+	}
+	if job.Progress != "" {
+		if err := json.Unmarshal([]byte(job.Progress), &info.Partitions); err != nil {
+			return nil, fmt.Errorf("failed to read search job %d: %w", job.ID, err)
+		}
+	}
+
+	// running jobs report their live progress rather than the last flushed one
+	s.mu.Lock()
+	run, running := s.running[job.ID]
+	s.mu.Unlock()
+	if running && job.Status == dto.SearchJobRunning {
+		info.Partitions, info.MatchCount = run.snapshot()
+	}
+
+	for _, partition := range info.Partitions {
+		info.TotalRecords += partition.EndOffset - partition.StartOffset
+		info.ScannedRecords += partition.CurrentOffset - partition.StartOffset
+	}
+	return info, nil
+}
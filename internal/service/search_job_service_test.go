@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"github.com/bingfengfeifei/kafka-map-go/internal/repository"
+	"github.com/bingfengfeifei/kafka-map-go/pkg/database"
+)
+
+func TestScanPartitionSendsMatchesAndProgress(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxMatches    int64
+		wantOffsets   []int64
+		wantCurrent   int64
+		wantCancel    bool
+		wantTruncated bool
+	}{
+		{name: "whole range", maxMatches: 10, wantOffsets: []int64{1, 3}, wantCurrent: 4},
+		{name: "exactly the match limit", maxMatches: 2, wantOffsets: []int64{1, 3}, wantCurrent: 4},
+		{name: "match limit", maxMatches: 1, wantOffsets: []int64{1}, wantCurrent: 4, wantCancel: true, wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := mocks.NewConsumer(t, nil)
+			partitionConsumer := consumer.ExpectConsumePartition("orders", 0, 0)
+			for _, value := range []string{"new", "paid", "new", "paid", "beyond the end offset"} {
+				partitionConsumer.YieldMessage(&sarama.ConsumerMessage{Value: []byte(value)})
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			run := &searchRun{
+				cancel:     cancel,
+				maxMatches: tt.maxMatches,
+				progress:   []dto.SearchPartitionProgress{{Partition: 0, StartOffset: 0, EndOffset: 4}},
+			}
+			matcher, err := newRecordMatcher(dto.MessageQuery{ValueFilter: "paid"})
+			if err != nil {
+				t.Fatalf("newRecordMatcher() error = %v", err)
+			}
+
+			matches := make(chan model.SearchMatch, 10)
+			job := &model.SearchJob{ID: 7, Topic: "orders"}
+			rng := partitionRange{partition: 0, start: 0, stop: 4}
+			decoder := newMessageDecoder(&model.Cluster{}, nil, nil, dto.EncodingAuto)
+			if err := scanPartition(ctx, consumer, noRemaining, decoder, matcher, job, run, 0, rng, dto.MessageQuery{}, matches); err != nil {
+				t.Fatalf("scanPartition() error = %v", err)
+			}
+			close(matches)
+
+			var offsets []int64
+			for match := range matches {
+				if match.JobID != job.ID {
+					t.Fatalf("JobID = %d, want %d", match.JobID, job.ID)
+				}
+				offsets = append(offsets, match.Offset)
+			}
+			if len(offsets) != len(tt.wantOffsets) {
+				t.Fatalf("match offsets = %v, want %v", offsets, tt.wantOffsets)
+			}
+			for i := range offsets {
+				if offsets[i] != tt.wantOffsets[i] {
+					t.Fatalf("match offsets = %v, want %v", offsets, tt.wantOffsets)
+				}
+			}
+
+			progress, matchCount := run.snapshot()
+			if progress[0].CurrentOffset != tt.wantCurrent || matchCount != int64(len(tt.wantOffsets)) {
+				t.Fatalf("progress = %+v (%d matches), want current offset %d", progress[0], matchCount, tt.wantCurrent)
+			}
+			if cancelled := ctx.Err() != nil; cancelled != tt.wantCancel {
+				t.Fatalf("job cancelled = %v, want %v", cancelled, tt.wantCancel)
+			}
+			if run.truncated != tt.wantTruncated {
+				t.Fatalf("truncated = %v, want %v", run.truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestSearchJobServiceReportsStoredJobs(t *testing.T) {
+	db, err := database.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init test database: %v", err)
+	}
+	searchRepo := repository.NewSearchJobRepository(db)
+	searchService := NewSearchJobService(repository.NewClusterRepository(db), searchRepo, nil, nil)
+
+	job := &model.SearchJob{
+		ClusterID: 1,
+		Topic:     "orders",
+		Status:    dto.SearchJobRunning,
+		Request:   `{"valueFilter":"paid","maxMatches":1000}`,
+		Progress:  `[{"partition":0,"startOffset":10,"endOffset":110,"currentOffset":60,"matches":2},{"partition":1,"startOffset":0,"endOffset":50,"currentOffset":50,"matches":1}]`,
+	}
+	if err := searchRepo.Create(job); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if err := searchRepo.CreateMatches([]model.SearchMatch{
+		{JobID: job.ID, Partition: 0, Offset: 12, Record: `{"partition":0,"offset":12,"value":"paid"}`},
+		{JobID: job.ID, Partition: 1, Offset: 3, Record: `{"partition":1,"offset":3,"value":"paid"}`},
+		{JobID: job.ID, Partition: 0, Offset: 40, Record: `{"partition":0,"offset":40,"value":"paid"}`},
+	}); err != nil {
+		t.Fatalf("create matches: %v", err)
+	}
+
+	if err := searchService.FailInterruptedJobs(); err != nil {
+		t.Fatalf("FailInterruptedJobs() error = %v", err)
+	}
+	info, err := searchService.GetSearchJob(job.ID)
+	if err != nil {
+		t.Fatalf("GetSearchJob() error = %v", err)
+	}
+	if info.Status != dto.SearchJobFailed || info.Error == "" {
+		t.Fatalf("Status = %q (%q), want failed after restart", info.Status, info.Error)
+	}
+	if info.TotalRecords != 150 || info.ScannedRecords != 100 {
+		t.Fatalf("scanned %d of %d records, want 100 of 150", info.ScannedRecords, info.TotalRecords)
+	}
+	if info.Request.ValueFilter != "paid" || len(info.Partitions) != 2 {
+		t.Fatalf("info = %+v, want stored request and progress", info)
+	}
+
+	first, err := searchService.GetSearchMatches(job.ID, 0, 2)
+	if err != nil {
+		t.Fatalf("GetSearchMatches() error = %v", err)
+	}
+	if len(first) != 2 || first[0].Record.Offset != 12 || first[1].Record.Partition != 1 {
+		t.Fatalf("first page = %+v, want the first two matches", first)
+	}
+	rest, err := searchService.GetSearchMatches(job.ID, first[1].ID, 2)
+	if err != nil {
+		t.Fatalf("GetSearchMatches() error = %v", err)
+	}
+	if len(rest) != 1 || rest[0].Record.Offset != 40 {
+		t.Fatalf("second page = %+v, want the last match", rest)
+	}
+
+	if err := searchService.CancelSearchJob(job.ID); err == nil {
+		t.Fatal("CancelSearchJob() error = nil, want error for a job that is not running")
+	}
+	if err := searchService.DeleteSearchJob(job.ID); err != nil {
+		t.Fatalf("DeleteSearchJob() error = %v", err)
+	}
+	if matches, _ := searchRepo.FindMatches(job.ID, 0, 10); len(matches) != 0 {
+		t.Fatalf("matches after delete = %d, want 0", len(matches))
+	}
+}
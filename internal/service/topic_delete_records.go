@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+// ErrInvalidDeleteRecords is returned when a delete records request does not fit the topic.
+var ErrInvalidDeleteRecords = errors.New("invalid delete records request")
+
+// ValidateDeleteRecords checks that a delete records request names exactly one position.
+func ValidateDeleteRecords(req *dto.DeleteRecordsRequest) error {
+	positions := 0
+	if req.Offset != nil {
+		positions++
+		if *req.Offset < 0 {
+			return errors.New("offset must not be negative")
+		}
+	}
+	if req.Timestamp != 0 {
+		positions++
+		if req.Timestamp < 0 {
+			return errors.New("timestamp must not be negative")
+		}
+	}
+	if req.Purge {
+		positions++
+	}
+	if positions != 1 {
+		return errors.New("set exactly one of offset, timestamp and purge")
+	}
+	return nil
+}
+
+// planDeleteRecords resolves the offset every selected partition would be truncated to.
+// An offset beyond the end of a partition is rejected; other positions outside a
+// partition's log are clamped to its start and end offsets.
+func planDeleteRecords(client sarama.Client, topicName string, req *dto.DeleteRecordsRequest) (*dto.DeleteRecordsResult, error) {
+	partitions, err := client.Partitions(topicName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions: %w", err)
+	}
+	if len(req.Partitions) > 0 {
+		known := make(map[int32]bool, len(partitions))
+		for _, partition := range partitions {
+			known[partition] = true
+		}
+		for _, partition := range req.Partitions {
+			if !known[partition] {
+				return nil, fmt.Errorf("%w: partition %d does not exist in topic %s", ErrInvalidDeleteRecords, partition, topicName)
+			}
+		}
+		partitions = req.Partitions
+	}
+
+	result := &dto.DeleteRecordsResult{Topic: topicName, DryRun: req.DryRun, Partitions: make([]dto.DeleteRecordsPartition, 0, len(partitions))}
+	for _, partition := range partitions {
+		start, err := client.GetOffset(topicName, partition, sarama.OffsetOldest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get beginning offset of partition %d: %w", partition, err)
+		}
+		end, err := client.GetOffset(topicName, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get end offset of partition %d: %w", partition, err)
+		}
+
+		deleteTo := end
+		switch {
+		case req.Offset != nil:
+			if *req.Offset > end {
+				return nil, fmt.Errorf("%w: offset %d is beyond the end offset %d of partition %d",
+					ErrInvalidDeleteRecords, *req.Offset, end, partition)
+			}
+			deleteTo = *req.Offset
+		case req.Timestamp > 0:
+			// the first record at or after the timestamp stays, the end offset when there is none
+			if deleteTo, err = offsetForTime(client, topicName, partition, req.Timestamp); err != nil {
+				return nil, err
+			}
+		}
+		if deleteTo < start {
+			deleteTo = start
+		}
+		if deleteTo > end {
+			deleteTo = end
+		}
+
+		result.Partitions = append(result.Partitions, dto.DeleteRecordsPartition{
+			Partition:   partition,
+			StartOffset: start,
+			EndOffset:   end,
+			DeleteTo:    deleteTo,
+			Records:     deleteTo - start,
+		})
+		result.Records += deleteTo - start
+	}
+	return result, nil
+}
+
+// DeleteRecords truncates partitions of a topic with the DeleteRecords admin operation.
+// A dry run only returns the plan; partitions that would lose nothing are left alone.
+func (s *TopicService) DeleteRecords(clusterID uint, topicName string, req *dto.DeleteRecordsRequest) (*dto.DeleteRecordsResult, error) {
+	if err := ValidateDeleteRecords(req); err != nil {
+		return nil, err
+	}
+
+	cluster, admin, err := s.getClusterAndAdmin(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.kafkaManager.CreateClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	result, err := planDeleteRecords(client, topicName, req)
+	if err != nil || req.DryRun {
+		return result, err
+	}
+
+	offsets := make(map[int32]int64)
+	for _, partition := range result.Partitions {
+		if partition.Records > 0 {
+			offsets[partition.Partition] = partition.DeleteTo
+		}
+	}
+	if len(offsets) == 0 {
+		return result, nil
+	}
+	if err := admin.DeleteRecords(topicName, offsets); err != nil {
+		return nil, fmt.Errorf("failed to delete records: %w", err)
+	}
+	return result, nil
+}
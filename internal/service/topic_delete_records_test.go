@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+func TestValidateDeleteRecords(t *testing.T) {
+	offset := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name    string
+		req     dto.DeleteRecordsRequest
+		wantErr bool
+	}{
+		{name: "offset", req: dto.DeleteRecordsRequest{Offset: offset(0)}},
+		{name: "timestamp", req: dto.DeleteRecordsRequest{Timestamp: 1700000000000}},
+		{name: "purge", req: dto.DeleteRecordsRequest{Purge: true}},
+		{name: "nothing", req: dto.DeleteRecordsRequest{}, wantErr: true},
+		{name: "offset and purge", req: dto.DeleteRecordsRequest{Offset: offset(3), Purge: true}, wantErr: true},
+		{name: "negative offset", req: dto.DeleteRecordsRequest{Offset: offset(-1)}, wantErr: true},
+		{name: "negative timestamp", req: dto.DeleteRecordsRequest{Timestamp: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateDeleteRecords(&tt.req); (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateDeleteRecords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlanDeleteRecords(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()).
+			SetLeader("orders", 1, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 0, sarama.OffsetOldest, 10).
+			SetOffset("orders", 0, sarama.OffsetNewest, 50).
+			SetOffset("orders", 0, 1700000000000, 30).
+			SetOffset("orders", 1, sarama.OffsetOldest, 0).
+			SetOffset("orders", 1, sarama.OffsetNewest, 20).
+			SetOffset("orders", 1, 1700000000000, -1),
+	})
+
+	config := sarama.NewConfig()
+	config.ApiVersionsRequest = false
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+
+	offset := func(v int64) *int64 { return &v }
+	tests := []struct {
+		name         string
+		req          dto.DeleteRecordsRequest
+		wantDeleteTo []int64
+		wantRecords  int64
+		wantErr      bool
+	}{
+		// offsets before a partition's log are clamped to its start, offsets past its end are rejected
+		{name: "offset", req: dto.DeleteRecordsRequest{Offset: offset(15)}, wantDeleteTo: []int64{15, 15}, wantRecords: 20},
+		{name: "offset before the log", req: dto.DeleteRecordsRequest{Offset: offset(5)}, wantDeleteTo: []int64{10, 5}, wantRecords: 5},
+		{name: "offset at the end", req: dto.DeleteRecordsRequest{Offset: offset(20), Partitions: []int32{1}}, wantDeleteTo: []int64{20}, wantRecords: 20},
+		{name: "offset beyond the end", req: dto.DeleteRecordsRequest{Offset: offset(25)}, wantErr: true},
+		// partition 1 has no record after the timestamp, so all of it goes
+		{name: "timestamp", req: dto.DeleteRecordsRequest{Timestamp: 1700000000000}, wantDeleteTo: []int64{30, 20}, wantRecords: 40},
+		{name: "purge one partition", req: dto.DeleteRecordsRequest{Purge: true, Partitions: []int32{0}}, wantDeleteTo: []int64{50}, wantRecords: 40},
+		{name: "unknown partition", req: dto.DeleteRecordsRequest{Purge: true, Partitions: []int32{7}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := planDeleteRecords(client, "orders", &tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("planDeleteRecords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDeleteRecords) {
+					t.Fatalf("planDeleteRecords() error = %v, want ErrInvalidDeleteRecords", err)
+				}
+				return
+			}
+			if result.Records != tt.wantRecords || len(result.Partitions) != len(tt.wantDeleteTo) {
+				t.Fatalf("result = %+v, want %d records on %d partitions", result, tt.wantRecords, len(tt.wantDeleteTo))
+			}
+			for i, deleteTo := range tt.wantDeleteTo {
+				if result.Partitions[i].DeleteTo != deleteTo {
+					t.Fatalf("Partitions[%d].DeleteTo = %d, want %d", i, result.Partitions[i].DeleteTo, deleteTo)
+				}
+			}
+		})
+	}
+}
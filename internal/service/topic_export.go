@@ -0,0 +1,237 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+const (
+	// exportIdleTimeout ends a partition export that stopped receiving records before its end offset.
+	exportIdleTimeout = 10 * time.Second
+	// exportBufferSize is the output buffered between flushes to the client.
+	exportBufferSize = 64 << 10
+	// exportFlushEvery flushes the output after this many records so the download keeps moving.
+	exportFlushEvery = 1000
+)
+
+// recordWriter writes exported records in one of the export formats.
+type recordWriter interface {
+	write(msg *sarama.ConsumerMessage, record *dto.MessageRecord) error
+	flush() error
+}
+
+func newRecordWriter(w io.Writer, format string) (recordWriter, error) {
+	switch format {
+	case dto.ExportFormatJSONL:
+		return &jsonlRecordWriter{encoder: json.NewEncoder(w)}, nil
+	case dto.ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"partition", "offset", "timestamp", "key", "value", "headers"}); err != nil {
+			return nil, err
+		}
+		return &csvRecordWriter{writer: writer}, nil
+	case dto.ExportFormatRaw:
+		return &rawRecordWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q, expected jsonl, csv or raw", format)
+	}
+}
+
+// jsonlRecordWriter writes each decoded record as a JSON object on its own line.
+type jsonlRecordWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlRecordWriter) write(_ *sarama.ConsumerMessage, record *dto.MessageRecord) error {
+	return w.encoder.Encode(record)
+}
+
+func (w *jsonlRecordWriter) flush() error {
+	return nil
+}
+
+// csvRecordWriter writes each decoded record as a row, the headers as a JSON array.
+type csvRecordWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvRecordWriter) write(_ *sarama.ConsumerMessage, record *dto.MessageRecord) error {
+	headers := ""
+	if len(record.Headers) > 0 {
+		data, _ := json.Marshal(record.Headers)
+		headers = string(data)
+	}
+	return w.writer.Write([]string{
+		strconv.FormatInt(int64(record.Partition), 10),
+		strconv.FormatInt(record.Offset, 10),
+		strconv.FormatInt(record.Timestamp, 10),
+		record.Key,
+		record.Value,
+		headers,
+	})
+}
+
+func (w *csvRecordWriter) flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// rawRecordWriter writes the undecoded value bytes of each record followed by a newline.
+type rawRecordWriter struct {
+	w io.Writer
+}
+
+func (w *rawRecordWriter) write(msg *sarama.ConsumerMessage, _ *dto.MessageRecord) error {
+	if _, err := w.w.Write(msg.Value); err != nil {
+		return err
+	}
+	_, err := w.w.Write([]byte{'\n'})
+	return err
+}
+
+func (w *rawRecordWriter) flush() error {
+	return nil
+}
+
+// TopicExport is a prepared export of the records matching a query. The offset ranges are
+// resolved up front, so records produced while the export is written are not included.
+type TopicExport struct {
+	topic   string
+	client  sarama.Client
+	decoder *messageDecoder
+	matcher *recordMatcher
+	ranges  []partitionRange
+	query   dto.MessageQuery
+}
+
+// PrepareExport resolves what an export of the records matching query covers. Nothing is
+// read yet, so errors surface before the download starts.
+func (s *TopicService) PrepareExport(clusterID uint, topicName string, query dto.MessageQuery) (*TopicExport, error) {
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := s.topicDecoder(cluster, topicName, query.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := newRecordMatcher(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// the consumer skips aborted and open transactions with read_committed
+	client, err := s.kafkaManager.CreateClient(cluster, func(config *sarama.Config) {
+		config.Consumer.IsolationLevel = isolationLevel(query.ReadCommitted)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []int32
+	if query.Partition != dto.AllPartitions {
+		selected = []int32{query.Partition}
+	}
+	ranges, err := searchRanges(client, topicName, selected, query)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &TopicExport{
+		topic:   topicName,
+		client:  client,
+		decoder: decoder,
+		matcher: matcher,
+		ranges:  ranges,
+		query:   query,
+	}, nil
+}
+
+// Close releases the connection of the export.
+func (e *TopicExport) Close() error {
+	return e.client.Close()
+}
+
+// WriteTo streams the matching records partition by partition in offset order and returns
+// how many were written. Output is flushed regularly, and so is w when it can be flushed.
+func (e *TopicExport) WriteTo(ctx context.Context, w io.Writer, format string) (int64, error) {
+	consumer, err := sarama.NewConsumerFromClient(e.client)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	return e.writeRecords(ctx, consumer, remainingIn(e.client), w, format)
+}
+
+func (e *TopicExport) writeRecords(ctx context.Context, consumer sarama.Consumer, remaining remainingCheck, w io.Writer, format string) (int64, error) {
+	buffer := bufio.NewWriterSize(w, exportBufferSize)
+	writer, err := newRecordWriter(buffer, format)
+	if err != nil {
+		return 0, err
+	}
+
+	flush := func() error {
+		if err := writer.flush(); err != nil {
+			return err
+		}
+		if err := buffer.Flush(); err != nil {
+			return err
+		}
+		if flusher, ok := w.(interface{ Flush() }); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	var written int64
+	var writeErr error
+	limited := func() bool {
+		return e.query.Limit > 0 && written >= int64(e.query.Limit)
+	}
+
+	for _, rng := range e.ranges {
+		if limited() {
+			break
+		}
+		_, err := scanRange(ctx, consumer, remaining, e.topic, rng, exportIdleTimeout, func(msg *sarama.ConsumerMessage) bool {
+			if !inTimeRange(msg.Timestamp.UnixMilli(), e.query.From, e.query.To) {
+				return true
+			}
+			record := e.decoder.record(msg)
+			if !e.matcher.match(msg, &record) {
+				return true
+			}
+			if writeErr = writer.write(msg, &record); writeErr != nil {
+				return false
+			}
+			written++
+			if written%exportFlushEvery == 0 {
+				if writeErr = flush(); writeErr != nil {
+					return false
+				}
+			}
+			return !limited()
+		})
+		if err == nil {
+			err = writeErr
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, flush()
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+)
+
+func TestTopicExportWritesMatchingRecords(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		limit  int
+		want   string
+	}{
+		{
+			name:   "csv",
+			format: dto.ExportFormatCSV,
+			want: "partition,offset,timestamp,key,value,headers\n" +
+				"0,1,1000,a,\"paid, twice\",\"[{\"\"key\"\":\"\"trace\"\",\"\"value\"\":\"\"x\"\",\"\"encoding\"\":\"\"utf8\"\"}]\"\n" +
+				"0,3,1000,,paid,\n" +
+				"1,0,1000,,paid,\n",
+		},
+		{
+			name:   "raw",
+			format: dto.ExportFormatRaw,
+			want:   "paid, twice\npaid\npaid\n",
+		},
+		{
+			name:   "limit",
+			format: dto.ExportFormatRaw,
+			limit:  2,
+			want:   "paid, twice\npaid\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := mocks.NewConsumer(t, nil)
+			first := consumer.ExpectConsumePartition("orders", 0, 0)
+			first.YieldMessage(&sarama.ConsumerMessage{Timestamp: time.UnixMilli(1000), Value: []byte("new")})
+			first.YieldMessage(&sarama.ConsumerMessage{Timestamp: time.UnixMilli(1000), Key: []byte("a"), Value: []byte("paid, twice"), Headers: []*sarama.RecordHeader{{Key: []byte("trace"), Value: []byte("x")}}})
+			first.YieldMessage(&sarama.ConsumerMessage{Timestamp: time.UnixMilli(1000), Value: []byte("new")})
+			first.YieldMessage(&sarama.ConsumerMessage{Timestamp: time.UnixMilli(1000), Value: []byte("paid")})
+			first.YieldMessage(&sarama.ConsumerMessage{Timestamp: time.UnixMilli(1000), Value: []byte("paid beyond the end offset")})
+			if tt.limit == 0 {
+				second := consumer.ExpectConsumePartition("orders", 1, 0)
+				second.YieldMessage(&sarama.ConsumerMessage{Timestamp: time.UnixMilli(1000), Value: []byte("paid")})
+			}
+
+			matcher, err := newRecordMatcher(dto.MessageQuery{ValueFilter: "paid"})
+			if err != nil {
+				t.Fatalf("newRecordMatcher() error = %v", err)
+			}
+			export := &TopicExport{
+				topic:   "orders",
+				decoder: newMessageDecoder(&model.Cluster{}, nil, nil, dto.EncodingUTF8),
+				matcher: matcher,
+				ranges:  []partitionRange{{partition: 0, start: 0, stop: 4}, {partition: 1, start: 0, stop: 1}},
+				query:   dto.MessageQuery{Limit: tt.limit},
+			}
+
+			var out bytes.Buffer
+			written, err := export.writeRecords(context.Background(), consumer, noRemaining, &out, tt.format)
+			if err != nil {
+				t.Fatalf("writeRecords() error = %v", err)
+			}
+			if wantWritten := int64(strings.Count(tt.want, "paid")); written != wantWritten {
+				t.Fatalf("writeRecords() = %d, want %d", written, wantWritten)
+			}
+			if out.String() != tt.want {
+				t.Fatalf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRecordWriterRejectsUnknownFormat(t *testing.T) {
+	if _, err := newRecordWriter(&bytes.Buffer{}, "parquet"); err == nil {
+		t.Fatal("newRecordWriter() error = nil, want error")
+	}
+}
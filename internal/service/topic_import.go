@@ -0,0 +1,365 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+const (
+	// importBatchSize bounds the records produced in one call during an import.
+	importBatchSize = 500
+	// maxImportErrors bounds the line errors listed in an import result.
+	maxImportErrors = 1000
+	// maxImportLine bounds a single JSONL line.
+	maxImportLine = 16 << 20
+	// utf8BOM is stripped from the start of a file, spreadsheets like to write one
+	utf8BOM = "\ufeff"
+)
+
+// ErrInvalidImportFile is returned when an import file cannot be read at all,
+// e.g. an unknown format or CSV header.
+var ErrInvalidImportFile = errors.New("invalid import file")
+
+// importColumns are the CSV columns an import understands.
+var importColumns = map[string]bool{"key": true, "value": true, "headers": true, "partition": true, "timestamp": true}
+
+// importLine is one entry of an import file: a record, or the reason it could not be parsed.
+type importLine struct {
+	line   int
+	record dto.ProduceRecord
+	err    error
+}
+
+// importReader yields the entries of an import file.
+type importReader interface {
+	// next returns the next entry, or io.EOF once the file is exhausted
+	next() (importLine, error)
+}
+
+func newImportReader(r io.Reader, format string) (importReader, error) {
+	switch format {
+	case dto.ImportFormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+		return &jsonlImportReader{scanner: scanner}, nil
+	case dto.ImportFormatCSV:
+		return newCSVImportReader(r)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q, expected jsonl or csv", ErrInvalidImportFile, format)
+	}
+}
+
+// jsonlImportReader reads one JSON object per line, blank lines are skipped.
+type jsonlImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *jsonlImportReader) next() (importLine, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := bytes.TrimSpace(r.scanner.Bytes())
+		if r.line == 1 {
+			text = bytes.TrimPrefix(text, []byte(utf8BOM))
+		}
+		if len(text) == 0 {
+			continue
+		}
+		record, err := parseJSONLRecord(text)
+		return importLine{line: r.line, record: record, err: err}, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return importLine{}, fmt.Errorf("line %d: %w", r.line+1, err)
+	}
+	return importLine{}, io.EOF
+}
+
+// parseJSONLRecord reads a record in the shape of a send request. A key or value that
+// is not a string is kept as its JSON text, so captured JSON payloads import as is. An
+// explicit null value imports as a tombstone, a missing one is an error.
+func parseJSONLRecord(text []byte) (dto.ProduceRecord, error) {
+	var raw struct {
+		Key       json.RawMessage   `json:"key"`
+		Value     json.RawMessage   `json:"value"`
+		Tombstone bool              `json:"tombstone"`
+		Headers   map[string]string `json:"headers"`
+		Partition *int32            `json:"partition"`
+		Timestamp int64             `json:"timestamp"`
+	}
+	if err := json.Unmarshal(text, &raw); err != nil {
+		return dto.ProduceRecord{}, err
+	}
+
+	record := dto.ProduceRecord{
+		Tombstone: raw.Tombstone || string(raw.Value) == "null",
+		Headers:   raw.Headers,
+		Partition: raw.Partition,
+		Timestamp: raw.Timestamp,
+	}
+	key, err := jsonText(raw.Key)
+	if err != nil {
+		return record, fmt.Errorf("key: %w", err)
+	}
+	if key != nil {
+		record.Key = *key
+	}
+	if record.Value, err = jsonText(raw.Value); err != nil {
+		return record, fmt.Errorf("value: %w", err)
+	}
+	return record, nil
+}
+
+// jsonText returns a JSON string as is and any other value as compact JSON, nil for null.
+func jsonText(raw json.RawMessage) (*string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '"' {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+		return &text, nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return nil, err
+	}
+	text := compact.String()
+	return &text, nil
+}
+
+// csvImportReader reads rows below a header naming the key, value, headers, partition
+// and timestamp columns. The headers column holds a JSON object.
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the CSV file is empty", ErrInvalidImportFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, utf8BOM)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !importColumns[name] {
+			return nil, fmt.Errorf("%w: unknown CSV column %q, expected key, value, headers, partition or timestamp", ErrInvalidImportFile, name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("%w: duplicate CSV column %q", ErrInvalidImportFile, name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["value"]; !ok {
+		return nil, fmt.Errorf("%w: the CSV header has no value column", ErrInvalidImportFile)
+	}
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvImportReader) next() (importLine, error) {
+	fields, err := r.reader.Read()
+	if err == io.EOF {
+		return importLine{}, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return importLine{line: parseErr.StartLine, err: parseErr.Err}, nil
+	}
+	if err != nil {
+		return importLine{}, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+	record, err := r.record(fields)
+	return importLine{line: line, record: record, err: err}, nil
+}
+
+func (r *csvImportReader) record(fields []string) (dto.ProduceRecord, error) {
+	var record dto.ProduceRecord
+	field := func(name string) string {
+		if i, ok := r.columns[name]; ok {
+			return fields[i]
+		}
+		return ""
+	}
+
+	record.Key = field("key")
+	value := field("value")
+	record.Value = &value
+	if headers := strings.TrimSpace(field("headers")); headers != "" {
+		if err := json.Unmarshal([]byte(headers), &record.Headers); err != nil {
+			return record, fmt.Errorf("headers: %w", err)
+		}
+	}
+	if partition := strings.TrimSpace(field("partition")); partition != "" {
+		p, err := strconv.ParseInt(partition, 10, 32)
+		if err != nil {
+			return record, fmt.Errorf("partition: %w", err)
+		}
+		p32 := int32(p)
+		record.Partition = &p32
+	}
+	if timestamp := strings.TrimSpace(field("timestamp")); timestamp != "" {
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return record, fmt.Errorf("timestamp: %w", err)
+		}
+		record.Timestamp = ts
+	}
+	return record, nil
+}
+
+func addImportError(result *dto.ImportResult, line int, message string) {
+	result.Failed++
+	if len(result.Errors) >= maxImportErrors {
+		result.Truncated = true
+		return
+	}
+	result.Errors = append(result.Errors, dto.ImportLineError{Line: line, Error: message})
+}
+
+// runImport reads every entry and, unless it is a dry run, produces the valid records in
+// batches paced to the rate limit. The result covers the lines read so far even on error.
+func runImport(ctx context.Context, reader importReader, topic string, options dto.ImportOptions,
+	send func([]*sarama.ProducerMessage) (*dto.SendMessageResult, error)) (*dto.ImportResult, error) {
+
+	started := time.Now()
+	result := &dto.ImportResult{DryRun: options.DryRun, Errors: []dto.ImportLineError{}}
+	defer func() {
+		result.DurationMs = time.Since(started).Milliseconds()
+	}()
+
+	batchSize := importBatchSize
+	if options.Rate > 0 && options.Rate < batchSize {
+		batchSize = options.Rate
+	}
+
+	var batch []importLine
+	submitted := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if options.Rate > 0 {
+			due := started.Add(time.Duration(submitted) * time.Second / time.Duration(options.Rate))
+			if wait := time.Until(due); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+
+		records := make([]dto.ProduceRecord, len(batch))
+		for i, entry := range batch {
+			records[i] = entry.record
+		}
+		sent, err := send(produceMessages(topic, records))
+		if err != nil {
+			return err
+		}
+		for i, record := range sent.Records {
+			if record.Error != "" {
+				addImportError(result, batch[i].line, record.Error)
+			} else {
+				result.Produced++
+			}
+		}
+		submitted += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		entry, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		result.Lines++
+		if entry.err == nil {
+			entry.err = validateProduceRecord(entry.record)
+		}
+		if entry.err != nil {
+			addImportError(result, entry.line, entry.err.Error())
+			continue
+		}
+		result.Records++
+		if options.DryRun {
+			continue
+		}
+
+		batch = append(batch, entry)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// ImportMessages streams the records of a JSONL or CSV file into a topic
+func (s *TopicService) ImportMessages(ctx context.Context, clusterID uint, topicName string, r io.Reader, options dto.ImportOptions) (*dto.ImportResult, error) {
+	codec, err := parseCompression(options.Compression)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := newImportReader(r, options.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	send := func([]*sarama.ProducerMessage) (*dto.SendMessageResult, error) {
+		return nil, errors.New("nothing is produced in a dry run")
+	}
+	if !options.DryRun {
+		cluster, err := s.clusterRepo.FindByID(clusterID)
+		if err != nil {
+			return nil, err
+		}
+		producer, err := s.createRecordProducer(cluster, codec)
+		if err != nil {
+			return nil, err
+		}
+		defer producer.Close()
+		send = func(messages []*sarama.ProducerMessage) (*dto.SendMessageResult, error) {
+			return sendMessages(producer, messages)
+		}
+	}
+	return runImport(ctx, reader, topicName, options, send)
+}
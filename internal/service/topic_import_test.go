@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+// recordingSend acknowledges every message on partition 0 and keeps the batches it was given.
+type recordingSend struct {
+	batches [][]*sarama.ProducerMessage
+	sentAt  []time.Time
+}
+
+func (r *recordingSend) send(messages []*sarama.ProducerMessage) (*dto.SendMessageResult, error) {
+	r.batches = append(r.batches, messages)
+	r.sentAt = append(r.sentAt, time.Now())
+	result := &dto.SendMessageResult{}
+	for i, msg := range messages {
+		record := dto.ProducedRecord{Offset: int64(i)}
+		if msg.Metadata == targetPartition(9) {
+			record = dto.ProducedRecord{Offset: -1, Error: "partition 9 does not exist"}
+			result.Failed++
+		}
+		result.Records = append(result.Records, record)
+	}
+	return result, nil
+}
+
+func TestRunImport(t *testing.T) {
+	tests := []struct {
+		name         string
+		format       string
+		input        string
+		dryRun       bool
+		wantRecords  int
+		wantProduced int
+		wantErrLines []int
+	}{
+		{
+			name:   "jsonl",
+			format: dto.ImportFormatJSONL,
+			input: "\ufeff{\"key\":\"a\",\"value\":\"one\"}\n" +
+				"\n" +
+				"{\"key\":\"b\",\"value\":{\"total\": 3},\"partition\":1,\"timestamp\":1700000000000}\n" +
+				"not json\n" +
+				"{\"value\":null}\n" +
+				"{\"key\":\"c\",\"value\":\"x\",\"partition\":9}\n" +
+				"{\"key\":\"d\"}\n" +
+				"{\"key\":\"e\",\"value\":null}\n",
+			wantRecords:  4,
+			wantProduced: 3,
+			wantErrLines: []int{4, 5, 7, 6},
+		},
+		{
+			name:   "csv",
+			format: dto.ImportFormatCSV,
+			input: "Key,value,headers,partition\n" +
+				"a,one,\"{\"\"trace\"\":\"\"x\"\"}\",0\n" +
+				"b,\"multi\nline\",,\n" +
+				"c,two,,zero\n" +
+				"d,three\n",
+			wantRecords:  2,
+			wantProduced: 2,
+			wantErrLines: []int{5, 6},
+		},
+		{
+			name:         "dry run",
+			format:       dto.ImportFormatJSONL,
+			input:        "{\"key\":\"a\",\"value\":\"one\"}\n{\"key\":\"b\",\"value\":\"two\",\"partition\":-1}\n",
+			dryRun:       true,
+			wantRecords:  1,
+			wantErrLines: []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := newImportReader(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("newImportReader() error = %v", err)
+			}
+			sender := &recordingSend{}
+			result, err := runImport(context.Background(), reader, "orders", dto.ImportOptions{DryRun: tt.dryRun}, sender.send)
+			if err != nil {
+				t.Fatalf("runImport() error = %v", err)
+			}
+
+			if result.Records != tt.wantRecords || result.Produced != tt.wantProduced || result.Failed != len(tt.wantErrLines) {
+				t.Fatalf("result = %+v, want %d records, %d produced, %d failed", result, tt.wantRecords, tt.wantProduced, len(tt.wantErrLines))
+			}
+			for i, line := range tt.wantErrLines {
+				if result.Errors[i].Line != line {
+					t.Fatalf("errors = %+v, want lines %v", result.Errors, tt.wantErrLines)
+				}
+			}
+			if tt.dryRun && len(sender.batches) != 0 {
+				t.Fatalf("dry run produced %d batches", len(sender.batches))
+			}
+		})
+	}
+}
+
+func TestParseJSONLRecordKeepsJSONValues(t *testing.T) {
+	record, err := parseJSONLRecord([]byte(`{"key": 42, "value": {"a": [1, 2]}, "headers": {"h": "v"}}`))
+	if err != nil {
+		t.Fatalf("parseJSONLRecord() error = %v", err)
+	}
+	if record.Key != "42" || record.Value == nil || *record.Value != `{"a":[1,2]}` || record.Headers["h"] != "v" {
+		t.Fatalf("record = %+v, want key 42 and the compact JSON value", record)
+	}
+}
+
+func TestRunImportPacesBatches(t *testing.T) {
+	input := strings.Repeat("{\"key\":\"k\",\"value\":\"v\"}\n", 15)
+	reader, err := newImportReader(strings.NewReader(input), dto.ImportFormatJSONL)
+	if err != nil {
+		t.Fatalf("newImportReader() error = %v", err)
+	}
+	sender := &recordingSend{}
+	started := time.Now()
+	result, err := runImport(context.Background(), reader, "orders", dto.ImportOptions{Rate: 10}, sender.send)
+	if err != nil {
+		t.Fatalf("runImport() error = %v", err)
+	}
+
+	if result.Produced != 15 || len(sender.batches) != 2 || len(sender.batches[0]) != 10 {
+		t.Fatalf("produced %d in batches of %d, want 15 in a batch of 10 and one of 5", result.Produced, len(sender.batches[0]))
+	}
+	if waited := sender.sentAt[1].Sub(started); waited < 900*time.Millisecond {
+		t.Fatalf("second batch sent after %v, want about a second at 10 records per second", waited)
+	}
+}
+
+func TestRunImportStopsWhenCancelled(t *testing.T) {
+	input := strings.Repeat("{\"key\":\"k\",\"value\":\"v\"}\n", 3)
+	reader, err := newImportReader(strings.NewReader(input), dto.ImportFormatJSONL)
+	if err != nil {
+		t.Fatalf("newImportReader() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sender := &recordingSend{}
+	if _, err := runImport(ctx, reader, "orders", dto.ImportOptions{Rate: 1}, sender.send); !errors.Is(err, context.Canceled) {
+		t.Fatalf("runImport() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestNewCSVImportReaderRejectsBadHeaders(t *testing.T) {
+	for _, header := range []string{"", "key,payload\n", "key,key,value\n", "key,headers\n"} {
+		if _, err := newImportReader(strings.NewReader(header), dto.ImportFormatCSV); !errors.Is(err, ErrInvalidImportFile) {
+			t.Fatalf("newImportReader(%q) error = %v, want ErrInvalidImportFile", header, err)
+		}
+	}
+}
@@ -0,0 +1,811 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+)
+
+// findInJSON recursively searches for a key-value pair in a JSON structure.
+// Returns true if the key exists anywhere in the structure with a value that contains the search string.
+func findInJSON(data interface{}, key, value string) bool {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if k == key {
+				// Check if value contains the search string (fuzzy match)
+				valStr := fmt.Sprintf("%v", val)
+				if strings.Contains(valStr, value) {
+					return true
+				}
+			}
+			// Recursively search nested structures
+			if findInJSON(val, key, value) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if findInJSON(item, key, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetMessages retrieves a page of messages from one partition, or from every
+// partition merged by timestamp when query.Partition is dto.AllPartitions.
+// Records are listed newest first, or oldest first with query.Ascending, together with
+// cursors for the neighbouring pages.
+func (s *TopicService) GetMessages(clusterID uint, topicName string, query dto.MessageQuery) (*dto.MessagePage, error) {
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := s.topicDecoder(cluster, topicName, query.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := newRecordMatcher(query)
+	if err != nil {
+		return nil, err
+	}
+
+	isolation := isolationLevel(query.ReadCommitted)
+	client, err := s.kafkaManager.CreateClient(cluster, func(config *sarama.Config) {
+		config.Consumer.IsolationLevel = isolation
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	allPartitions := query.Partition == dto.AllPartitions
+	partitions := []int32{query.Partition}
+	if allPartitions {
+		partitions, err = client.Partitions(topicName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get partitions: %w", err)
+		}
+	}
+
+	// Without a start position the merged view pages backwards from the newest records
+	backward := query.Backward
+	if allPartitions && query.Offset < 0 && query.From == 0 && len(query.Offsets) == 0 {
+		backward = true
+	}
+
+	ranges := make([]partitionRange, 0, len(partitions))
+	for _, partition := range partitions {
+		rng, err := resolvePartitionRange(client, topicName, partition, query, allPartitions, backward)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, rng)
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	reads := make([]partitionRead, len(ranges))
+	var wg sync.WaitGroup
+	for i, rng := range ranges {
+		wg.Add(1)
+		go func(i int, rng partitionRange) {
+			defer wg.Done()
+			if backward {
+				reads[i] = readPartitionBackward(consumer, decoder, matcher, topicName, rng, query)
+			} else {
+				reads[i] = readPartition(consumer, decoder, matcher, topicName, rng, query)
+			}
+		}(i, rng)
+	}
+	wg.Wait()
+
+	for _, read := range reads {
+		if read.err != nil {
+			return nil, read.err
+		}
+	}
+
+	page, prev := mergePartitionReads(reads, query.Limit, backward)
+	if query.Batches {
+		if err := attachBatches(client, topicName, page.Messages, isolation); err != nil {
+			return nil, err
+		}
+	}
+	if query.Ascending {
+		for i, j := 0, len(page.Messages)-1; i < j; i, j = i+1, j-1 {
+			page.Messages[i], page.Messages[j] = page.Messages[j], page.Messages[i]
+		}
+	}
+	page.NextCursor = EncodeMessageCursor(newMessageCursor(query, dto.CursorNext, page.Partitions))
+	page.PrevCursor = EncodeMessageCursor(newMessageCursor(query, dto.CursorPrev, prev))
+	return page, nil
+}
+
+// GetMessage reads the single record at an offset together with its timestamp type and
+// batch metadata. It returns ErrMessageNotFound when the partition or offset does not exist,
+// including offsets removed by compaction or retention and, with readCommitted, records of
+// aborted transactions.
+func (s *TopicService) GetMessage(clusterID uint, topicName string, partition int32, offset int64, encoding string, readCommitted bool) (*dto.MessageRecord, error) {
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := s.topicDecoder(cluster, topicName, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.kafkaManager.CreateClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	partitions, err := client.Partitions(topicName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions: %w", err)
+	}
+	exists := false
+	for _, p := range partitions {
+		exists = exists || p == partition
+	}
+	if !exists || offset < 0 {
+		return nil, ErrMessageNotFound
+	}
+
+	found, err := fetchRecord(client, topicName, partition, offset, isolationLevel(readCommitted))
+	if err != nil {
+		return nil, err
+	}
+
+	record := decoder.record(found.message)
+	record.TimestampType = found.timestampType
+	record.Batch = found.batch
+	return &record, nil
+}
+
+// topicDecoder builds the decoder for a topic, picking up its protobuf binding if any.
+func (s *TopicService) topicDecoder(cluster *model.Cluster, topicName, encoding string) (*messageDecoder, error) {
+	var proto *topicProto
+	if s.protoService != nil {
+		var err error
+		if proto, err = s.protoService.topicMessageTypes(cluster.ID, topicName); err != nil {
+			return nil, err
+		}
+	}
+	return newMessageDecoder(cluster, s.schemaRegistry, proto, encoding), nil
+}
+
+// EncodeMessageCursor serializes a cursor into the opaque token handed out with a MessagePage.
+func EncodeMessageCursor(cursor dto.MessageCursor) string {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeMessageCursor parses a token produced by EncodeMessageCursor.
+func DecodeMessageCursor(token string) (dto.MessageCursor, error) {
+	var cursor dto.MessageCursor
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(token))
+	if err != nil {
+		return cursor, errors.New("malformed cursor")
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, errors.New("malformed cursor")
+	}
+	if cursor.Direction != dto.CursorNext && cursor.Direction != dto.CursorPrev {
+		return cursor, fmt.Errorf("unknown cursor direction %q", cursor.Direction)
+	}
+	if cursor.Partition < dto.AllPartitions {
+		return cursor, fmt.Errorf("invalid cursor partition %d", cursor.Partition)
+	}
+	for partition, offset := range cursor.Offsets {
+		if partition < 0 || offset < 0 {
+			return cursor, errors.New("invalid cursor position")
+		}
+	}
+	return cursor, nil
+}
+
+// newMessageCursor continues query from positions in a direction.
+func newMessageCursor(query dto.MessageQuery, direction string, positions []dto.PartitionPosition) dto.MessageCursor {
+	return dto.MessageCursor{
+		Partition:     query.Partition,
+		Direction:     direction,
+		Offsets:       positionMap(positions),
+		From:          query.From,
+		To:            query.To,
+		KeyFilter:     query.KeyFilter,
+		ValueFilter:   query.ValueFilter,
+		JSONKey:       query.JSONKey,
+		JSONValue:     query.JSONValue,
+		Filter:        query.Filter,
+		JSONPath:      query.JSONPath,
+		JSONPathAny:   query.JSONPathAny,
+		Encoding:      query.Encoding,
+		ReadCommitted: query.ReadCommitted,
+		Ascending:     query.Ascending,
+	}
+}
+
+// ApplyMessageCursor restores the partition selection, positions, time range and filters
+// of a cursor onto a query, replacing whatever the request sent along with it.
+func ApplyMessageCursor(query *dto.MessageQuery, cursor dto.MessageCursor) {
+	query.Partition = cursor.Partition
+	query.Offsets = cursor.Offsets
+	query.Backward = cursor.Direction == dto.CursorPrev
+	query.From = cursor.From
+	query.To = cursor.To
+	query.KeyFilter = cursor.KeyFilter
+	query.ValueFilter = cursor.ValueFilter
+	query.JSONKey = cursor.JSONKey
+	query.JSONValue = cursor.JSONValue
+	query.Filter = cursor.Filter
+	query.JSONPath = cursor.JSONPath
+	query.JSONPathAny = cursor.JSONPathAny
+	query.Encoding = cursor.Encoding
+	query.ReadCommitted = cursor.ReadCommitted
+	query.Ascending = cursor.Ascending
+}
+
+func positionMap(positions []dto.PartitionPosition) map[int32]int64 {
+	offsets := make(map[int32]int64, len(positions))
+	for _, position := range positions {
+		offsets[position.Partition] = position.Offset
+	}
+	return offsets
+}
+
+// partitionRange is the offset window to read from a single partition.
+type partitionRange struct {
+	partition int32
+	start     int64 // first offset of a forward read
+	stop      int64 // exclusive upper bound, -1 keeps waiting for new records
+	floor     int64 // lowest offset a backward read may reach
+}
+
+// partitionRead holds the matching records read from a partition in offset order,
+// together with the scanned offset window [start, next).
+type partitionRead struct {
+	partition int32
+	start     int64
+	next      int64
+	records   []dto.MessageRecord
+	err       error
+}
+
+// resolvePartitionRange turns the query's offset, per-partition positions and time range into
+// absolute offsets. The merged view is bounded by the current end offset, the last stable
+// offset for read_committed queries, so it never waits for new records, while a single
+// partition keeps waiting briefly as the live view relies on it.
+func resolvePartitionRange(client sarama.Client, topicName string, partition int32, query dto.MessageQuery, bounded, backward bool) (partitionRange, error) {
+	beginning, err := client.GetOffset(topicName, partition, sarama.OffsetOldest)
+	if err != nil {
+		return partitionRange{}, fmt.Errorf("failed to get beginning offset of partition %d: %w", partition, err)
+	}
+	end, err := endOffset(client, topicName, partition, isolationLevel(query.ReadCommitted))
+	if err != nil {
+		return partitionRange{}, fmt.Errorf("failed to get end offset of partition %d: %w", partition, err)
+	}
+
+	floor := beginning
+	if query.From > 0 {
+		fromOffset, err := offsetForTime(client, topicName, partition, query.From)
+		if err != nil {
+			return partitionRange{}, err
+		}
+		if fromOffset > floor {
+			floor = fromOffset
+		}
+	}
+
+	stop := int64(-1)
+	if bounded || backward {
+		stop = end
+	}
+	if query.To > 0 {
+		// the first offset past the requested time window
+		stop, err = offsetForTime(client, topicName, partition, query.To+1)
+		if err != nil {
+			return partitionRange{}, err
+		}
+	}
+
+	if query.Until > 0 && (stop < 0 || query.Until < stop) {
+		stop = query.Until
+	}
+
+	start := query.Offset
+	if offset, ok := query.Offsets[partition]; ok {
+		start = offset
+	} else if start == sarama.OffsetNewest || (backward && start < 0) {
+		start = end
+	}
+	if start < beginning {
+		start = beginning
+	}
+	if start > end {
+		start = end
+	}
+
+	if backward {
+		// a backward read ends right before the requested position
+		if start < stop {
+			stop = start
+		}
+		return partitionRange{partition: partition, start: floor, stop: stop, floor: floor}, nil
+	}
+
+	// A time lower bound moves the start offset forward but never back before an explicit offset
+	if floor > start {
+		start = floor
+	}
+	return partitionRange{partition: partition, start: start, stop: stop, floor: floor}, nil
+}
+
+// recordMatcher applies the filters of a MessageQuery to decoded records.
+type recordMatcher struct {
+	keyFilter   string
+	valueFilter string
+	jsonKey     string
+	jsonValue   string
+	jsonPath    *JSONPathFilter
+	expr        *FilterExpression
+}
+
+func newRecordMatcher(query dto.MessageQuery) (*recordMatcher, error) {
+	matcher := &recordMatcher{
+		keyFilter:   query.KeyFilter,
+		valueFilter: query.ValueFilter,
+	}
+	// JSON field filtering needs both the key and the value
+	if query.JSONKey != "" && query.JSONValue != "" {
+		matcher.jsonKey, matcher.jsonValue = query.JSONKey, query.JSONValue
+	}
+	mode := "and"
+	if query.JSONPathAny {
+		mode = "or"
+	}
+	jsonPath, err := ParseJSONPathFilter(query.JSONPath, mode)
+	if err != nil {
+		return nil, err
+	}
+	matcher.jsonPath = jsonPath
+	if strings.TrimSpace(query.Filter) != "" {
+		expr, err := CompileFilterExpression(query.Filter)
+		if err != nil {
+			return nil, err
+		}
+		matcher.expr = expr
+	}
+	return matcher, nil
+}
+
+// empty reports whether the matcher has no filters, so every record matches.
+func (m *recordMatcher) empty() bool {
+	return m.keyFilter == "" && m.valueFilter == "" && m.jsonKey == "" && m.jsonPath == nil && m.expr == nil
+}
+
+// match applies the filters to a record decoded from msg. Keys and values are matched
+// as their payload bytes, or as the JSON of payloads decoded with a schema, so the
+// encoding a record is rendered with does not change what matches.
+func (m *recordMatcher) match(msg *sarama.ConsumerMessage, decoded *dto.MessageRecord) bool {
+	record := *decoded
+	record.Key = filterText(msg.Key, decoded.Key, decoded.KeyEncoding)
+	record.Value = filterText(msg.Value, decoded.Value, decoded.ValueEncoding)
+
+	// Apply key filter (message key contains)
+	if m.keyFilter != "" && !strings.Contains(record.Key, m.keyFilter) {
+		return false
+	}
+
+	// Apply value filter (message value contains)
+	if m.valueFilter != "" && !strings.Contains(record.Value, m.valueFilter) {
+		return false
+	}
+
+	// Apply JSON field and JSONPath filters
+	if m.jsonKey != "" || m.jsonPath != nil {
+		var jsonData interface{}
+		if err := json.Unmarshal([]byte(record.Value), &jsonData); err != nil {
+			// Not valid JSON, skip this message
+			return false
+		}
+		if m.jsonKey != "" && !findInJSON(jsonData, m.jsonKey, m.jsonValue) {
+			return false
+		}
+		if m.jsonPath != nil && !m.jsonPath.Match(jsonData) {
+			return false
+		}
+	}
+
+	return m.expr == nil || m.expr.Match(&record)
+}
+
+// filterText is what the filters see of a key or value rendered as text with encoding.
+func filterText(raw []byte, text, encoding string) string {
+	if encoding == dto.EncodingAvro || encoding == dto.EncodingProtobuf {
+		return text
+	}
+	return string(raw)
+}
+
+// readPartition collects up to query.Limit matching records from a partition range.
+func readPartition(consumer sarama.Consumer, decoder *messageDecoder, matcher *recordMatcher, topicName string, rng partitionRange, query dto.MessageQuery) partitionRead {
+	read := partitionRead{partition: rng.partition, start: rng.start, next: rng.start}
+	if rng.stop >= 0 && rng.start >= rng.stop {
+		return read
+	}
+
+	partitionConsumer, err := consumer.ConsumePartition(topicName, rng.partition, rng.start)
+	if err != nil {
+		read.err = fmt.Errorf("failed to consume partition %d: %w", rng.partition, err)
+		return read
+	}
+	defer partitionConsumer.Close()
+
+	// When filters are applied, we need to read more messages to find enough matches
+	// Set a maximum number of messages to scan to avoid infinite loops
+	maxScan := query.Limit * 100
+	if maxScan < 1000 {
+		maxScan = 1000
+	}
+	scanned := 0
+
+	var (
+		initialWait = 5 * time.Second
+		idleWait    = 200 * time.Millisecond
+		timer       = time.NewTimer(initialWait)
+	)
+	defer timer.Stop()
+
+	resetTimer := func(d time.Duration) {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(d)
+	}
+
+	for len(read.records) < query.Limit && scanned < maxScan {
+		select {
+		case msg, ok := <-partitionConsumer.Messages():
+			if !ok {
+				return read
+			}
+			if rng.stop >= 0 && msg.Offset >= rng.stop {
+				return read
+			}
+			scanned++
+			read.next = msg.Offset + 1
+			resetTimer(idleWait)
+
+			// Timestamps are not strictly ordered within a partition, so the
+			// offset window is narrowed down per record as well.
+			if !inTimeRange(msg.Timestamp.UnixMilli(), query.From, query.To) {
+				continue
+			}
+
+			record := decoder.record(msg)
+			if !matcher.match(msg, &record) {
+				continue
+			}
+
+			read.records = append(read.records, record)
+
+			if rng.stop >= 0 && read.next >= rng.stop {
+				return read
+			}
+		case <-timer.C:
+			return read
+		}
+	}
+
+	return read
+}
+
+// remainingCheck reports whether offsets from next up to stop of a partition still hold
+// records a consumer would deliver.
+type remainingCheck func(topicName string, partition int32, next, stop int64) (bool, error)
+
+// remainingIn checks the remaining offsets of partitions by fetching them from their leader.
+func remainingIn(client sarama.Client) remainingCheck {
+	return func(topicName string, partition int32, next, stop int64) (bool, error) {
+		return remainingRecords(client, topicName, partition, next, stop)
+	}
+}
+
+// remainingRecords fetches the offsets from next up to stop with the isolation level of the
+// client and reports whether any of them is a record a consumer delivers. Transaction
+// markers, aborted records and offsets past the end offset, the last stable offset for
+// read_committed, are never delivered.
+func remainingRecords(client sarama.Client, topicName string, partition int32, next, stop int64) (bool, error) {
+	isolation := client.Config().Consumer.IsolationLevel
+	for next < stop {
+		block, err := fetchBatches(client, topicName, partition, next, fetchMaxBytes, isolation)
+		if err != nil {
+			return false, err
+		}
+		end := block.HighWaterMarkOffset
+		if isolation == sarama.ReadCommitted {
+			end = block.LastStableOffset
+		}
+		if end < stop {
+			stop = end
+		}
+
+		records := batchRecords(topicName, partition, block)
+		if isolation == sarama.ReadCommitted {
+			records = dropAborted(records, block.AbortedTransactions)
+		}
+		last := next
+		for _, record := range records {
+			offset := record.message.Offset
+			if offset < next {
+				// batches are returned whole and may start before next
+				continue
+			}
+			if offset >= stop {
+				return false, nil
+			}
+			if !record.batch.Control {
+				return true, nil
+			}
+			last = offset + 1
+		}
+		if last == next {
+			// nothing left to read below the end offset
+			return false, nil
+		}
+		next = last
+	}
+	return false, nil
+}
+
+// scanRange consumes a bounded partition range and hands every record to visit. It reports
+// whether the range was exhausted. When the partition stays idle before the stop offset,
+// remaining decides: offsets that are only transaction markers, aborted records or beyond
+// the last stable offset exhaust the range, records that were not delivered are an error.
+// The scan ends early without error on cancellation or when visit returns false.
+func scanRange(ctx context.Context, consumer sarama.Consumer, remaining remainingCheck, topicName string, rng partitionRange, idleTimeout time.Duration, visit func(*sarama.ConsumerMessage) bool) (bool, error) {
+	if rng.start >= rng.stop {
+		return true, nil
+	}
+
+	partitionConsumer, err := consumer.ConsumePartition(topicName, rng.partition, rng.start)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume partition %d: %w", rng.partition, err)
+	}
+	defer partitionConsumer.Close()
+
+	idle := time.NewTimer(idleTimeout)
+	defer idle.Stop()
+
+	next := rng.start
+	for {
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case <-idle.C:
+			pending, err := remaining(topicName, rng.partition, next, rng.stop)
+			if err != nil {
+				return false, fmt.Errorf("partition %d stalled at offset %d: %w", rng.partition, next, err)
+			}
+			if pending {
+				return false, fmt.Errorf("partition %d stalled at offset %d with records left before offset %d", rng.partition, next, rng.stop)
+			}
+			return true, nil
+		case msg, ok := <-partitionConsumer.Messages():
+			if !ok {
+				return false, nil
+			}
+			if msg.Offset >= rng.stop {
+				return true, nil
+			}
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(idleTimeout)
+
+			next = msg.Offset + 1
+			if !visit(msg) {
+				return false, nil
+			}
+			if msg.Offset+1 >= rng.stop {
+				return true, nil
+			}
+		}
+	}
+}
+
+// readPartitionBackward collects up to query.Limit matching records right before rng.stop.
+// It reads forward through growing windows below the stop offset until enough records
+// matched, the floor was reached or the scan budget ran out.
+func readPartitionBackward(consumer sarama.Consumer, decoder *messageDecoder, matcher *recordMatcher, topicName string, rng partitionRange, query dto.MessageQuery) partitionRead {
+	read := partitionRead{partition: rng.partition, start: rng.stop, next: rng.stop}
+
+	maxScan := int64(query.Limit) * 100
+	if maxScan < 1000 {
+		maxScan = 1000
+	}
+
+	window := int64(query.Limit)
+	upper := rng.stop
+	var scanned int64
+	for upper > rng.floor && len(read.records) < query.Limit && scanned < maxScan {
+		lower := upper - window
+		if lower < rng.floor {
+			lower = rng.floor
+		}
+
+		windowQuery := query
+		windowQuery.Limit = int(upper - lower)
+		chunk := readPartition(consumer, decoder, matcher, topicName, partitionRange{partition: rng.partition, start: lower, stop: upper}, windowQuery)
+		if chunk.err != nil {
+			read.err = chunk.err
+			return read
+		}
+
+		read.records = append(chunk.records, read.records...)
+		read.start = lower
+		scanned += upper - lower
+		upper = lower
+		window *= 4
+	}
+
+	if len(read.records) > query.Limit {
+		read.records = read.records[len(read.records)-query.Limit:]
+		read.start = read.records[0].Offset
+	}
+	return read
+}
+
+// mergePartitionReads merges per-partition results by timestamp and keeps the first limit
+// records, or the last limit records when paging backward. The page lists the newest
+// records first, by timestamp or, for a single partition, by offset. It returns the page with the
+// positions of the next page, followed by the positions of the previous page; neither
+// skips a record that did not fit on this page.
+func mergePartitionReads(reads []partitionRead, limit int, backward bool) (*dto.MessagePage, []dto.PartitionPosition) {
+	var merged []dto.MessageRecord
+	for _, read := range reads {
+		merged = append(merged, read.records...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Timestamp != merged[j].Timestamp {
+			return merged[i].Timestamp < merged[j].Timestamp
+		}
+		if merged[i].Partition != merged[j].Partition {
+			return merged[i].Partition < merged[j].Partition
+		}
+		return merged[i].Offset < merged[j].Offset
+	})
+
+	if len(merged) > limit {
+		if backward {
+			merged = merged[len(merged)-limit:]
+		} else {
+			merged = merged[:limit]
+		}
+	}
+
+	type recordID struct {
+		partition int32
+		offset    int64
+	}
+	taken := make(map[recordID]struct{}, len(merged))
+	for _, record := range merged {
+		taken[recordID{record.Partition, record.Offset}] = struct{}{}
+	}
+
+	next := make([]dto.PartitionPosition, 0, len(reads))
+	prev := make([]dto.PartitionPosition, 0, len(reads))
+	for _, read := range reads {
+		nextOffset, prevOffset := read.next, read.start
+		firstTaken, firstUntaken, lastUntaken := int64(-1), int64(-1), int64(-1)
+		for _, record := range read.records {
+			if _, ok := taken[recordID{record.Partition, record.Offset}]; ok {
+				if firstTaken < 0 {
+					firstTaken = record.Offset
+				}
+				continue
+			}
+			if firstUntaken < 0 {
+				firstUntaken = record.Offset
+			}
+			lastUntaken = record.Offset
+		}
+
+		if backward {
+			// records left out are older than the page
+			if lastUntaken >= 0 {
+				prevOffset = lastUntaken + 1
+			} else if firstTaken >= 0 {
+				prevOffset = firstTaken
+			}
+		} else {
+			// records left out are newer than the page
+			if firstUntaken >= 0 {
+				nextOffset = firstUntaken
+			}
+			if firstTaken >= 0 {
+				prevOffset = firstTaken
+			}
+		}
+
+		next = append(next, dto.PartitionPosition{Partition: read.partition, Offset: nextOffset})
+		prev = append(prev, dto.PartitionPosition{Partition: read.partition, Offset: prevOffset})
+	}
+	sort.Slice(next, func(i, j int) bool {
+		return next[i].Partition < next[j].Partition
+	})
+	sort.Slice(prev, func(i, j int) bool {
+		return prev[i].Partition < prev[j].Partition
+	})
+
+	// a single partition is shown in offset order rather than by timestamp
+	if len(reads) == 1 {
+		sort.Slice(merged, func(i, j int) bool {
+			return merged[i].Offset > merged[j].Offset
+		})
+	} else {
+		for i, j := 0, len(merged)-1; i < j; i, j = i+1, j-1 {
+			merged[i], merged[j] = merged[j], merged[i]
+		}
+	}
+	if merged == nil {
+		merged = []dto.MessageRecord{}
+	}
+
+	return &dto.MessagePage{Messages: merged, Partitions: next}, prev
+}
+
+// offsetForTime resolves the first offset whose timestamp is at or after the given
+// Unix millis. Kafka answers -1 when no such record exists, which maps to the end offset.
+func offsetForTime(client sarama.Client, topicName string, partition int32, timestamp int64) (int64, error) {
+	offset, err := client.GetOffset(topicName, partition, timestamp)
+	if err != nil {
+		return -1, fmt.Errorf("failed to look up offset for timestamp %d: %w", timestamp, err)
+	}
+	if offset >= 0 {
+		return offset, nil
+	}
+	end, err := client.GetOffset(topicName, partition, sarama.OffsetNewest)
+	if err != nil {
+		return -1, fmt.Errorf("failed to get end offset: %w", err)
+	}
+	return end, nil
+}
+
+// inTimeRange reports whether a Unix millis timestamp falls inside [from, to]; zero bounds are open.
+func inTimeRange(timestamp, from, to int64) bool {
+	if from > 0 && timestamp < from {
+		return false
+	}
+	if to > 0 && timestamp > to {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+)
+
+func TestMessageCursorRoundTrip(t *testing.T) {
+	cursor := dto.MessageCursor{
+		Partition: dto.AllPartitions,
+		Direction: dto.CursorPrev,
+		Offsets:   map[int32]int64{0: 12, 3: 40},
+		From:      1700000000000,
+		KeyFilter: "user-",
+		JSONPath:  []string{"$.status == 'NEW'"},
+	}
+
+	got, err := DecodeMessageCursor(EncodeMessageCursor(cursor))
+	if err != nil {
+		t.Fatalf("DecodeMessageCursor() error = %v", err)
+	}
+	if got.Partition != cursor.Partition || got.Direction != cursor.Direction {
+		t.Fatalf("DecodeMessageCursor() = %+v, want %+v", got, cursor)
+	}
+	if len(got.Offsets) != 2 || got.Offsets[0] != 12 || got.Offsets[3] != 40 {
+		t.Fatalf("Offsets = %v, want %v", got.Offsets, cursor.Offsets)
+	}
+
+	// the filters of the cursor replace whatever came with the request
+	query := dto.MessageQuery{Partition: 2, KeyFilter: "other"}
+	ApplyMessageCursor(&query, got)
+	if query.Partition != dto.AllPartitions || !query.Backward || query.From != cursor.From ||
+		query.KeyFilter != "user-" || len(query.JSONPath) != 1 {
+		t.Fatalf("ApplyMessageCursor() = %+v, want the partition, direction and filters of %+v", query, cursor)
+	}
+}
+
+func TestDecodeMessageCursorRejectsGarbage(t *testing.T) {
+	for _, token := range []string{"not-a-cursor", EncodeMessageCursor(dto.MessageCursor{Direction: "sideways"})} {
+		if _, err := DecodeMessageCursor(token); err == nil {
+			t.Fatalf("DecodeMessageCursor(%q) error = nil, want error", token)
+		}
+	}
+}
+
+func TestRecordMatcherMatchesPayloadBytes(t *testing.T) {
+	msg := &sarama.ConsumerMessage{Key: []byte("user-7"), Value: []byte(`{"status":"paid"}`)}
+
+	tests := []struct {
+		name     string
+		query    dto.MessageQuery
+		encoding string
+		want     bool
+	}{
+		{name: "key filter on hex", query: dto.MessageQuery{KeyFilter: "user-"}, encoding: dto.EncodingHex, want: true},
+		{name: "value filter on base64", query: dto.MessageQuery{ValueFilter: "paid"}, encoding: dto.EncodingBase64, want: true},
+		{name: "hex text does not match", query: dto.MessageQuery{KeyFilter: "75736572"}, encoding: dto.EncodingHex, want: false},
+		{name: "json path on hex", query: dto.MessageQuery{JSONPath: []string{"$.status == 'paid'"}}, encoding: dto.EncodingHex, want: true},
+		{name: "expression on base64", query: dto.MessageQuery{Filter: "value.status == 'paid'"}, encoding: dto.EncodingBase64, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := newRecordMatcher(tt.query)
+			if err != nil {
+				t.Fatalf("newRecordMatcher() error = %v", err)
+			}
+			record := newMessageDecoder(&model.Cluster{}, nil, nil, tt.encoding).record(msg)
+			if got := matcher.match(msg, &record); got != tt.want {
+				t.Fatalf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// noRemaining is a remainingCheck for mocked consumers: nothing is left after the last record.
+func noRemaining(string, int32, int64, int64) (bool, error) {
+	return false, nil
+}
+
+func TestScanRangeStopsOnIdlePartition(t *testing.T) {
+	tests := []struct {
+		name          string
+		remaining     remainingCheck
+		wantExhausted bool
+		wantErr       bool
+	}{
+		{name: "only markers left", remaining: noRemaining, wantExhausted: true},
+		{
+			name:      "records left",
+			remaining: func(string, int32, int64, int64) (bool, error) { return true, nil },
+			wantErr:   true,
+		},
+		{
+			name:      "leader unavailable",
+			remaining: func(string, int32, int64, int64) (bool, error) { return false, sarama.ErrNotLeaderForPartition },
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := mocks.NewConsumer(t, nil)
+			partitionConsumer := consumer.ExpectConsumePartition("orders", 0, 0)
+			partitionConsumer.YieldMessage(&sarama.ConsumerMessage{Value: []byte("a")})
+
+			var next int64 = -1
+			remaining := func(topicName string, partition int32, from, stop int64) (bool, error) {
+				next = from
+				return tt.remaining(topicName, partition, from, stop)
+			}
+			exhausted, err := scanRange(context.Background(), consumer, remaining, "orders", partitionRange{start: 0, stop: 5}, 20*time.Millisecond,
+				func(*sarama.ConsumerMessage) bool { return true })
+			if (err != nil) != tt.wantErr || exhausted != tt.wantExhausted {
+				t.Fatalf("scanRange() = %v, %v, want exhausted %v and error %v", exhausted, err, tt.wantExhausted, tt.wantErr)
+			}
+			if next != 1 {
+				t.Fatalf("remaining offsets checked from %d, want 1", next)
+			}
+		})
+	}
+}
+
+func TestRemainingRecords(t *testing.T) {
+	tests := []struct {
+		name      string
+		isolation sarama.IsolationLevel
+		fill      func(response *sarama.FetchResponse)
+		want      bool
+	}{
+		{
+			name: "commit marker",
+			fill: func(response *sarama.FetchResponse) {
+				response.AddRecordBatch("orders", 0, nil, sarama.StringEncoder("read"), 1, 7, true)
+				response.AddControlRecord("orders", 0, 2, 7, sarama.ControlRecordCommit)
+			},
+		},
+		{
+			name: "record after a marker",
+			fill: func(response *sarama.FetchResponse) {
+				response.AddControlRecord("orders", 0, 2, 7, sarama.ControlRecordCommit)
+				response.AddRecordBatch("orders", 0, nil, sarama.StringEncoder("missed"), 3, -1, false)
+			},
+			want: true,
+		},
+		{
+			name:      "aborted transaction",
+			isolation: sarama.ReadCommitted,
+			fill: func(response *sarama.FetchResponse) {
+				response.AddRecordBatch("orders", 0, nil, sarama.StringEncoder("aborted"), 2, 7, true)
+				response.AddControlRecord("orders", 0, 3, 7, sarama.ControlRecordAbort)
+				response.GetBlock("orders", 0).AbortedTransactions = []*sarama.AbortedTransaction{{ProducerID: 7, FirstOffset: 2}}
+			},
+		},
+		{
+			name:      "open transaction past the last stable offset",
+			isolation: sarama.ReadCommitted,
+			fill: func(response *sarama.FetchResponse) {
+				response.AddRecordBatch("orders", 0, nil, sarama.StringEncoder("open"), 2, 7, true)
+				response.SetLastStableOffset("orders", 0, 2)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &sarama.FetchResponse{Version: 11}
+			tt.fill(response)
+			block := response.GetBlock("orders", 0)
+			block.HighWaterMarkOffset = 4
+			if tt.isolation == sarama.ReadUncommitted {
+				block.LastStableOffset = 4
+			}
+
+			broker := sarama.NewMockBroker(t, 1)
+			defer broker.Close()
+			broker.SetHandlerByMap(map[string]sarama.MockResponse{
+				"MetadataRequest": sarama.NewMockMetadataResponse(t).
+					SetBroker(broker.Addr(), broker.BrokerID()).
+					SetLeader("orders", 0, broker.BrokerID()),
+				"FetchRequest": sarama.NewMockWrapper(response),
+			})
+
+			config := sarama.NewConfig()
+			config.ApiVersionsRequest = false
+			config.Version = sarama.V3_4_0_0
+			config.Consumer.IsolationLevel = tt.isolation
+			client, err := sarama.NewClient([]string{broker.Addr()}, config)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			defer client.Close()
+
+			got, err := remainingRecords(client, "orders", 0, 2, 4)
+			if err != nil {
+				t.Fatalf("remainingRecords() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("remainingRecords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+)
+
+// MaxProduceRecords bounds the records sent in one request.
+const MaxProduceRecords = 1000
+
+// targetPartition marks a message whose partition was chosen by the caller.
+type targetPartition int32
+
+// explicitPartitioner honors a partition chosen by the caller and otherwise hashes
+// the key like the default partitioner, so explicit and keyed records can share a batch.
+type explicitPartitioner struct {
+	hash sarama.Partitioner
+}
+
+func newExplicitPartitioner(topic string) sarama.Partitioner {
+	return &explicitPartitioner{hash: sarama.NewHashPartitioner(topic)}
+}
+
+func (p *explicitPartitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if target, ok := msg.Metadata.(targetPartition); ok {
+		if int32(target) >= numPartitions {
+			return -1, fmt.Errorf("partition %d does not exist, the topic has %d partitions", target, numPartitions)
+		}
+		return int32(target), nil
+	}
+	return p.hash.Partition(msg, numPartitions)
+}
+
+func (p *explicitPartitioner) RequiresConsistency() bool {
+	return true
+}
+
+// produceRecords returns the records of a send request: the batch, or the single record.
+func produceRecords(req *dto.SendMessageRequest) []dto.ProduceRecord {
+	if len(req.Records) > 0 {
+		return req.Records
+	}
+	return []dto.ProduceRecord{req.ProduceRecord}
+}
+
+// parseCompression resolves a compression codec name, none when empty.
+func parseCompression(name string) (sarama.CompressionCodec, error) {
+	codec := sarama.CompressionNone
+	if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+		if err := codec.UnmarshalText([]byte(name)); err != nil {
+			return codec, fmt.Errorf("invalid compression %q, expected none, gzip, snappy, lz4 or zstd", name)
+		}
+	}
+	return codec, nil
+}
+
+// ValidateSendMessage checks a send request before anything is produced.
+func ValidateSendMessage(req *dto.SendMessageRequest) error {
+	if len(req.Records) > MaxProduceRecords {
+		return fmt.Errorf("at most %d records can be sent at once", MaxProduceRecords)
+	}
+	if _, err := parseCompression(req.Compression); err != nil {
+		return err
+	}
+	for i, record := range produceRecords(req) {
+		if err := validateProduceRecord(record); err != nil {
+			return fmt.Errorf("record %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateProduceRecord(record dto.ProduceRecord) error {
+	if record.Tombstone {
+		if record.Value != nil {
+			return errors.New("a tombstone has no value")
+		}
+		if record.Key == "" {
+			return errors.New("a tombstone needs a key")
+		}
+	} else if record.Value == nil {
+		return errors.New("value is required, set tombstone to write a null value")
+	}
+	if record.Partition != nil && *record.Partition < 0 {
+		return errors.New("partition must not be negative")
+	}
+	if record.Timestamp < 0 {
+		return errors.New("timestamp must not be negative")
+	}
+	return nil
+}
+
+func produceMessages(topic string, records []dto.ProduceRecord) []*sarama.ProducerMessage {
+	messages := make([]*sarama.ProducerMessage, 0, len(records))
+	for _, record := range records {
+		msg := &sarama.ProducerMessage{Topic: topic}
+		if record.Key != "" {
+			msg.Key = sarama.StringEncoder(record.Key)
+		}
+		// a tombstone is written with a null value, which compaction treats as a delete
+		if !record.Tombstone {
+			msg.Value = sarama.StringEncoder(*record.Value)
+		}
+		for key, value := range record.Headers {
+			msg.Headers = append(msg.Headers, sarama.RecordHeader{
+				Key:   []byte(key),
+				Value: []byte(value),
+			})
+		}
+		if record.Partition != nil {
+			msg.Metadata = targetPartition(*record.Partition)
+		}
+		if record.Timestamp > 0 {
+			msg.Timestamp = time.UnixMilli(record.Timestamp)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// sendMessages produces the messages in one call and reports where each one landed.
+// Failures of single records are part of the result; only other errors are returned.
+func sendMessages(producer sarama.SyncProducer, messages []*sarama.ProducerMessage) (*dto.SendMessageResult, error) {
+	failed := make(map[*sarama.ProducerMessage]error)
+	if err := producer.SendMessages(messages); err != nil {
+		var produceErrs sarama.ProducerErrors
+		if !errors.As(err, &produceErrs) {
+			return nil, fmt.Errorf("failed to send messages: %w", err)
+		}
+		for _, produceErr := range produceErrs {
+			failed[produceErr.Msg] = produceErr.Err
+		}
+	}
+
+	result := &dto.SendMessageResult{Records: make([]dto.ProducedRecord, 0, len(messages))}
+	for _, msg := range messages {
+		record := dto.ProducedRecord{Partition: msg.Partition, Offset: msg.Offset}
+		if err, ok := failed[msg]; ok {
+			record.Offset = -1
+			record.Error = err.Error()
+			result.Failed++
+		}
+		result.Records = append(result.Records, record)
+	}
+	return result, nil
+}
+
+// SendMessage sends one record or a batch of records to a topic
+func (s *TopicService) SendMessage(clusterID uint, topicName string, req *dto.SendMessageRequest) (*dto.SendMessageResult, error) {
+	if err := ValidateSendMessage(req); err != nil {
+		return nil, err
+	}
+	codec, _ := parseCompression(req.Compression)
+
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	producer, err := s.createRecordProducer(cluster, codec)
+	if err != nil {
+		return nil, err
+	}
+	defer producer.Close()
+
+	return sendMessages(producer, produceMessages(topicName, produceRecords(req)))
+}
+
+// createRecordProducer creates a producer that honors the partition chosen for each record.
+func (s *TopicService) createRecordProducer(cluster *model.Cluster, codec sarama.CompressionCodec) (sarama.SyncProducer, error) {
+	return s.kafkaManager.CreateProducer(cluster, func(config *sarama.Config) {
+		config.Producer.Compression = codec
+		config.Producer.Partitioner = newExplicitPartitioner
+	})
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+// partialProducer places every message on its partition and fails the ones listed.
+type partialProducer struct {
+	sarama.SyncProducer
+	partitioner sarama.Partitioner
+	partitions  int32
+	fail        map[int]error
+}
+
+func (p *partialProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	var errs sarama.ProducerErrors
+	for i, msg := range msgs {
+		partition, err := p.partitioner.Partition(msg, p.partitions)
+		if err == nil {
+			err = p.fail[i]
+		}
+		if err != nil {
+			errs = append(errs, &sarama.ProducerError{Msg: msg, Err: err})
+			continue
+		}
+		msg.Partition = partition
+		msg.Offset = int64(100 + i)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func TestValidateSendMessage(t *testing.T) {
+	value := "v"
+	negative := int32(-1)
+
+	tests := []struct {
+		name    string
+		req     dto.SendMessageRequest
+		wantErr bool
+	}{
+		{name: "single record", req: dto.SendMessageRequest{ProduceRecord: dto.ProduceRecord{Value: &value}}},
+		{name: "tombstone with key", req: dto.SendMessageRequest{ProduceRecord: dto.ProduceRecord{Key: "k", Tombstone: true}}},
+		{name: "tombstone without key", req: dto.SendMessageRequest{ProduceRecord: dto.ProduceRecord{Tombstone: true}}, wantErr: true},
+		{name: "tombstone with value", req: dto.SendMessageRequest{ProduceRecord: dto.ProduceRecord{Key: "k", Value: &value, Tombstone: true}}, wantErr: true},
+		{name: "missing value", req: dto.SendMessageRequest{ProduceRecord: dto.ProduceRecord{Key: "k"}}, wantErr: true},
+		{name: "batch tombstone without key", req: dto.SendMessageRequest{Records: []dto.ProduceRecord{{Value: &value}, {Tombstone: true}}}, wantErr: true},
+		{name: "negative partition", req: dto.SendMessageRequest{ProduceRecord: dto.ProduceRecord{Value: &value, Partition: &negative}}, wantErr: true},
+		{name: "compression", req: dto.SendMessageRequest{ProduceRecord: dto.ProduceRecord{Value: &value}, Compression: "ZSTD"}},
+		{name: "unknown compression", req: dto.SendMessageRequest{ProduceRecord: dto.ProduceRecord{Value: &value}, Compression: "brotli"}, wantErr: true},
+		{name: "batch too large", req: dto.SendMessageRequest{Records: make([]dto.ProduceRecord, MaxProduceRecords+1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateSendMessage(&tt.req); (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateSendMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProduceMessages(t *testing.T) {
+	value := "paid"
+	partition := int32(2)
+	messages := produceMessages("orders", []dto.ProduceRecord{
+		{Key: "a", Value: &value, Partition: &partition, Timestamp: 1_700_000_000_000, Headers: map[string]string{"trace": "x"}},
+		{Key: "b", Tombstone: true},
+	})
+
+	first := messages[0]
+	if first.Metadata != targetPartition(2) || !first.Timestamp.Equal(time.UnixMilli(1_700_000_000_000)) || len(first.Headers) != 1 {
+		t.Fatalf("first message = %+v, want partition 2, the timestamp and one header", first)
+	}
+	if tombstone := messages[1]; tombstone.Value != nil || tombstone.Metadata != nil || !tombstone.Timestamp.IsZero() {
+		t.Fatalf("tombstone = %+v, want a nil value with no partition or timestamp", tombstone)
+	}
+}
+
+func TestSendMessagesReportsEachRecord(t *testing.T) {
+	value := "v"
+	first, missing := int32(1), int32(5)
+	records := []dto.ProduceRecord{
+		{Value: &value, Partition: &first},
+		{Value: &value, Partition: &missing},
+		{Key: "k", Value: &value},
+		{Key: "k", Value: &value},
+	}
+	producer := &partialProducer{
+		partitioner: newExplicitPartitioner("orders"),
+		partitions:  3,
+		fail:        map[int]error{3: errors.New("not enough replicas")},
+	}
+
+	result, err := sendMessages(producer, produceMessages("orders", records))
+	if err != nil {
+		t.Fatalf("sendMessages() error = %v", err)
+	}
+	if result.Failed != 2 || len(result.Records) != 4 {
+		t.Fatalf("result = %+v, want 4 records with 2 failures", result)
+	}
+	if got := result.Records[0]; got.Partition != 1 || got.Offset != 100 || got.Error != "" {
+		t.Fatalf("records[0] = %+v, want partition 1 offset 100", got)
+	}
+	if got := result.Records[1]; got.Offset != -1 || got.Error == "" {
+		t.Fatalf("records[1] = %+v, want an error for the missing partition", got)
+	}
+	if got := result.Records[2]; got.Offset != 102 || got.Partition < 0 || got.Partition >= 3 {
+		t.Fatalf("records[2] = %+v, want a hashed partition at offset 102", got)
+	}
+	if got := result.Records[3]; got.Error != "not enough replicas" {
+		t.Fatalf("records[3] = %+v, want the producer error", got)
+	}
+}
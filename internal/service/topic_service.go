@@ -1,7 +1,6 @@
 package service
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -9,7 +8,6 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
@@ -22,13 +20,17 @@ type TopicService struct {
 	clusterRepo    *repository.ClusterRepository
 	topicStatsRepo *repository.TopicStatsRepository
 	kafkaManager   *util.KafkaClientManager
+	schemaRegistry *util.SchemaRegistryClient
+	protoService   *ProtoService
 }
 
-func NewTopicService(clusterRepo *repository.ClusterRepository, topicStatsRepo *repository.TopicStatsRepository, kafkaManager *util.KafkaClientManager) *TopicService {
+func NewTopicService(clusterRepo *repository.ClusterRepository, topicStatsRepo *repository.TopicStatsRepository, kafkaManager *util.KafkaClientManager, schemaRegistry *util.SchemaRegistryClient, protoService *ProtoService) *TopicService {
 	return &TopicService{
 		clusterRepo:    clusterRepo,
 		topicStatsRepo: topicStatsRepo,
 		kafkaManager:   kafkaManager,
+		schemaRegistry: schemaRegistry,
+		protoService:   protoService,
 	}
 }
 
@@ -658,197 +660,6 @@ func (s *TopicService) UpdateTopicConfigs(clusterID uint, topicName string, conf
 	return nil
 }
 
-// findInJSON recursively searches for a key-value pair in a JSON structure.
-// Returns true if the key exists anywhere in the structure with a value that contains the search string.
-func findInJSON(data interface{}, key, value string) bool {
-	switch v := data.(type) {
-	case map[string]interface{}:
-		for k, val := range v {
-			if k == key {
-				// Check if value contains the search string (fuzzy match)
-				valStr := fmt.Sprintf("%v", val)
-				if strings.Contains(valStr, value) {
-					return true
-				}
-			}
-			// Recursively search nested structures
-			if findInJSON(val, key, value) {
-				return true
-			}
-		}
-	case []interface{}:
-		for _, item := range v {
-			if findInJSON(item, key, value) {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-// GetMessages retrieves messages from a topic with optional filtering
-func (s *TopicService) GetMessages(clusterID uint, topicName string, partition int32, offset int64, limit int, keyFilter, valueFilter, jsonKey, jsonValue string) ([]dto.MessageRecord, error) {
-	cluster, err := s.clusterRepo.FindByID(clusterID)
-	if err != nil {
-		return nil, err
-	}
-
-	consumer, err := s.kafkaManager.CreateConsumer(cluster)
-	if err != nil {
-		return nil, err
-	}
-	defer consumer.Close()
-
-	partitionConsumer, err := consumer.ConsumePartition(topicName, partition, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to consume partition: %w", err)
-	}
-	defer partitionConsumer.Close()
-
-	// When filters are applied, we need to read more messages to find enough matches
-	// Set a maximum number of messages to scan to avoid infinite loops
-	maxScan := limit * 100
-	if maxScan < 1000 {
-		maxScan = 1000
-	}
-	scanned := 0
-
-	// Check if JSON filtering is enabled (both key and value must be provided)
-	jsonFilterEnabled := jsonKey != "" && jsonValue != ""
-
-	var (
-		messages    []dto.MessageRecord
-		initialWait = 5 * time.Second
-		idleWait    = 200 * time.Millisecond
-		timer       = time.NewTimer(initialWait)
-	)
-	defer timer.Stop()
-
-	resetTimer := func(d time.Duration) {
-		if !timer.Stop() {
-			select {
-			case <-timer.C:
-			default:
-			}
-		}
-		timer.Reset(d)
-	}
-
-	for len(messages) < limit && scanned < maxScan {
-		select {
-		case msg, ok := <-partitionConsumer.Messages():
-			if !ok {
-				// 按 timestamp 降序排序，确保最新的消息在最前面
-				sort.Slice(messages, func(i, j int) bool {
-					return messages[i].Timestamp > messages[j].Timestamp
-				})
-				return messages, nil
-			}
-			scanned++
-
-			keyStr := string(msg.Key)
-			valueStr := string(msg.Value)
-
-			// Apply key filter (message key contains)
-			if keyFilter != "" && !strings.Contains(keyStr, keyFilter) {
-				resetTimer(idleWait)
-				continue
-			}
-
-			// Apply value filter (message value contains)
-			if valueFilter != "" && !strings.Contains(valueStr, valueFilter) {
-				resetTimer(idleWait)
-				continue
-			}
-
-			// Apply JSON field filter
-			if jsonFilterEnabled {
-				var jsonData interface{}
-				if err := json.Unmarshal(msg.Value, &jsonData); err != nil {
-					// Not valid JSON, skip this message
-					resetTimer(idleWait)
-					continue
-				}
-				if !findInJSON(jsonData, jsonKey, jsonValue) {
-					resetTimer(idleWait)
-					continue
-				}
-			}
-
-			headers := make(map[string]string, len(msg.Headers))
-			for _, header := range msg.Headers {
-				headers[string(header.Key)] = string(header.Value)
-			}
-			messages = append(messages, dto.MessageRecord{
-				Partition: msg.Partition,
-				Offset:    msg.Offset,
-				Key:       keyStr,
-				Value:     valueStr,
-				Timestamp: msg.Timestamp.UnixMilli(),
-				Headers:   headers,
-			})
-
-			if len(messages) >= limit {
-				// 按 timestamp 降序排序，确保最新的消息在最前面
-				sort.Slice(messages, func(i, j int) bool {
-					return messages[i].Timestamp > messages[j].Timestamp
-				})
-				return messages, nil
-			}
-
-			resetTimer(idleWait)
-		case <-timer.C:
-			// 按 timestamp 降序排序，确保最新的消息在最前面
-			sort.Slice(messages, func(i, j int) bool {
-				return messages[i].Timestamp > messages[j].Timestamp
-			})
-			return messages, nil
-		}
-	}
-
-	// 按 timestamp 降序排序，确保最新的消息在最前面
-	sort.Slice(messages, func(i, j int) bool {
-		return messages[i].Timestamp > messages[j].Timestamp
-	})
-
-	return messages, nil
-}
-
-// SendMessage sends a message to a topic
-func (s *TopicService) SendMessage(clusterID uint, topicName string, req *dto.SendMessageRequest) error {
-	cluster, err := s.clusterRepo.FindByID(clusterID)
-	if err != nil {
-		return err
-	}
-
-	producer, err := s.kafkaManager.CreateProducer(cluster)
-	if err != nil {
-		return err
-	}
-	defer producer.Close()
-
-	msg := &sarama.ProducerMessage{
-		Topic: topicName,
-		Value: sarama.StringEncoder(req.Value),
-	}
-
-	if req.Key != "" {
-		msg.Key = sarama.StringEncoder(req.Key)
-	}
-
-	for key, value := range req.Headers {
-		msg.Headers = append(msg.Headers, sarama.RecordHeader{
-			Key:   []byte(key),
-			Value: []byte(value),
-		})
-	}
-
-	if _, _, err := producer.SendMessage(msg); err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
-	}
-	return nil
-}
-
 func (s *TopicService) getClusterAndAdmin(clusterID uint) (*model.Cluster, sarama.ClusterAdmin, error) {
 	cluster, err := s.clusterRepo.FindByID(clusterID)
 	if err != nil {
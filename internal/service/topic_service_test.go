@@ -0,0 +1,108 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+)
+
+func TestMergePartitionReadsKeepsEarliestAndContinuesPerPartition(t *testing.T) {
+	reads := []partitionRead{
+		{
+			partition: 0,
+			start:     10,
+			next:      13,
+			records: []dto.MessageRecord{
+				{Partition: 0, Offset: 10, Timestamp: 100},
+				{Partition: 0, Offset: 11, Timestamp: 300},
+				{Partition: 0, Offset: 12, Timestamp: 500},
+			},
+		},
+		{
+			partition: 1,
+			start:     4,
+			next:      6,
+			records: []dto.MessageRecord{
+				{Partition: 1, Offset: 4, Timestamp: 200},
+				{Partition: 1, Offset: 5, Timestamp: 600},
+			},
+		},
+		{partition: 2, start: 30, next: 40},
+	}
+
+	page, prev := mergePartitionReads(reads, 3, false)
+
+	if len(page.Messages) != 3 {
+		t.Fatalf("len(Messages) = %d, want 3", len(page.Messages))
+	}
+	if page.Messages[0].Timestamp != 300 || page.Messages[2].Timestamp != 100 {
+		t.Fatalf("Messages are not ordered newest first: %+v", page.Messages)
+	}
+
+	wantNext := map[int32]int64{0: 12, 1: 5, 2: 40}
+	for _, position := range page.Partitions {
+		if position.Offset != wantNext[position.Partition] {
+			t.Fatalf("partition %d next position = %d, want %d", position.Partition, position.Offset, wantNext[position.Partition])
+		}
+	}
+	wantPrev := map[int32]int64{0: 10, 1: 4, 2: 30}
+	for _, position := range prev {
+		if position.Offset != wantPrev[position.Partition] {
+			t.Fatalf("partition %d prev position = %d, want %d", position.Partition, position.Offset, wantPrev[position.Partition])
+		}
+	}
+}
+
+func TestMergePartitionReadsBackwardKeepsNewest(t *testing.T) {
+	reads := []partitionRead{
+		{
+			partition: 0,
+			start:     0,
+			next:      2,
+			records: []dto.MessageRecord{
+				{Partition: 0, Offset: 0, Timestamp: 100},
+				{Partition: 0, Offset: 1, Timestamp: 400},
+			},
+		},
+		{
+			partition: 1,
+			start:     0,
+			next:      1,
+			records: []dto.MessageRecord{
+				{Partition: 1, Offset: 0, Timestamp: 300},
+			},
+		},
+	}
+
+	page, prev := mergePartitionReads(reads, 2, true)
+
+	if len(page.Messages) != 2 || page.Messages[0].Timestamp != 400 || page.Messages[1].Timestamp != 300 {
+		t.Fatalf("Messages = %+v, want timestamps 400 and 300", page.Messages)
+	}
+	if page.Partitions[0].Offset != 2 || page.Partitions[1].Offset != 1 {
+		t.Fatalf("next positions = %+v, want end positions", page.Partitions)
+	}
+	if prev[0].Offset != 1 || prev[1].Offset != 0 {
+		t.Fatalf("prev positions = %+v, want [1 0]", prev)
+	}
+}
+
+func TestMergePartitionReadsSinglePartitionNewestOffsetFirst(t *testing.T) {
+	reads := []partitionRead{
+		{
+			partition: 0,
+			start:     7,
+			next:      9,
+			records: []dto.MessageRecord{
+				{Partition: 0, Offset: 7, Timestamp: 500},
+				{Partition: 0, Offset: 8, Timestamp: 100},
+			},
+		},
+	}
+
+	page, _ := mergePartitionReads(reads, 10, false)
+
+	if page.Messages[0].Offset != 8 || page.Messages[1].Offset != 7 {
+		t.Fatalf("Messages = %+v, want descending offset order", page.Messages)
+	}
+}
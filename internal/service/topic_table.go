@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/repository"
+	"github.com/bingfengfeifei/kafka-map-go/internal/util"
+)
+
+const (
+	// maxTableKeys bounds the distinct keys held for the table view of one topic.
+	maxTableKeys = 200000
+	// maxTableBytes bounds the keys, values and headers held for the table view of one topic.
+	maxTableBytes = 64 << 20
+	// maxCachedTables and maxCachedTableBytes bound the table views kept in memory, the
+	// least recently used goes first.
+	maxCachedTables     = 16
+	maxCachedTableBytes = 256 << 20
+	// tableIdleTimeout ends a partition scan that stopped receiving records before its end offset.
+	tableIdleTimeout = 10 * time.Second
+)
+
+// ErrTopicNotCompacted is returned for a table view of a topic without cleanup.policy=compact.
+var ErrTopicNotCompacted = errors.New("topic is not compacted")
+
+// TopicTableService materializes compacted topics as tables of the latest value per key.
+// Tables are cached and rebuilt once the start or end offsets of the topic move.
+type TopicTableService struct {
+	clusterRepo  *repository.ClusterRepository
+	kafkaManager *util.KafkaClientManager
+	topicService *TopicService
+	tables       map[string]*tableCache
+	mu           sync.Mutex
+}
+
+func NewTopicTableService(clusterRepo *repository.ClusterRepository, kafkaManager *util.KafkaClientManager, topicService *TopicService) *TopicTableService {
+	return &TopicTableService{
+		clusterRepo:  clusterRepo,
+		kafkaManager: kafkaManager,
+		topicService: topicService,
+		tables:       make(map[string]*tableCache),
+	}
+}
+
+// tableCache holds the table view of one topic. Its lock is held while the table is built,
+// so concurrent requests for a topic wait for one scan instead of starting their own.
+type tableCache struct {
+	mu    sync.Mutex
+	table *topicTable
+	used  time.Time // guarded by the service lock
+	size  int64     // bytes of the table, guarded by the service lock
+}
+
+// partitionBounds are the log start and end offsets of a partition.
+type partitionBounds struct {
+	start int64
+	end   int64
+}
+
+// topicTable is a built table view: the latest live record of every key, sorted by key.
+type topicTable struct {
+	bounds     map[int32]partitionBounds
+	records    []dto.MessageRecord
+	size       int64 // bytes of the rendered keys, values and headers
+	tombstoned int64
+	nullKeys   int64
+	scanned    int64
+	builtAt    time.Time
+}
+
+// tableScan is the latest record of every key seen while scanning a topic.
+type tableScan struct {
+	latest   map[string]*sarama.ConsumerMessage
+	bytes    int64 // bytes of the latest records
+	nullKeys int64
+	scanned  int64
+}
+
+// messageSize is the number of bytes of the key, value and headers of a record.
+func messageSize(msg *sarama.ConsumerMessage) int64 {
+	size := len(msg.Key) + len(msg.Value)
+	for _, header := range msg.Headers {
+		if header != nil {
+			size += len(header.Key) + len(header.Value)
+		}
+	}
+	return int64(size)
+}
+
+// recordSize is the number of bytes of the rendered key, value and headers of a record.
+func recordSize(record *dto.MessageRecord) int64 {
+	size := len(record.Key) + len(record.Value)
+	for _, header := range record.Headers {
+		size += len(header.Key) + len(header.Value)
+	}
+	return int64(size)
+}
+
+func (s *TopicTableService) cache(key string) *tableCache {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cache, ok := s.tables[key]
+	if !ok {
+		cache = &tableCache{}
+		s.tables[key] = cache
+		s.evict(cache)
+	}
+	cache.used = time.Now()
+	return cache
+}
+
+// resize records the size of a built table and makes room for it in the cache.
+func (s *TopicTableService) resize(cache *tableCache, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cache.size = size
+	s.evict(cache)
+}
+
+// evict drops the least recently used tables other than keep while more than
+// maxCachedTables are cached or they hold more than maxCachedTableBytes together.
+// s.mu must be held.
+func (s *TopicTableService) evict(keep *tableCache) {
+	for {
+		var total int64
+		var oldest string
+		for k, c := range s.tables {
+			total += c.size
+			if c != keep && (oldest == "" || c.used.Before(s.tables[oldest].used)) {
+				oldest = k
+			}
+		}
+		if oldest == "" || (len(s.tables) <= maxCachedTables && total <= maxCachedTableBytes) {
+			return
+		}
+		delete(s.tables, oldest)
+	}
+}
+
+// GetTable returns a page of the table view of a compacted topic, building it first when
+// it is not cached yet or the topic's offsets moved since it was built.
+func (s *TopicTableService) GetTable(ctx context.Context, clusterID uint, topicName string, query dto.TopicTableQuery) (*dto.TopicTable, error) {
+	cluster, err := s.clusterRepo.FindByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.kafkaManager.CreateClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	bounds, err := topicBounds(client, topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := s.cache(fmt.Sprintf("%d/%s/%s", clusterID, topicName, query.Encoding))
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cached := cache.table != nil && !query.Refresh && sameBounds(cache.table.bounds, bounds)
+	if !cached {
+		if err := s.checkCompacted(clusterID, topicName); err != nil {
+			return nil, err
+		}
+		decoder, err := s.topicService.topicDecoder(cluster, topicName, query.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		consumer, err := sarama.NewConsumerFromClient(client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create consumer: %w", err)
+		}
+		scan, err := scanLatest(ctx, consumer, remainingIn(client), topicName, bounds)
+		consumer.Close()
+		if err != nil {
+			return nil, err
+		}
+		cache.table = newTopicTable(scan, decoder, bounds)
+		s.resize(cache, cache.table.size)
+	}
+
+	table := cache.table.page(query)
+	table.Topic = topicName
+	table.Cached = cached
+	return table, nil
+}
+
+func (s *TopicTableService) checkCompacted(clusterID uint, topicName string) error {
+	configs, err := s.topicService.GetTopicConfigs(clusterID, topicName)
+	if err != nil {
+		return err
+	}
+	for _, config := range configs {
+		if config.Name == "cleanup.policy" && strings.Contains(config.Value, "compact") {
+			return nil
+		}
+	}
+	return ErrTopicNotCompacted
+}
+
+// topicBounds returns the start and end offsets of every partition of a topic.
+func topicBounds(client sarama.Client, topicName string) (map[int32]partitionBounds, error) {
+	partitions, err := client.Partitions(topicName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions: %w", err)
+	}
+
+	bounds := make(map[int32]partitionBounds, len(partitions))
+	for _, partition := range partitions {
+		start, err := client.GetOffset(topicName, partition, sarama.OffsetOldest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get beginning offset of partition %d: %w", partition, err)
+		}
+		end, err := client.GetOffset(topicName, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get end offset of partition %d: %w", partition, err)
+		}
+		bounds[partition] = partitionBounds{start: start, end: end}
+	}
+	return bounds, nil
+}
+
+func sameBounds(a, b map[int32]partitionBounds) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for partition, bound := range a {
+		if other, ok := b[partition]; !ok || other != bound {
+			return false
+		}
+	}
+	return true
+}
+
+// scanLatest reads every partition up to its end offset and keeps the latest record of
+// each key. A key found on several partitions, e.g. after partitions were added, keeps
+// the record with the newest timestamp.
+func scanLatest(ctx context.Context, consumer sarama.Consumer, remaining remainingCheck, topicName string, bounds map[int32]partitionBounds) (*tableScan, error) {
+	partitions := make([]int32, 0, len(bounds))
+	for partition := range bounds {
+		partitions = append(partitions, partition)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+	scan := &tableScan{latest: make(map[string]*sarama.ConsumerMessage)}
+	var tooMany, tooLarge bool
+	for _, partition := range partitions {
+		rng := partitionRange{partition: partition, start: bounds[partition].start, stop: bounds[partition].end}
+		exhausted, err := scanRange(ctx, consumer, remaining, topicName, rng, tableIdleTimeout, func(msg *sarama.ConsumerMessage) bool {
+			scan.scanned++
+			if msg.Key == nil {
+				scan.nullKeys++
+				return true
+			}
+			key := string(msg.Key)
+			previous, ok := scan.latest[key]
+			if ok && previous.Partition != msg.Partition && previous.Timestamp.After(msg.Timestamp) {
+				return true
+			}
+			if ok {
+				scan.bytes -= messageSize(previous)
+			}
+			scan.latest[key] = msg
+			scan.bytes += messageSize(msg)
+			if len(scan.latest) > maxTableKeys {
+				tooMany = true
+				return false
+			}
+			if scan.bytes > maxTableBytes {
+				tooLarge = true
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if tooMany {
+			return nil, fmt.Errorf("topic %s has more than %d keys", topicName, maxTableKeys)
+		}
+		if tooLarge {
+			return nil, fmt.Errorf("the latest records of topic %s take more than %d MiB", topicName, maxTableBytes>>20)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !exhausted {
+			return nil, fmt.Errorf("partition %d stopped before offset %d", partition, rng.stop)
+		}
+	}
+	return scan, nil
+}
+
+// newTopicTable decodes the latest live record of every key and sorts them by key.
+func newTopicTable(scan *tableScan, decoder *messageDecoder, bounds map[int32]partitionBounds) *topicTable {
+	table := &topicTable{
+		bounds:   bounds,
+		records:  make([]dto.MessageRecord, 0, len(scan.latest)),
+		nullKeys: scan.nullKeys,
+		scanned:  scan.scanned,
+		builtAt:  time.Now(),
+	}
+	for _, msg := range scan.latest {
+		// a null value is a tombstone, compaction deletes the key
+		if msg.Value == nil {
+			table.tombstoned++
+			continue
+		}
+		record := decoder.record(msg)
+		table.size += recordSize(&record)
+		table.records = append(table.records, record)
+	}
+	sort.Slice(table.records, func(i, j int) bool {
+		a, b := table.records[i], table.records[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Partition != b.Partition {
+			return a.Partition < b.Partition
+		}
+		return a.Offset < b.Offset
+	})
+	return table
+}
+
+// page returns the live records of one page, limited to the keys containing query.Key.
+func (t *topicTable) page(query dto.TopicTableQuery) *dto.TopicTable {
+	matches := t.records
+	if query.Key != "" {
+		matches = make([]dto.MessageRecord, 0)
+		for _, record := range t.records {
+			if strings.Contains(record.Key, query.Key) {
+				matches = append(matches, record)
+			}
+		}
+	}
+
+	result := &dto.TopicTable{
+		LiveKeys:       int64(len(t.records)),
+		TombstonedKeys: t.tombstoned,
+		NullKeys:       t.nullKeys,
+		ScannedRecords: t.scanned,
+		Total:          int64(len(matches)),
+		PageIndex:      query.PageIndex,
+		PageSize:       query.PageSize,
+		Items:          []dto.MessageRecord{},
+		EndOffsets:     make([]dto.PartitionPosition, 0, len(t.bounds)),
+		BuiltAt:        t.builtAt,
+	}
+	// compare page numbers, the offset of a huge page index would overflow
+	if query.PageSize > 0 && query.PageIndex >= 1 && query.PageIndex <= (len(matches)+query.PageSize-1)/query.PageSize {
+		start := (query.PageIndex - 1) * query.PageSize
+		end := start + query.PageSize
+		if end > len(matches) {
+			end = len(matches)
+		}
+		result.Items = append(result.Items, matches[start:end]...)
+	}
+	for partition, bound := range t.bounds {
+		result.EndOffsets = append(result.EndOffsets, dto.PartitionPosition{Partition: partition, Offset: bound.end})
+	}
+	sort.Slice(result.EndOffsets, func(i, j int) bool {
+		return result.EndOffsets[i].Partition < result.EndOffsets[j].Partition
+	})
+	return result
+}
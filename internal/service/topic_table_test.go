@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/bingfengfeifei/kafka-map-go/internal/dto"
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+)
+
+func TestTopicTable(t *testing.T) {
+	timestamp := time.UnixMilli(1_700_000_000_000)
+	consumer := mocks.NewConsumer(t, nil)
+	p0 := consumer.ExpectConsumePartition("users", 0, 3)
+	p0.YieldMessage(&sarama.ConsumerMessage{Key: []byte("alice"), Value: []byte("v1"), Timestamp: timestamp})
+	p0.YieldMessage(&sarama.ConsumerMessage{Key: []byte("bob"), Value: []byte("v1"), Timestamp: timestamp})
+	p0.YieldMessage(&sarama.ConsumerMessage{Key: []byte("alice"), Value: []byte("v2"), Timestamp: timestamp.Add(time.Second)})
+	p0.YieldMessage(&sarama.ConsumerMessage{Key: []byte("bob"), Timestamp: timestamp.Add(time.Second)})
+	p1 := consumer.ExpectConsumePartition("users", 1, 0)
+	p1.YieldMessage(&sarama.ConsumerMessage{Value: []byte("no key"), Timestamp: timestamp})
+	// an older record of alice on another partition does not replace the newer one
+	p1.YieldMessage(&sarama.ConsumerMessage{Key: []byte("alice"), Value: []byte("stale"), Timestamp: timestamp})
+	p1.YieldMessage(&sarama.ConsumerMessage{Key: []byte("carol"), Value: []byte("v1"), Timestamp: timestamp})
+	p1.YieldMessage(&sarama.ConsumerMessage{Key: []byte("alicia"), Value: []byte{}, Timestamp: timestamp})
+
+	bounds := map[int32]partitionBounds{0: {start: 3, end: 7}, 1: {start: 0, end: 4}}
+	scan, err := scanLatest(context.Background(), consumer, noRemaining, "users", bounds)
+	if err != nil {
+		t.Fatalf("scanLatest() error = %v", err)
+	}
+	if scan.scanned != 8 || scan.nullKeys != 1 {
+		t.Fatalf("scanned %d records with %d null keys, want 8 and 1", scan.scanned, scan.nullKeys)
+	}
+	table := newTopicTable(scan, newMessageDecoder(&model.Cluster{}, nil, nil, dto.EncodingUTF8), bounds)
+
+	tests := []struct {
+		name      string
+		query     dto.TopicTableQuery
+		wantTotal int64
+		wantKeys  []string
+	}{
+		{name: "first page", query: dto.TopicTableQuery{PageIndex: 1, PageSize: 2}, wantTotal: 3, wantKeys: []string{"alice", "alicia"}},
+		{name: "last page", query: dto.TopicTableQuery{PageIndex: 2, PageSize: 2}, wantTotal: 3, wantKeys: []string{"carol"}},
+		{name: "past the end", query: dto.TopicTableQuery{PageIndex: 3, PageSize: 2}, wantTotal: 3, wantKeys: []string{}},
+		{name: "huge page index", query: dto.TopicTableQuery{PageIndex: math.MaxInt, PageSize: 2}, wantTotal: 3, wantKeys: []string{}},
+		{name: "key search", query: dto.TopicTableQuery{Key: "ali", PageIndex: 1, PageSize: 10}, wantTotal: 2, wantKeys: []string{"alice", "alicia"}},
+		{name: "tombstoned key", query: dto.TopicTableQuery{Key: "bob", PageIndex: 1, PageSize: 10}, wantTotal: 0, wantKeys: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := table.page(tt.query)
+			if page.LiveKeys != 3 || page.TombstonedKeys != 1 || page.NullKeys != 1 {
+				t.Fatalf("counts = %d live, %d tombstoned, %d null, want 3, 1 and 1", page.LiveKeys, page.TombstonedKeys, page.NullKeys)
+			}
+			if page.Total != tt.wantTotal || len(page.Items) != len(tt.wantKeys) {
+				t.Fatalf("page = %d of %d items, want %d of %d", len(page.Items), page.Total, len(tt.wantKeys), tt.wantTotal)
+			}
+			for i, key := range tt.wantKeys {
+				if page.Items[i].Key != key {
+					t.Fatalf("Items[%d].Key = %q, want %q", i, page.Items[i].Key, key)
+				}
+			}
+		})
+	}
+
+	if first := table.page(dto.TopicTableQuery{PageIndex: 1, PageSize: 1}).Items[0]; first.Value != "v2" || first.Partition != 0 {
+		t.Fatalf("alice = %+v, want the newest value v2 from partition 0", first)
+	}
+	if offsets := table.page(dto.TopicTableQuery{PageIndex: 1, PageSize: 1}).EndOffsets; len(offsets) != 2 || offsets[0].Offset != 7 || offsets[1].Offset != 4 {
+		t.Fatalf("EndOffsets = %+v, want 7 and 4", offsets)
+	}
+}
+
+func TestTopicTableCacheEviction(t *testing.T) {
+	s := NewTopicTableService(nil, nil, nil)
+
+	first := s.cache("1/users/auto")
+	s.resize(first, maxCachedTableBytes/2)
+	second := s.cache("1/orders/auto")
+	s.resize(second, maxCachedTableBytes/2)
+	if len(s.tables) != 2 {
+		t.Fatalf("cached %d tables, want 2 within the byte budget", len(s.tables))
+	}
+
+	// a third table over the byte budget drops the least recently used one
+	third := s.cache("1/payments/auto")
+	s.resize(third, 1)
+	if _, ok := s.tables["1/users/auto"]; ok || len(s.tables) != 2 {
+		t.Fatalf("cached tables = %v, want users evicted", s.tables)
+	}
+
+	for i := 0; i < maxCachedTables+2; i++ {
+		s.cache(fmt.Sprintf("1/topic-%d/auto", i))
+	}
+	if len(s.tables) != maxCachedTables {
+		t.Fatalf("cached %d tables, want %d", len(s.tables), maxCachedTables)
+	}
+}
+
+func TestSameBounds(t *testing.T) {
+	base := map[int32]partitionBounds{0: {start: 0, end: 10}, 1: {start: 5, end: 8}}
+
+	tests := []struct {
+		name  string
+		other map[int32]partitionBounds
+		want  bool
+	}{
+		{name: "unchanged", other: map[int32]partitionBounds{0: {start: 0, end: 10}, 1: {start: 5, end: 8}}, want: true},
+		{name: "new records", other: map[int32]partitionBounds{0: {start: 0, end: 11}, 1: {start: 5, end: 8}}},
+		{name: "truncated", other: map[int32]partitionBounds{0: {start: 3, end: 10}, 1: {start: 5, end: 8}}},
+		{name: "partition added", other: map[int32]partitionBounds{0: {start: 0, end: 10}, 1: {start: 5, end: 8}, 2: {}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameBounds(base, tt.other); got != tt.want {
+				t.Fatalf("sameBounds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -72,9 +72,15 @@ func (m *KafkaClientManager) CreateConsumer(cluster *model.Cluster) (sarama.Cons
 	return consumer, nil
 }
 
+// ConfigOption adjusts the configuration of a client or producer
+type ConfigOption func(*sarama.Config)
+
 // CreateClient creates a new Kafka client
-func (m *KafkaClientManager) CreateClient(cluster *model.Cluster) (sarama.Client, error) {
+func (m *KafkaClientManager) CreateClient(cluster *model.Cluster, options ...ConfigOption) (sarama.Client, error) {
 	config := m.buildConfig(cluster)
+	for _, option := range options {
+		option(config)
+	}
 	brokers := brokerList(cluster.Servers)
 
 	client, err := sarama.NewClient(brokers, config)
@@ -85,10 +91,13 @@ func (m *KafkaClientManager) CreateClient(cluster *model.Cluster) (sarama.Client
 }
 
 // CreateProducer creates a new Kafka producer
-func (m *KafkaClientManager) CreateProducer(cluster *model.Cluster) (sarama.SyncProducer, error) {
+func (m *KafkaClientManager) CreateProducer(cluster *model.Cluster, options ...ConfigOption) (sarama.SyncProducer, error) {
 	config := m.buildConfig(cluster)
 	config.Producer.Return.Successes = true
 	config.Producer.RequiredAcks = sarama.WaitForAll
+	for _, option := range options {
+		option(config)
+	}
 
 	brokers := brokerList(cluster.Servers)
 
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ProtoFiles is a compiled FileDescriptorSet.
+type ProtoFiles struct {
+	files *protoregistry.Files
+	types *dynamicpb.Types
+}
+
+// ParseDescriptorSet compiles a serialized FileDescriptorSet, as written by
+// `protoc --include_imports --descriptor_set_out`.
+func ParseDescriptorSet(data []byte) (*ProtoFiles, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse descriptor set: %w", err)
+	}
+	if len(set.GetFile()) == 0 {
+		return nil, errors.New("descriptor set contains no files")
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	return &ProtoFiles{files: files, types: dynamicpb.NewTypes(files)}, nil
+}
+
+// MessageTypes lists the fully qualified names of all messages, including nested ones.
+func (p *ProtoFiles) MessageTypes() []string {
+	var names []string
+	var collect func(messages protoreflect.MessageDescriptors)
+	collect = func(messages protoreflect.MessageDescriptors) {
+		for i := 0; i < messages.Len(); i++ {
+			message := messages.Get(i)
+			if message.IsMapEntry() {
+				continue
+			}
+			names = append(names, string(message.FullName()))
+			collect(message.Messages())
+		}
+	}
+	p.files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		collect(file.Messages())
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// FindMessage looks up a message type by its fully qualified name.
+func (p *ProtoFiles) FindMessage(name string) (protoreflect.MessageDescriptor, error) {
+	descriptor, err := p.files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("message type %s not found in descriptor set", name)
+	}
+	message, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message type", name)
+	}
+	return message, nil
+}
+
+// DecodeProtobuf decodes a binary protobuf payload of the given message type into compact JSON.
+func (p *ProtoFiles) DecodeProtobuf(message protoreflect.MessageDescriptor, payload []byte) (string, error) {
+	decoded := dynamicpb.NewMessage(message)
+	if err := (proto.UnmarshalOptions{Resolver: p.types}).Unmarshal(payload, decoded); err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", message.FullName(), err)
+	}
+
+	textual, err := (protojson.MarshalOptions{Resolver: p.types}).Marshal(decoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", message.FullName(), err)
+	}
+
+	// protojson deliberately randomizes whitespace, compact it for stable output
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, textual); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", message.FullName(), err)
+	}
+	return compacted.String(), nil
+}
@@ -0,0 +1,173 @@
+package util
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bingfengfeifei/kafka-map-go/internal/model"
+	"github.com/linkedin/goavro/v2"
+	"github.com/patrickmn/go-cache"
+)
+
+// confluentMagicByte prefixes payloads framed by the Confluent serializers,
+// followed by a 4-byte big-endian schema ID.
+const confluentMagicByte = 0x0
+
+// registryRetryAfter is how long a failed schema lookup, or a registry that could not
+// be reached, is answered from the cache before the registry is asked again.
+const registryRetryAfter = 30 * time.Second
+
+var (
+	// ErrSchemaNotFound is returned when the registry does not know a schema ID.
+	ErrSchemaNotFound = errors.New("schema not found")
+	// ErrRegistryUnavailable is returned when the registry cannot be reached or fails.
+	ErrRegistryUnavailable = errors.New("schema registry unavailable")
+)
+
+// SchemaRegistryClient fetches writer schemas from Confluent compatible schema
+// registries and caches the compiled codecs. Schema IDs are immutable, so cached
+// codecs never expire. Failures are cached for registryRetryAfter, per schema ID or,
+// when the registry itself fails, for every ID of the registry, so a scan over many
+// records does not wait on the registry once per record.
+type SchemaRegistryClient struct {
+	httpClient *http.Client
+	codecs     map[string]*goavro.Codec
+	failures   *cache.Cache // errors by schema cache key, or by registry URL
+	mu         sync.RWMutex
+}
+
+func NewSchemaRegistryClient() *SchemaRegistryClient {
+	return &SchemaRegistryClient{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		codecs:     make(map[string]*goavro.Codec),
+		failures:   cache.New(registryRetryAfter, 2*registryRetryAfter),
+	}
+}
+
+// ParseSchemaID extracts the schema ID from a Confluent framed payload. Registries
+// hand out positive IDs, so anything else is not taken as framed.
+func ParseSchemaID(data []byte) (int32, []byte, bool) {
+	if len(data) < 5 || data[0] != confluentMagicByte {
+		return 0, nil, false
+	}
+	id := int32(binary.BigEndian.Uint32(data[1:5]))
+	if id <= 0 {
+		return 0, nil, false
+	}
+	return id, data[5:], true
+}
+
+// DecodeAvro decodes a Confluent framed Avro payload into standard JSON.
+func (c *SchemaRegistryClient) DecodeAvro(cluster *model.Cluster, id int32, payload []byte) (string, error) {
+	codec, err := c.avroCodec(cluster, id)
+	if err != nil {
+		return "", err
+	}
+
+	native, _, err := codec.NativeFromBinary(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode avro payload with schema %d: %w", id, err)
+	}
+	textual, err := codec.TextualFromNative(nil, native)
+	if err != nil {
+		return "", fmt.Errorf("failed to render avro payload with schema %d: %w", id, err)
+	}
+	return string(textual), nil
+}
+
+// avroCodec returns the cached codec for a schema ID or fetches it from the registry
+func (c *SchemaRegistryClient) avroCodec(cluster *model.Cluster, id int32) (*goavro.Codec, error) {
+	baseURL := strings.TrimRight(strings.TrimSpace(cluster.SchemaRegistryURL), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("no schema registry configured for cluster %s", cluster.Name)
+	}
+	cacheKey := fmt.Sprintf("%s#%d", baseURL, id)
+
+	c.mu.RLock()
+	codec, exists := c.codecs[cacheKey]
+	c.mu.RUnlock()
+
+	if exists {
+		return codec, nil
+	}
+	if cached, found := c.failures.Get(baseURL); found {
+		return nil, cached.(error)
+	}
+	if cached, found := c.failures.Get(cacheKey); found {
+		return nil, cached.(error)
+	}
+
+	schema, err := c.fetchSchema(cluster, baseURL, id)
+	if err != nil {
+		if errors.Is(err, ErrRegistryUnavailable) {
+			c.failures.SetDefault(baseURL, err)
+		} else {
+			c.failures.SetDefault(cacheKey, err)
+		}
+		return nil, err
+	}
+	if schema.SchemaType != "" && !strings.EqualFold(schema.SchemaType, "AVRO") {
+		err := fmt.Errorf("schema %d is %s, not AVRO", id, schema.SchemaType)
+		c.failures.SetDefault(cacheKey, err)
+		return nil, err
+	}
+
+	codec, err = goavro.NewCodecForStandardJSONFull(schema.Schema)
+	if err != nil {
+		err = fmt.Errorf("failed to parse avro schema %d: %w", id, err)
+		c.failures.SetDefault(cacheKey, err)
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.codecs[cacheKey] = codec
+	c.mu.Unlock()
+
+	return codec, nil
+}
+
+type registrySchema struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+}
+
+func (c *SchemaRegistryClient) fetchSchema(cluster *model.Cluster, baseURL string, id int32) (*registrySchema, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/schemas/ids/%d", baseURL, id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build schema registry request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+	if cluster.SchemaRegistryUsername != "" {
+		req.SetBasicAuth(cluster.SchemaRegistryUsername, cluster.SchemaRegistryPassword)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to fetch schema %d: %v", ErrRegistryUnavailable, id, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read schema %d: %v", ErrRegistryUnavailable, id, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: schema registry returned %s for schema %d", ErrSchemaNotFound, resp.Status, id)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: schema registry returned %s for schema %d", ErrRegistryUnavailable, resp.Status, id)
+	}
+
+	var schema registrySchema
+	if err := json.Unmarshal(body, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema %d: %w", id, err)
+	}
+	return &schema, nil
+}
@@ -0,0 +1,141 @@
+import React, {useState} from 'react';
+import {Alert, Button, Form, InputNumber, Modal, Popconfirm, Select, Table} from "antd/lib/index";
+import {FormattedMessage} from "react-intl";
+import request from "../common/request";
+
+const DeleteRecordsModal = ({clusterId, topic, partitions, handleCancel, handleDeleted}) => {
+
+    const [form] = Form.useForm();
+    const [loading, setLoading] = useState(false);
+    const [preview, setPreview] = useState(undefined);
+
+    const formItemLayout = {
+        labelCol: {span: 6},
+        wrapperCol: {span: 14},
+    };
+
+    const submit = async (dryRun) => {
+        let values = await form.validateFields();
+        let data = {dryRun: dryRun, partitions: values.partitions};
+        if (values.mode === 'offset') {
+            data.offset = values.offset;
+        } else if (values.mode === 'timestamp') {
+            data.timestamp = values.timestamp;
+        } else {
+            data.purge = true;
+        }
+
+        setLoading(true);
+        try {
+            let response = await request.post(`/topics/${topic}/delete-records?clusterId=${clusterId}`, data);
+            if (dryRun) {
+                setPreview(response.data);
+                return;
+            }
+            if (handleDeleted) {
+                handleDeleted(response.data);
+            }
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    const columns = [{
+        title: 'Partition',
+        dataIndex: 'partition',
+        key: 'partition'
+    }, {
+        title: 'Beginning Offset',
+        dataIndex: 'startOffset',
+        key: 'startOffset'
+    }, {
+        title: 'End Offset',
+        dataIndex: 'endOffset',
+        key: 'endOffset'
+    }, {
+        title: <FormattedMessage id="new-beginning-offset"/>,
+        dataIndex: 'deleteTo',
+        key: 'deleteTo'
+    }, {
+        title: <FormattedMessage id="records-to-delete"/>,
+        dataIndex: 'records',
+        key: 'records'
+    }];
+
+    return (
+        <Modal
+            title={<FormattedMessage id="delete-records"/>}
+            width={window.innerWidth * 0.5}
+            open={true}
+            maskClosable={false}
+            onCancel={handleCancel}
+            footer={[
+                <Button key="cancel" onClick={handleCancel}><FormattedMessage id="cancelText"/></Button>,
+                <Button key="preview" loading={loading} onClick={() => submit(true)}>
+                    <FormattedMessage id="preview"/>
+                </Button>,
+                <Popconfirm key="delete" title={<FormattedMessage id="delete-confirm"/>}
+                            disabled={!preview || preview['records'] === 0}
+                            onConfirm={() => submit(false)}>
+                    <Button type="primary" danger loading={loading} disabled={!preview || preview['records'] === 0}>
+                        <FormattedMessage id="delete"/>
+                    </Button>
+                </Popconfirm>,
+            ]}
+        >
+            <Form form={form} {...formItemLayout} initialValues={{mode: 'offset'}}
+                  onValuesChange={() => setPreview(undefined)}>
+                <Form.Item label={'Partition'} name='partitions'>
+                    <Select mode="multiple" allowClear placeholder={<FormattedMessage id="all-partitions"/>}>
+                        {
+                            partitions.map(item => {
+                                return <Select.Option key={'p' + item['partition']}
+                                                      value={item['partition']}>{item['partition']}</Select.Option>
+                            })
+                        }
+                    </Select>
+                </Form.Item>
+
+                <Form.Item label={<FormattedMessage id="delete-before"/>} name='mode'>
+                    <Select>
+                        <Select.Option value="offset">Offset</Select.Option>
+                        <Select.Option value="timestamp"><FormattedMessage id="timestamp"/></Select.Option>
+                        <Select.Option value="purge"><FormattedMessage id="purge"/></Select.Option>
+                    </Select>
+                </Form.Item>
+
+                <Form.Item noStyle shouldUpdate={(prev, cur) => prev.mode !== cur.mode}>
+                    {({getFieldValue}) => {
+                        let mode = getFieldValue('mode');
+                        if (mode === 'offset') {
+                            return <Form.Item label={'Offset'} name='offset'
+                                              rules={[{required: true, message: 'Please enter offset'}]}>
+                                <InputNumber min={0} style={{width: '100%'}}/>
+                            </Form.Item>
+                        }
+                        if (mode === 'timestamp') {
+                            return <Form.Item label={<FormattedMessage id="timestamp"/>} name='timestamp'
+                                              rules={[{required: true, message: 'Please enter timestamp'}]}>
+                                <InputNumber min={1} style={{width: '100%'}} placeholder="ms"/>
+                            </Form.Item>
+                        }
+                        return undefined;
+                    }}
+                </Form.Item>
+            </Form>
+
+            {
+                preview ?
+                    <div>
+                        <Alert type={preview['records'] > 0 ? 'warning' : 'info'} style={{marginBottom: 8}}
+                               message={<FormattedMessage id="records-to-delete-total"
+                                                          values={{total: preview['records']}}/>}/>
+                        <Table rowKey='partition' size='small' pagination={false}
+                               dataSource={preview['partitions']} columns={columns}/>
+                    </div> : undefined
+            }
+        </Modal>
+    )
+};
+
+export default DeleteRecordsModal;
@@ -0,0 +1,119 @@
+import React, {useState} from 'react';
+import {Alert, Button, Form, InputNumber, List, Modal, Select, Switch, Upload} from "antd/lib/index";
+import {FormattedMessage} from "react-intl";
+import request from "../common/request";
+import qs from "qs";
+
+const ImportMessageModal = ({clusterId, topic, handleCancel, handleImported}) => {
+
+    const [form] = Form.useForm();
+    const [file, setFile] = useState(undefined);
+    const [loading, setLoading] = useState(false);
+    const [result, setResult] = useState(undefined);
+
+    const formItemLayout = {
+        labelCol: {span: 6},
+        wrapperCol: {span: 14},
+    };
+
+    const handleOk = async () => {
+        let values = await form.validateFields();
+        if (!file) {
+            return;
+        }
+        let query = {
+            clusterId: clusterId,
+            dryRun: values.dryRun === true,
+            compression: values.compression,
+        };
+        if (values.format) {
+            query.format = values.format;
+        }
+        if (values.rate) {
+            query.rate = values.rate;
+        }
+
+        let data = new FormData();
+        data.append('file', file);
+        setLoading(true);
+        try {
+            let response = await request.post(`/topics/${topic}/import?${qs.stringify(query)}`, data);
+            setResult(response.data);
+            if (!query.dryRun && handleImported) {
+                handleImported();
+            }
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    return (
+        <Modal
+            title={<FormattedMessage id="import-messages"/>}
+            width={window.innerWidth * 0.4}
+            open={true}
+            maskClosable={false}
+            onOk={handleOk}
+            onCancel={handleCancel}
+            confirmLoading={loading}
+            okText={<FormattedMessage id="okText"/>}
+            cancelText={<FormattedMessage id="cancelText"/>}
+        >
+            <Form form={form} {...formItemLayout} initialValues={{dryRun: true, compression: 'none'}}>
+                <Form.Item label={<FormattedMessage id="file"/>} required>
+                    <Upload accept=".jsonl,.ndjson,.json,.csv" maxCount={1}
+                            beforeUpload={(f) => {
+                                setFile(f);
+                                setResult(undefined);
+                                return false;
+                            }}
+                            onRemove={() => setFile(undefined)}>
+                        <Button><FormattedMessage id="select-file"/></Button>
+                    </Upload>
+                </Form.Item>
+
+                <Form.Item label={<FormattedMessage id="format"/>} name='format'>
+                    <Select allowClear placeholder={<FormattedMessage id="format-by-extension"/>}>
+                        <Select.Option value="jsonl">JSONL</Select.Option>
+                        <Select.Option value="csv">CSV</Select.Option>
+                    </Select>
+                </Form.Item>
+
+                <Form.Item label={<FormattedMessage id="dry-run"/>} name='dryRun' valuePropName="checked">
+                    <Switch/>
+                </Form.Item>
+
+                <Form.Item label={<FormattedMessage id="rate-limit"/>} name='rate'>
+                    <InputNumber min={0} style={{width: '100%'}} placeholder="records/s"/>
+                </Form.Item>
+
+                <Form.Item label={<FormattedMessage id="compression"/>} name='compression'>
+                    <Select>
+                        {
+                            ['none', 'gzip', 'snappy', 'lz4', 'zstd'].map(codec => {
+                                return <Select.Option key={codec} value={codec}>{codec}</Select.Option>
+                            })
+                        }
+                    </Select>
+                </Form.Item>
+            </Form>
+
+            {
+                result ?
+                    <div>
+                        <Alert type={result['failed'] > 0 ? 'warning' : 'success'}
+                               message={`${result['dryRun'] ? 'Dry run: ' : ''}${result['records']} / ${result['lines']} valid, ${result['produced']} produced, ${result['failed']} failed`}/>
+                        {
+                            result['errors'] && result['errors'].length > 0 ?
+                                <List size="small" style={{maxHeight: 240, overflow: 'auto'}}
+                                      dataSource={result['errors']}
+                                      renderItem={item => <List.Item>{`#${item['line']}: ${item['error']}`}</List.Item>}/>
+                                : undefined
+                        }
+                    </div> : undefined
+            }
+        </Modal>
+    )
+};
+
+export default ImportMessageModal;
@@ -1,5 +1,5 @@
 import React from 'react';
-import {Form, Input, Modal, Select} from "antd/lib/index";
+import {Form, Input, InputNumber, Modal, Select, Switch} from "antd/lib/index";
 import {FormattedMessage} from "react-intl";
 
 const {TextArea} = Input;
@@ -24,7 +24,11 @@ const SendMessageModal = ({partitions, handleOk, handleCancel, confirmLoading})
                 form
                     .validateFields()
                     .then(values => {
-                        let success = handleOk(values);
+                        let message = {...values};
+                        if (message.tombstone === true) {
+                            delete message.value;
+                        }
+                        let success = handleOk(message);
                         if (success === true) {
                             form.resetFields();
                         }
@@ -39,10 +43,10 @@ const SendMessageModal = ({partitions, handleOk, handleCancel, confirmLoading})
             cancelText={<FormattedMessage id="cancelText"/>}
         >
 
-            <Form form={form} {...formItemLayout} initialValues={{partition: 0}}>
+            <Form form={form} {...formItemLayout} initialValues={{compression: 'none', tombstone: false}}>
 
-                <Form.Item label={'Partition'} name='partition' rules={[{required: true}]}>
-                    <Select>
+                <Form.Item label={'Partition'} name='partition'>
+                    <Select allowClear placeholder={<FormattedMessage id="partition-by-key"/>}>
                         {
                             partitions.map(item => {
                                 return <Select.Option key={'p' + item['partition']}
@@ -52,13 +56,44 @@ const SendMessageModal = ({partitions, handleOk, handleCancel, confirmLoading})
                     </Select>
                 </Form.Item>
 
-                <Form.Item label={'Key'} name='key'>
+                <Form.Item label={'Key'} name='key'
+                           rules={[({getFieldValue}) => ({
+                               validator(_, value) {
+                                   if (getFieldValue('tombstone') === true && !value) {
+                                       return Promise.reject(new Error('A tombstone needs a key'));
+                                   }
+                                   return Promise.resolve();
+                               },
+                           })]}>
                     <Input placeholder=""/>
                 </Form.Item>
 
-                <Form.Item label={'Value'} name='value' rules={[{required: true, message: 'Please enter value'}]}>
-                    <TextArea rows={6}
-                              placeholder=""/>
+                <Form.Item label={<FormattedMessage id="tombstone"/>} name='tombstone' valuePropName="checked">
+                    <Switch/>
+                </Form.Item>
+
+                <Form.Item noStyle shouldUpdate={(prev, cur) => prev.tombstone !== cur.tombstone}>
+                    {({getFieldValue}) => getFieldValue('tombstone') === true ? undefined :
+                        <Form.Item label={'Value'} name='value'
+                                   rules={[{required: true, message: 'Please enter value'}]}>
+                            <TextArea rows={6}
+                                      placeholder=""/>
+                        </Form.Item>
+                    }
+                </Form.Item>
+
+                <Form.Item label={<FormattedMessage id="timestamp"/>} name='timestamp'>
+                    <InputNumber min={0} style={{width: '100%'}} placeholder="ms"/>
+                </Form.Item>
+
+                <Form.Item label={<FormattedMessage id="compression"/>} name='compression'>
+                    <Select>
+                        {
+                            ['none', 'gzip', 'snappy', 'lz4', 'zstd'].map(codec => {
+                                return <Select.Option key={codec} value={codec}>{codec}</Select.Option>
+                            })
+                        }
+                    </Select>
                 </Form.Item>
             </Form>
         </Modal>
@@ -9,9 +9,11 @@ import {
     InputNumber,
     List,
     Space,
-    Statistic, Col
+    Statistic, Col, Dropdown, Switch
 } from "antd";
 import request from "../common/request";
+import {server} from "../common/env";
+import {getToken} from "../utils/utils.jsx";
 import qs from "qs";
 import dayjs from "dayjs";
 
@@ -27,6 +29,9 @@ import {Empty} from "antd";
 
 const {Text} = Typography;
 
+// ALL_PARTITIONS selects the merged view of every partition, see dto.AllPartitions
+const ALL_PARTITIONS = -1;
+
 class TopicData extends Component {
 
     form = React.createRef();
@@ -43,6 +48,11 @@ class TopicData extends Component {
         autoOffsetReset: 'newest'
     }
 
+    messageLink = (item) => {
+        const base = new URL(server, window.location.origin).href.replace(/\/$/, '');
+        return `${base}/topics/${encodeURIComponent(this.state.topic)}/partitions/${item['partition']}/offsets/${item['offset']}?clusterId=${this.state.clusterId}`;
+    }
+
     componentDidMount() {
         let urlParams = new URLSearchParams(this.props.location.search);
         let clusterId = urlParams.get('clusterId');
@@ -67,6 +77,17 @@ class TopicData extends Component {
         if (partitions.length === 0) {
             return;
         }
+        if (this.state.partition === ALL_PARTITIONS) {
+            // the merged view starts at the beginning or the end of every partition
+            this.setState({
+                offset: undefined
+            })
+            if (this.form.current) {
+                this.form.current.setFieldsValue({'offset': undefined});
+            }
+            this.handleReset();
+            return;
+        }
         const partitionIndex = this.state.partition < partitions.length ? this.state.partition : 0;
         if (partitionIndex !== this.state.partition) {
             this.setState({partition: partitionIndex});
@@ -90,6 +111,16 @@ class TopicData extends Component {
         this.handleReset();
     }
 
+    exportMessages = (format) => {
+        // the export covers the whole partition, only the filters of the form apply
+        let {offset, count, autoOffsetReset, ...queryParams} = this.form.current ? this.form.current.getFieldsValue() : {};
+        queryParams['clusterId'] = this.state.clusterId;
+        queryParams['format'] = format;
+        queryParams['X-Auth-Token'] = getToken();
+        let paramsStr = qs.stringify(queryParams, {arrayFormat: 'repeat', skipNulls: true});
+        window.open(`${server}/topics/${encodeURIComponent(this.state.topic)}/export?${paramsStr}`, '_blank');
+    }
+
     handleReset = () => {
         this.setState({
             items: []
@@ -102,9 +133,17 @@ class TopicData extends Component {
         })
         try {
             queryParams['clusterId'] = this.state.clusterId;
-            let paramsStr = qs.stringify(queryParams);
+            if (queryParams['partition'] === ALL_PARTITIONS) {
+                // without an offset the merged view shows the newest records of all partitions
+                queryParams['partition'] = 'all';
+                delete queryParams['offset'];
+                if (queryParams['autoOffsetReset'] === 'earliest') {
+                    queryParams['offset'] = 0;
+                }
+            }
+            let paramsStr = qs.stringify(queryParams, {arrayFormat: 'repeat'});
             let response = await request.get(`/topics/${this.state.topic}/data?${paramsStr}`);
-            let result = response && Array.isArray(response.data) ? response.data : [];
+            let result = response && response.data && Array.isArray(response.data['messages']) ? response.data['messages'] : [];
             // Auto-expand JSON by default
             result.forEach(item => {
                 try {
@@ -129,8 +168,10 @@ class TopicData extends Component {
 
         const partitions = this.state.topicInfo && Array.isArray(this.state.topicInfo['partitions']) ?
             this.state.topicInfo['partitions'] : [];
+        const allPartitions = this.state.partition === ALL_PARTITIONS;
         const partitionIndex = this.state.partition < partitions.length ? this.state.partition : 0;
-        const selectedPartition = partitions.length > 0 ? partitions[partitionIndex] : null;
+        const selectedPartition = partitions.length > 0 && !allPartitions ? partitions[partitionIndex] : null;
+        const totalSize = partitions.reduce((total, item) => total + item['endOffset'] - item['beginningOffset'], 0);
 
         return (
             <div>
@@ -153,7 +194,7 @@ class TopicData extends Component {
                                             <Statistic title="End Offset"
                                                        value={selectedPartition ? selectedPartition['endOffset'] : '-'}/>
                                             <Statistic title="Size"
-                                                       value={selectedPartition ? selectedPartition['endOffset'] - selectedPartition['beginningOffset'] : '-'}/>
+                                                       value={selectedPartition ? selectedPartition['endOffset'] - selectedPartition['beginningOffset'] : (allPartitions ? totalSize : '-')}/>
                                         </>
                                         : undefined
                                 }
@@ -181,6 +222,9 @@ class TopicData extends Component {
                                             partition: value
                                         }, this.handlePartitionChange);
                                     }}>
+                                        <Select.Option key='all' value={ALL_PARTITIONS}>
+                                            <FormattedMessage id="all-partitions"/>
+                                        </Select.Option>
                                         {
                                             partitions.map(item => {
                                                 return <Select.Option key={'p' + item['partition']}
@@ -281,12 +325,84 @@ class TopicData extends Component {
                                     <Input allowClear placeholder="field value (fuzzy)"/>
                                 </Form.Item>
                             </Col>
-                            <Col span={12} style={{textAlign: 'right'}}>
+
+                            <Col span={18} key='jsonPath'>
+                                <Form.Item
+                                    name={'jsonPath'}
+                                    label={'JSONPath'}
+                                >
+                                    <Select mode="tags" allowClear open={false} tokenSeparators={[';']}
+                                            placeholder={'$.payload.user.id == 12; $.status in ["NEW", "PAID"]'}/>
+                                </Form.Item>
+                            </Col>
+
+                            <Col span={6} key='jsonPathMode'>
+                                <Form.Item
+                                    name={'jsonPathMode'}
+                                    label={'Match'}
+                                >
+                                    <Select allowClear placeholder="and">
+                                        <Select.Option value="and">and</Select.Option>
+                                        <Select.Option value="or">or</Select.Option>
+                                    </Select>
+                                </Form.Item>
+                            </Col>
+
+                            <Col span={18} key='filter'>
+                                <Form.Item
+                                    name={'filter'}
+                                    label={'Filter'}
+                                >
+                                    <Input allowClear placeholder={'value.order.amount > 100 && headers["source"] == "billing" && key matches "^user-"'}/>
+                                </Form.Item>
+                            </Col>
+
+                            <Col span={6} key='encoding'>
+                                <Form.Item
+                                    name={'encoding'}
+                                    label={'Encoding'}
+                                >
+                                    <Select allowClear placeholder="auto">
+                                        <Select.Option value="auto">auto</Select.Option>
+                                        <Select.Option value="utf8">utf8</Select.Option>
+                                        <Select.Option value="hex">hex</Select.Option>
+                                        <Select.Option value="base64">base64</Select.Option>
+                                    </Select>
+                                </Form.Item>
+                            </Col>
+                            <Col span={6} key='isolation'>
+                                <Form.Item
+                                    name={'isolation'}
+                                    label={<FormattedMessage id="isolation"/>}
+                                >
+                                    <Select allowClear placeholder="read_uncommitted">
+                                        <Select.Option value="read_uncommitted">read_uncommitted</Select.Option>
+                                        <Select.Option value="read_committed">read_committed</Select.Option>
+                                    </Select>
+                                </Form.Item>
+                            </Col>
+                            <Col span={6} key='batches'>
+                                <Form.Item
+                                    name={'batches'}
+                                    label={<FormattedMessage id="batch-metadata"/>}
+                                    valuePropName="checked"
+                                >
+                                    <Switch/>
+                                </Form.Item>
+                            </Col>
+                            <Col span={6} style={{textAlign: 'right'}}>
                                 <Space>
                                     <Button type="primary" htmlType="submit" loading={this.state.loading}>
                                         <FormattedMessage id="pull"/>
                                     </Button>
 
+                                    <Dropdown menu={{
+                                        items: ['jsonl', 'csv', 'raw'].map(format => ({key: format, label: format.toUpperCase()})),
+                                        onClick: ({key}) => this.exportMessages(key),
+                                    }}>
+                                        <Button><FormattedMessage id="export"/></Button>
+                                    </Dropdown>
+
                                     <Button type="default" danger onClick={this.handleReset}>
                                         <FormattedMessage id="reset"/>
                                     </Button>
@@ -324,9 +440,27 @@ class TopicData extends Component {
                                         title={dayjs(item['timestamp']).format("YYYY-MM-DD HH:mm:ss")}>
                                         <Text>{dayjs(item['timestamp']).fromNow()}</Text>
                                     </Tooltip>
+                                    <Text copyable={{
+                                        text: this.messageLink(item),
+                                        tooltips: [<FormattedMessage id="copy-message-link"/>, <FormattedMessage id="copied"/>]
+                                    }}/>
                                 </Space>
                             </>;
 
+                            const batch = item['batch'];
+                            const batchInfo = batch ? <Space wrap>
+                                <Text code>timestampType:</Text>
+                                <Text>{item['timestampType']}</Text>
+                                <Text code>producer:</Text>
+                                <Text>{batch['producerId']} / {batch['producerEpoch']}</Text>
+                                <Text code>baseSequence:</Text>
+                                <Text>{batch['baseSequence']}</Text>
+                                <Text code>compression:</Text>
+                                <Text>{batch['compression']}</Text>
+                                {batch['transactional'] ? <Text type="warning">transactional</Text> : undefined}
+                                {batch['control'] ? <Text type="danger">control</Text> : undefined}
+                            </Space> : undefined;
+
                             const description = <Row wrap={false}>
                                 <Col flex="none">
                                     <div style={{padding: '0 5px'}}>
@@ -355,6 +489,7 @@ class TopicData extends Component {
                                     </div>
                                 </Col>
                                 <Col flex="auto">
+                                    {batchInfo}
                                     <pre>{item['format'] ? item['format'] : item['value']}</pre>
                                 </Col>
                             </Row>;
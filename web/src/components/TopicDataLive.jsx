@@ -77,6 +77,18 @@ class TopicDataLive extends Component {
         })
     }
 
+    withEndOffsets = (positions) => {
+        let topicInfo = {...this.state.topicInfo};
+        if (!Array.isArray(topicInfo['partitions']) || !Array.isArray(positions)) {
+            return topicInfo;
+        }
+        topicInfo['partitions'] = topicInfo['partitions'].map(item => {
+            const position = positions.find(p => p['partition'] === item['partition']);
+            return position ? {...item, endOffset: position['endOffset']} : item;
+        });
+        return topicInfo;
+    }
+
     pullMessage = async (queryParams) => {
         if (this.state.eventSource) {
             this.state.eventSource.close();
@@ -85,6 +97,14 @@ class TopicDataLive extends Component {
         this.setState({
             loading: true
         })
+        const selected = Array.isArray(queryParams['partitions']) ? queryParams['partitions'] : [];
+        if (selected.length > 0) {
+            queryParams['partitions'] = selected.join(',');
+        } else {
+            // no selection follows every partition
+            delete queryParams['partitions'];
+            queryParams['partition'] = 'all';
+        }
         queryParams['clusterId'] = this.state.clusterId;
         queryParams['X-Auth-Token'] = getToken();
         let paramsStr = qs.stringify(queryParams);
@@ -127,18 +147,9 @@ class TopicDataLive extends Component {
                 items = items.slice(-100);
             }
 
-            let topicInfo = {...this.state.topicInfo};
-            if (Array.isArray(topicInfo['partitions']) && topicInfo['partitions'][liveMessage['partition']]) {
-                topicInfo['partitions'][liveMessage['partition']] = {
-                    ...topicInfo['partitions'][liveMessage['partition']],
-                    beginningOffset: liveMessage['beginningOffset'],
-                    endOffset: liveMessage['endOffset']
-                };
-            }
-
             this.setState({
                 items: items,
-                topicInfo: topicInfo
+                topicInfo: this.withEndOffsets(liveMessage['partitions'])
             })
 
             let listEle = document.getElementById('list');
@@ -147,6 +158,13 @@ class TopicDataLive extends Component {
             }
         });
 
+        eventSource.addEventListener("ping", (event) => {
+            let ping = JSON.parse(event.data);
+            this.setState({
+                topicInfo: this.withEndOffsets(ping['partitions'])
+            });
+        });
+
         eventSource.onerror = (error) => {
             console.log("SSE error", error);
             eventSource.close();
@@ -203,19 +221,21 @@ class TopicDataLive extends Component {
                 <div className='kd-page-header' style={{padding: 20}}>
                     <Form ref={this.form} onFinish={this.pullMessage}
                           initialValues={{
-                              partition: this.state.partition,
+                              partitions: [],
                           }}>
                         <Row gutter={24}>
                             <Col span={6}>
                                 <Form.Item
-                                    name={'partition'}
+                                    name={'partitions'}
                                     label={'Partition'}
                                 >
-                                    <Select onChange={(value) => {
-                                        this.setState({
-                                            partition: value
-                                        }, this.handlePartitionChange);
-                                    }}>
+                                    <Select mode="multiple" allowClear
+                                            placeholder={<FormattedMessage id="all-partitions"/>}
+                                            onChange={(value) => {
+                                                this.setState({
+                                                    partition: value.length > 0 ? value[0] : 0
+                                                });
+                                            }}>
                                         {
                                             this.state.topicInfo ?
                                                 partitions.map(item => {
@@ -264,6 +284,15 @@ class TopicDataLive extends Component {
                                     <Input allowClear placeholder="field value (fuzzy)"/>
                                 </Form.Item>
                             </Col>
+                            <Col span={18} key='filter'>
+                                <Form.Item
+                                    name={'filter'}
+                                    label={'Filter'}
+                                >
+                                    <Input allowClear placeholder={'value.order.amount > 100 && headers["source"] == "billing" && key matches "^user-"'}/>
+                                </Form.Item>
+                            </Col>
+
                             <Col span={6} style={{textAlign: 'right'}}>
                                 <Space>
                                     <Button type="primary" htmlType="submit" loading={this.state.loading}>
@@ -9,6 +9,8 @@ import {renderSize} from "../utils/utils.jsx";
 import TopicConfig from "./TopicConfig";
 import {FormattedMessage} from "react-intl";
 import SendMessageModal from "./SendMessageModal";
+import ImportMessageModal from "./ImportMessageModal";
+import DeleteRecordsModal from "./DeleteRecordsModal";
 import withRouter from "../hook/withRouter.jsx";
 import {PageHeader} from "@ant-design/pro-components";
 
@@ -70,10 +72,10 @@ class TopicInfo extends Component {
         })
         try {
             let response = await request.post(`/topics/${this.state.topic}/data?clusterId=${this.state.clusterId}`, values);
-            let offset = response && response.data !== undefined ? response.data : null;
+            let record = response && response.data && response.data.records ? response.data.records[0] : null;
             notification['success']({
                 message: '提示',
-                description: `发送数据成功，位于 offset ${offset}。`,
+                description: record ? `发送数据成功，位于分区 ${record.partition} offset ${record.offset}。` : '发送数据成功。',
             });
         } finally {
             this.setState({
@@ -117,6 +119,16 @@ class TopicInfo extends Component {
                                     modalVisible: true
                                 })
                             }}><FormattedMessage id="produce-message"/></Button>,
+                            <Button key="btn-import" onClick={() => {
+                                this.setState({
+                                    importVisible: true
+                                })
+                            }}><FormattedMessage id="import-messages"/></Button>,
+                            <Button key="btn-delete-records" danger onClick={() => {
+                                this.setState({
+                                    deleteRecordsVisible: true
+                                })
+                            }}><FormattedMessage id="delete-records"/></Button>,
                             <Link key={'link-2'}
                                   to={`/topic-data?clusterId=${this.state.clusterId}&topic=${this.state.topic}`}>
                                 <Button key="btn-consume-message" type="primary">
@@ -201,6 +213,50 @@ class TopicInfo extends Component {
                             confirmLoading={this.state.modalConfirmLoading}
                         /> : undefined
                 }
+
+                {
+                    this.state.importVisible ?
+                        <ImportMessageModal
+                            clusterId={this.state.clusterId}
+                            topic={this.state.topic}
+                            handleImported={() => {
+                                if (this.state.topicPartitionRef) {
+                                    this.state.topicPartitionRef.refresh();
+                                }
+                            }}
+                            handleCancel={() => {
+                                this.setState({
+                                    importVisible: false
+                                })
+                            }}
+                        /> : undefined
+                }
+
+                {
+                    this.state.deleteRecordsVisible ?
+                        <DeleteRecordsModal
+                            clusterId={this.state.clusterId}
+                            topic={this.state.topic}
+                            partitions={this.state.topicInfo.partitions}
+                            handleDeleted={(result) => {
+                                notification['success']({
+                                    message: <FormattedMessage id="records-deleted"
+                                                               values={{total: result['records']}}/>,
+                                });
+                                this.setState({
+                                    deleteRecordsVisible: false
+                                })
+                                if (this.state.topicPartitionRef) {
+                                    this.state.topicPartitionRef.refresh();
+                                }
+                            }}
+                            handleCancel={() => {
+                                this.setState({
+                                    deleteRecordsVisible: false
+                                })
+                            }}
+                        /> : undefined
+                }
             </div>
         );
     }
@@ -31,6 +31,31 @@ const en_US = {
     'total-messages': 'Total Messages',
     'last-update': 'Last Update',
     'no-messages': 'No messages',
+    'all-partitions': 'All partitions',
+    'partition-by-key': 'By key hash',
+    'tombstone': 'Tombstone',
+    'timestamp': 'Timestamp',
+    'compression': 'Compression',
+    'import-messages': 'Import Messages',
+    'file': 'File',
+    'select-file': 'Select file',
+    'format': 'Format',
+    'format-by-extension': 'By file extension',
+    'dry-run': 'Dry run',
+    'rate-limit': 'Rate limit',
+    'export': 'Export',
+    'isolation': 'Isolation',
+    'batch-metadata': 'Batch Metadata',
+    'delete-records': 'Delete Records',
+    'delete-before': 'Delete before',
+    'purge': 'Everything',
+    'preview': 'Preview',
+    'new-beginning-offset': 'New Beginning Offset',
+    'records-to-delete': 'Records to delete',
+    'records-to-delete-total': '{total} records will be deleted',
+    'records-deleted': '{total} records deleted',
+    'copy-message-link': 'Copy message link',
+    'copied': 'Copied',
     'numPartitions': 'Partitions Num',
     'replicationFactor': 'Replication Factor',
     'topic-alter': 'Alter',
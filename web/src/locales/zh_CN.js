@@ -32,6 +32,31 @@ const zh_CN = {
     'total-messages': '消息数量',
     'last-update': '最新消息',
     'no-messages': '暂无消息',
+    'all-partitions': '全部分区',
+    'partition-by-key': '按 key 哈希',
+    'tombstone': '墓碑消息',
+    'timestamp': '时间戳',
+    'compression': '压缩方式',
+    'import-messages': '批量导入',
+    'file': '文件',
+    'select-file': '选择文件',
+    'format': '格式',
+    'format-by-extension': '按文件扩展名',
+    'dry-run': '仅校验',
+    'rate-limit': '限速',
+    'export': '导出',
+    'isolation': '隔离级别',
+    'batch-metadata': '批次元数据',
+    'delete-records': '删除消息',
+    'delete-before': '删除此位置之前',
+    'purge': '全部消息',
+    'preview': '预览',
+    'new-beginning-offset': '新的起始 Offset',
+    'records-to-delete': '待删除消息数',
+    'records-to-delete-total': '将删除 {total} 条消息',
+    'records-deleted': '已删除 {total} 条消息',
+    'copy-message-link': '复制消息链接',
+    'copied': '已复制',
     'numPartitions': '分区数量',
     'replicationFactor': '副本数量',
     'topic-alter': '分区扩容',